@@ -6,22 +6,326 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/oapi-codegen/runtime"
+	openapi_types "github.com/oapi-codegen/runtime/types"
 )
 
+const (
+	BearerAuthScopes     = "BearerAuth.Scopes"
+	IdentityHeaderScopes = "IdentityHeader.Scopes"
+)
+
+// Defines values for AuditEntryAction.
+const (
+	Create  AuditEntryAction = "create"
+	Delete  AuditEntryAction = "delete"
+	Restore AuditEntryAction = "restore"
+	Rsvp    AuditEntryAction = "rsvp"
+	Update  AuditEntryAction = "update"
+)
+
+// Defines values for RSVPStatus.
+const (
+	Accepted    RSVPStatus = "accepted"
+	Declined    RSVPStatus = "declined"
+	NeedsAction RSVPStatus = "needs-action"
+	Tentative   RSVPStatus = "tentative"
+)
+
+// Defines values for ListEventsParamsSort.
+const (
+	StartDate ListEventsParamsSort = "startDate"
+	Title     ListEventsParamsSort = "title"
+)
+
+// Defines values for ListEventsParamsOrder.
+const (
+	Asc  ListEventsParamsOrder = "asc"
+	Desc ListEventsParamsOrder = "desc"
+)
+
+// Attendee defines model for Attendee.
+type Attendee struct {
+	Status *RSVPStatus `json:"status,omitempty"`
+	User   string      `json:"user"`
+}
+
+// AuditChange defines model for AuditChange.
+type AuditChange struct {
+	// After Field value after the change, missing if the field is unset
+	After *interface{} `json:"after,omitempty"`
+
+	// Before Field value before the change, missing if the field wasn't set
+	Before *interface{} `json:"before,omitempty"`
+	Field  *string      `json:"field,omitempty"`
+}
+
+// AuditEntry defines model for AuditEntry.
+type AuditEntry struct {
+	Action *AuditEntryAction `json:"action,omitempty"`
+
+	// Actor Caller who made the change
+	Actor *string `json:"actor,omitempty"`
+
+	// Changes Changed event fields, the version and the reminder state aren't recorded
+	Changes *[]AuditChange `json:"changes,omitempty"`
+	EventId *string        `json:"eventId,omitempty"`
+	Id      *int64         `json:"id,omitempty"`
+	Time    *time.Time     `json:"time,omitempty"`
+}
+
+// AuditEntryAction defines model for AuditEntry.Action.
+type AuditEntryAction string
+
 // Event defines model for Event.
 type Event struct {
-	Description *string   `json:"description,omitempty"`
-	Duration    int64     `json:"duration"`
-	Id          *string   `json:"id,omitempty"`
-	Owner       string    `json:"owner"`
-	RemindAt    *int64    `json:"remindAt,omitempty"`
-	StartDate   time.Time `json:"startDate"`
-	Title       string    `json:"title"`
+	// Attendees Invited users, they see the event in their listings unless they decline it and are reminded of it once they accept it. The statuses are set by the attendees only.
+	Attendees *[]Attendee `json:"attendees,omitempty"`
+
+	// DeletedAt Time the event was moved to the trash, set for the deleted events only
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Duration    int64      `json:"duration"`
+
+	// ExDates Start dates of the recurring event occurrences to skip
+	ExDates *[]time.Time `json:"exDates,omitempty"`
+	Id      *string      `json:"id,omitempty"`
+	Owner   string       `json:"owner"`
+
+	// Reminders Reminders of every event occurrence sent to the owner and the accepted attendees
+	Reminders *[]Reminder `json:"reminders,omitempty"`
+
+	// Rrule RFC 5545 recurrence rule (FREQ=DAILY|WEEKLY|MONTHLY with BYDAY, COUNT, UNTIL, INTERVAL)
+	Rrule     *string   `json:"rrule,omitempty"`
+	StartDate time.Time `json:"startDate"`
+	Title     string    `json:"title"`
+
+	// Version Increased by every change of the event, the ETag of the event
+	Version *int64 `json:"version,omitempty"`
+}
+
+// EventPage defines model for EventPage.
+type EventPage struct {
+	Events *[]Event `json:"events,omitempty"`
+
+	// NextCursor Cursor of the next page, absent on the last page
+	NextCursor *string `json:"nextCursor,omitempty"`
+}
+
+// EventPatch Event fields to change, the missing fields are kept and null removes the field. The id, version and reminder state of the event can't be patched.
+type EventPatch struct {
+	Attendees   *[]Attendee  `json:"attendees,omitempty"`
+	Description *string      `json:"description,omitempty"`
+	Duration    *int64       `json:"duration,omitempty"`
+	ExDates     *[]time.Time `json:"exDates,omitempty"`
+	Owner       *string      `json:"owner,omitempty"`
+	Reminders   *[]Reminder  `json:"reminders,omitempty"`
+	Rrule       *string      `json:"rrule,omitempty"`
+	StartDate   *time.Time   `json:"startDate,omitempty"`
+	Title       *string      `json:"title,omitempty"`
+}
+
+// FreeBusy defines model for FreeBusy.
+type FreeBusy struct {
+	// Busy Merged busy intervals of all owners
+	Busy *[]Interval `json:"busy,omitempty"`
+
+	// Slots Free intervals within the working hours fitting the requested meeting length
+	Slots *[]Interval `json:"slots,omitempty"`
+}
+
+// ImportResult defines model for ImportResult.
+type ImportResult struct {
+	Errors *[]struct {
+		Error *string `json:"error,omitempty"`
+
+		// Index Position of the VEVENT in the document
+		Index *int    `json:"index,omitempty"`
+		Uid   *string `json:"uid,omitempty"`
+	} `json:"errors,omitempty"`
+	Imported *[]struct {
+		Id  *string `json:"id,omitempty"`
+		Uid *string `json:"uid,omitempty"`
+	} `json:"imported,omitempty"`
+}
+
+// Interval defines model for Interval.
+type Interval struct {
+	End   *time.Time `json:"end,omitempty"`
+	Start *time.Time `json:"start,omitempty"`
+}
+
+// Problem RFC 9457 problem details
+type Problem struct {
+	Conflicts     *[]Event `json:"conflicts,omitempty"`
+	Detail        *string  `json:"detail,omitempty"`
+	InvalidParams *[]struct {
+		Name   *string `json:"name,omitempty"`
+		Reason *string `json:"reason,omitempty"`
+	} `json:"invalidParams,omitempty"`
+	Status int    `json:"status"`
+	Title  string `json:"title"`
+	Type   string `json:"type"`
+}
+
+// RSVP defines model for RSVP.
+type RSVP struct {
+	Status RSVPStatus `json:"status"`
+}
+
+// RSVPStatus defines model for RSVPStatus.
+type RSVPStatus string
+
+// Reminder Fires before minutes before every occurrence starts or once at the absolute time, the reminder of the same trigger keeps its sent state while the event start date is kept.
+type Reminder struct {
+	// At Absolute time of the reminder, it reminds of the next occurrence
+	At *time.Time `json:"at,omitempty"`
+
+	// Before Minutes before the occurrence start, the reminder is relative unless at is set
+	Before *int64 `json:"before,omitempty"`
+
+	// IsSend Set once the reminder has nothing left to send
+	IsSend *bool `json:"isSend,omitempty"`
+
+	// SentUntil Start of the last occurrence the relative reminder is sent for
+	SentUntil *time.Time `json:"sentUntil,omitempty"`
+}
+
+// BadRequest RFC 9457 problem details
+type BadRequest = Problem
+
+// Conflict RFC 9457 problem details
+type Conflict = Problem
+
+// Forbidden RFC 9457 problem details
+type Forbidden = Problem
+
+// NotFound RFC 9457 problem details
+type NotFound = Problem
+
+// PreconditionFailed RFC 9457 problem details
+type PreconditionFailed = Problem
+
+// PreconditionRequired RFC 9457 problem details
+type PreconditionRequired = Problem
+
+// Unauthorized RFC 9457 problem details
+type Unauthorized = Problem
+
+// UnprocessableEntity RFC 9457 problem details
+type UnprocessableEntity = Problem
+
+// ListEventsParams defines parameters for ListEvents.
+type ListEventsParams struct {
+	// Owner Owner of events to return
+	Owner *string `form:"owner,omitempty" json:"owner,omitempty"`
+
+	// Title Case-insensitive substring of the event title
+	Title *string `form:"title,omitempty" json:"title,omitempty"`
+
+	// Description Case-insensitive substring of the event description
+	Description *string `form:"description,omitempty" json:"description,omitempty"`
+
+	// From Start date lower bound, inclusive
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+
+	// To Start date upper bound, exclusive
+	To *time.Time `form:"to,omitempty" json:"to,omitempty"`
+
+	// Sort Field to sort events by
+	Sort *ListEventsParamsSort `form:"sort,omitempty" json:"sort,omitempty"`
+
+	// Order Sort order
+	Order *ListEventsParamsOrder `form:"order,omitempty" json:"order,omitempty"`
+
+	// Limit Page size
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Cursor nextCursor of the previous page
+	Cursor *string `form:"cursor,omitempty" json:"cursor,omitempty"`
+}
+
+// ListEventsParamsSort defines parameters for ListEvents.
+type ListEventsParamsSort string
+
+// ListEventsParamsOrder defines parameters for ListEvents.
+type ListEventsParamsOrder string
+
+// UpdateEventParams defines parameters for UpdateEvent.
+type UpdateEventParams struct {
+	// IfMatch ETag of the event version the update is based on, as returned by the previous response
+	IfMatch string `json:"If-Match"`
+}
+
+// PatchEventParams defines parameters for PatchEvent.
+type PatchEventParams struct {
+	// IfMatch ETag of the event version the update is based on, as returned by the previous response
+	IfMatch string `json:"If-Match"`
+}
+
+// ExportEventsParams defines parameters for ExportEvents.
+type ExportEventsParams struct {
+	// From Period start, inclusive
+	From time.Time `form:"from" json:"from"`
+
+	// To Period end, exclusive
+	To time.Time `form:"to" json:"to"`
+}
+
+// GetDayEventsParams defines parameters for GetDayEvents.
+type GetDayEventsParams struct {
+	// Date Day to return events for, today if omitted
+	Date *openapi_types.Date `form:"date,omitempty" json:"date,omitempty"`
+
+	// TimeZone IANA time zone the period is aligned to, UTC if omitted
+	TimeZone *string `form:"timeZone,omitempty" json:"timeZone,omitempty"`
+}
+
+// GetMonthEventsParams defines parameters for GetMonthEvents.
+type GetMonthEventsParams struct {
+	// Date Any day of the month to return events for, today if omitted
+	Date *openapi_types.Date `form:"date,omitempty" json:"date,omitempty"`
+
+	// TimeZone IANA time zone the period is aligned to, UTC if omitted
+	TimeZone *string `form:"timeZone,omitempty" json:"timeZone,omitempty"`
+}
+
+// GetWeekEventsParams defines parameters for GetWeekEvents.
+type GetWeekEventsParams struct {
+	// Date Any day of the week to return events for, weeks start on Monday, today if omitted
+	Date *openapi_types.Date `form:"date,omitempty" json:"date,omitempty"`
+
+	// TimeZone IANA time zone the period is aligned to, UTC if omitted
+	TimeZone *string `form:"timeZone,omitempty" json:"timeZone,omitempty"`
+}
+
+// GetFreeBusyParams defines parameters for GetFreeBusy.
+type GetFreeBusyParams struct {
+	// Owners Owners to find a meeting slot for
+	Owners []string `form:"owners" json:"owners"`
+
+	// From Period start, inclusive
+	From time.Time `form:"from" json:"from"`
+
+	// To Period end, exclusive, at most 31 days after the start
+	To time.Time `form:"to" json:"to"`
+
+	// SlotMinutes Meeting length in minutes
+	SlotMinutes *int `form:"slotMinutes,omitempty" json:"slotMinutes,omitempty"`
+
+	// WorkdayStart Working hours start as HH:MM in the time zone
+	WorkdayStart *string `form:"workdayStart,omitempty" json:"workdayStart,omitempty"`
+
+	// WorkdayEnd Working hours end as HH:MM in the time zone, 24:00 is the end of the day
+	WorkdayEnd *string `form:"workdayEnd,omitempty" json:"workdayEnd,omitempty"`
+
+	// TimeZone IANA time zone of the working hours, UTC by default
+	TimeZone *string `form:"timeZone,omitempty" json:"timeZone,omitempty"`
 }
 
 // CreateEventJSONRequestBody defines body for CreateEvent for application/json ContentType.
@@ -30,26 +334,65 @@ type CreateEventJSONRequestBody = Event
 // UpdateEventJSONRequestBody defines body for UpdateEvent for application/json ContentType.
 type UpdateEventJSONRequestBody = Event
 
+// PatchEventApplicationMergePatchPlusJSONRequestBody defines body for PatchEvent for application/merge-patch+json ContentType.
+type PatchEventApplicationMergePatchPlusJSONRequestBody = EventPatch
+
+// RespondToInvitationJSONRequestBody defines body for RespondToInvitation for application/json ContentType.
+type RespondToInvitationJSONRequestBody = RSVP
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
 	// Delete an existing calendar event
 	// (DELETE /event)
 	DeleteEvent(w http.ResponseWriter, r *http.Request)
+	// List calendar events
+	// (GET /event)
+	ListEvents(w http.ResponseWriter, r *http.Request, params ListEventsParams)
 	// Create calendar event
 	// (POST /event)
 	CreateEvent(w http.ResponseWriter, r *http.Request)
 	// Update an existing calendar event
 	// (PUT /event)
-	UpdateEvent(w http.ResponseWriter, r *http.Request)
+	UpdateEvent(w http.ResponseWriter, r *http.Request, params UpdateEventParams)
+	// Delete a calendar event
+	// (DELETE /event/{id})
+	DeleteEventByID(w http.ResponseWriter, r *http.Request, id string)
+	// Get a calendar event
+	// (GET /event/{id})
+	GetEvent(w http.ResponseWriter, r *http.Request, id string)
+	// Partially update a calendar event
+	// (PATCH /event/{id})
+	PatchEvent(w http.ResponseWriter, r *http.Request, id string, params PatchEventParams)
+	// Get the change history of a calendar event
+	// (GET /event/{id}/history)
+	GetEventHistory(w http.ResponseWriter, r *http.Request, id string)
+	// Reply to an event invitation
+	// (PUT /event/{id}/rsvp)
+	RespondToInvitation(w http.ResponseWriter, r *http.Request, id string)
+	// Export owner events as iCalendar
+	// (GET /event/{owner}/export)
+	ExportEvents(w http.ResponseWriter, r *http.Request, owner string, params ExportEventsParams)
 	// Get day events an existing calendar event
 	// (GET /event/{owner}/getDay)
-	GetDayEvents(w http.ResponseWriter, r *http.Request, owner string)
+	GetDayEvents(w http.ResponseWriter, r *http.Request, owner string, params GetDayEventsParams)
 	// Get month events an existing calendar event
 	// (GET /event/{owner}/getMonth)
-	GetMonthEvents(w http.ResponseWriter, r *http.Request, owner string)
+	GetMonthEvents(w http.ResponseWriter, r *http.Request, owner string, params GetMonthEventsParams)
 	// Get week events an existing calendar event
 	// (GET /event/{owner}/getWeek)
-	GetWeekEvents(w http.ResponseWriter, r *http.Request, owner string)
+	GetWeekEvents(w http.ResponseWriter, r *http.Request, owner string, params GetWeekEventsParams)
+	// Import iCalendar events
+	// (POST /event/{owner}/import)
+	ImportEvents(w http.ResponseWriter, r *http.Request, owner string)
+	// Find free meeting slots of several owners
+	// (GET /freebusy)
+	GetFreeBusy(w http.ResponseWriter, r *http.Request, params GetFreeBusyParams)
+	// List deleted calendar events
+	// (GET /trash)
+	ListTrash(w http.ResponseWriter, r *http.Request)
+	// Restore a deleted calendar event
+	// (POST /trash/{id}/restore)
+	RestoreEvent(w http.ResponseWriter, r *http.Request, id string)
 }
 
 // ServerInterfaceWrapper converts contexts to parameters.
@@ -64,6 +407,14 @@ type MiddlewareFunc func(http.Handler) http.Handler
 // DeleteEvent operation middleware
 func (siw *ServerInterfaceWrapper) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.DeleteEvent(w, r)
 	}))
@@ -75,9 +426,116 @@ func (siw *ServerInterfaceWrapper) DeleteEvent(w http.ResponseWriter, r *http.Re
 	handler.ServeHTTP(w, r)
 }
 
+// ListEvents operation middleware
+func (siw *ServerInterfaceWrapper) ListEvents(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListEventsParams
+
+	// ------------- Optional query parameter "owner" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "owner", r.URL.Query(), &params.Owner)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "owner", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "title" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "title", r.URL.Query(), &params.Title)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "title", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "description" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "description", r.URL.Query(), &params.Description)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "description", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "from" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "from", r.URL.Query(), &params.From)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "from", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "to" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "to", r.URL.Query(), &params.To)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "to", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", r.URL.Query(), &params.Sort)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "sort", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "order" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "order", r.URL.Query(), &params.Order)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "order", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "cursor" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "cursor", r.URL.Query(), &params.Cursor)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "cursor", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListEvents(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 // CreateEvent operation middleware
 func (siw *ServerInterfaceWrapper) CreateEvent(w http.ResponseWriter, r *http.Request) {
 
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.CreateEvent(w, r)
 	}))
@@ -92,8 +550,46 @@ func (siw *ServerInterfaceWrapper) CreateEvent(w http.ResponseWriter, r *http.Re
 // UpdateEvent operation middleware
 func (siw *ServerInterfaceWrapper) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params UpdateEventParams
+
+	headers := r.Header
+
+	// ------------- Required header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch string
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "If-Match", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithOptions("simple", "If-Match", valueList[0], &IfMatch, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: true})
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "If-Match", Err: err})
+			return
+		}
+
+		params.IfMatch = IfMatch
+
+	} else {
+		err := fmt.Errorf("Header parameter If-Match is required, but not found")
+		siw.ErrorHandlerFunc(w, r, &RequiredHeaderError{ParamName: "If-Match", Err: err})
+		return
+	}
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.UpdateEvent(w, r)
+		siw.Handler.UpdateEvent(w, r, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -103,22 +599,30 @@ func (siw *ServerInterfaceWrapper) UpdateEvent(w http.ResponseWriter, r *http.Re
 	handler.ServeHTTP(w, r)
 }
 
-// GetDayEvents operation middleware
-func (siw *ServerInterfaceWrapper) GetDayEvents(w http.ResponseWriter, r *http.Request) {
+// DeleteEventByID operation middleware
+func (siw *ServerInterfaceWrapper) DeleteEventByID(w http.ResponseWriter, r *http.Request) {
 
 	var err error
 
-	// ------------- Path parameter "owner" -------------
-	var owner string
+	// ------------- Path parameter "id" -------------
+	var id string
 
-	err = runtime.BindStyledParameterWithOptions("simple", "owner", r.PathValue("owner"), &owner, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
 	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "owner", Err: err})
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
 		return
 	}
 
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetDayEvents(w, r, owner)
+		siw.Handler.DeleteEventByID(w, r, id)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -128,22 +632,30 @@ func (siw *ServerInterfaceWrapper) GetDayEvents(w http.ResponseWriter, r *http.R
 	handler.ServeHTTP(w, r)
 }
 
-// GetMonthEvents operation middleware
-func (siw *ServerInterfaceWrapper) GetMonthEvents(w http.ResponseWriter, r *http.Request) {
+// GetEvent operation middleware
+func (siw *ServerInterfaceWrapper) GetEvent(w http.ResponseWriter, r *http.Request) {
 
 	var err error
 
-	// ------------- Path parameter "owner" -------------
-	var owner string
+	// ------------- Path parameter "id" -------------
+	var id string
 
-	err = runtime.BindStyledParameterWithOptions("simple", "owner", r.PathValue("owner"), &owner, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
 	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "owner", Err: err})
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
 		return
 	}
 
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetMonthEvents(w, r, owner)
+		siw.Handler.GetEvent(w, r, id)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -153,22 +665,538 @@ func (siw *ServerInterfaceWrapper) GetMonthEvents(w http.ResponseWriter, r *http
 	handler.ServeHTTP(w, r)
 }
 
-// GetWeekEvents operation middleware
-func (siw *ServerInterfaceWrapper) GetWeekEvents(w http.ResponseWriter, r *http.Request) {
+// PatchEvent operation middleware
+func (siw *ServerInterfaceWrapper) PatchEvent(w http.ResponseWriter, r *http.Request) {
 
 	var err error
 
-	// ------------- Path parameter "owner" -------------
-	var owner string
+	// ------------- Path parameter "id" -------------
+	var id string
 
-	err = runtime.BindStyledParameterWithOptions("simple", "owner", r.PathValue("owner"), &owner, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
 	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "owner", Err: err})
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PatchEventParams
+
+	headers := r.Header
+
+	// ------------- Required header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch string
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "If-Match", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithOptions("simple", "If-Match", valueList[0], &IfMatch, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: true})
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "If-Match", Err: err})
+			return
+		}
+
+		params.IfMatch = IfMatch
+
+	} else {
+		err := fmt.Errorf("Header parameter If-Match is required, but not found")
+		siw.ErrorHandlerFunc(w, r, &RequiredHeaderError{ParamName: "If-Match", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PatchEvent(w, r, id, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetEventHistory operation middleware
+func (siw *ServerInterfaceWrapper) GetEventHistory(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
 		return
 	}
 
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetEventHistory(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// RespondToInvitation operation middleware
+func (siw *ServerInterfaceWrapper) RespondToInvitation(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.RespondToInvitation(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ExportEvents operation middleware
+func (siw *ServerInterfaceWrapper) ExportEvents(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "owner" -------------
+	var owner string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "owner", r.PathValue("owner"), &owner, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "owner", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ExportEventsParams
+
+	// ------------- Required query parameter "from" -------------
+
+	if paramValue := r.URL.Query().Get("from"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "from"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "from", r.URL.Query(), &params.From)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "from", Err: err})
+		return
+	}
+
+	// ------------- Required query parameter "to" -------------
+
+	if paramValue := r.URL.Query().Get("to"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "to"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "to", r.URL.Query(), &params.To)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "to", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ExportEvents(w, r, owner, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetDayEvents operation middleware
+func (siw *ServerInterfaceWrapper) GetDayEvents(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "owner" -------------
+	var owner string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "owner", r.PathValue("owner"), &owner, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "owner", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetDayEventsParams
+
+	// ------------- Optional query parameter "date" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "date", r.URL.Query(), &params.Date)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "date", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "timeZone" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "timeZone", r.URL.Query(), &params.TimeZone)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "timeZone", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetDayEvents(w, r, owner, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetMonthEvents operation middleware
+func (siw *ServerInterfaceWrapper) GetMonthEvents(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "owner" -------------
+	var owner string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "owner", r.PathValue("owner"), &owner, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "owner", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetMonthEventsParams
+
+	// ------------- Optional query parameter "date" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "date", r.URL.Query(), &params.Date)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "date", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "timeZone" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "timeZone", r.URL.Query(), &params.TimeZone)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "timeZone", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetMonthEvents(w, r, owner, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetWeekEvents operation middleware
+func (siw *ServerInterfaceWrapper) GetWeekEvents(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "owner" -------------
+	var owner string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "owner", r.PathValue("owner"), &owner, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "owner", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetWeekEventsParams
+
+	// ------------- Optional query parameter "date" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "date", r.URL.Query(), &params.Date)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "date", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "timeZone" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "timeZone", r.URL.Query(), &params.TimeZone)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "timeZone", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetWeekEvents(w, r, owner, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ImportEvents operation middleware
+func (siw *ServerInterfaceWrapper) ImportEvents(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "owner" -------------
+	var owner string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "owner", r.PathValue("owner"), &owner, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "owner", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ImportEvents(w, r, owner)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetFreeBusy operation middleware
+func (siw *ServerInterfaceWrapper) GetFreeBusy(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetFreeBusyParams
+
+	// ------------- Required query parameter "owners" -------------
+
+	if paramValue := r.URL.Query().Get("owners"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "owners"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "owners", r.URL.Query(), &params.Owners)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "owners", Err: err})
+		return
+	}
+
+	// ------------- Required query parameter "from" -------------
+
+	if paramValue := r.URL.Query().Get("from"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "from"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "from", r.URL.Query(), &params.From)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "from", Err: err})
+		return
+	}
+
+	// ------------- Required query parameter "to" -------------
+
+	if paramValue := r.URL.Query().Get("to"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "to"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "to", r.URL.Query(), &params.To)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "to", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "slotMinutes" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "slotMinutes", r.URL.Query(), &params.SlotMinutes)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "slotMinutes", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "workdayStart" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "workdayStart", r.URL.Query(), &params.WorkdayStart)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "workdayStart", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "workdayEnd" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "workdayEnd", r.URL.Query(), &params.WorkdayEnd)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "workdayEnd", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "timeZone" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "timeZone", r.URL.Query(), &params.TimeZone)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "timeZone", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetFreeBusy(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ListTrash operation middleware
+func (siw *ServerInterfaceWrapper) ListTrash(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListTrash(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// RestoreEvent operation middleware
+func (siw *ServerInterfaceWrapper) RestoreEvent(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, IdentityHeaderScopes, []string{})
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetWeekEvents(w, r, owner)
+		siw.Handler.RestoreEvent(w, r, id)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -299,11 +1327,22 @@ func HandlerWithOptions(si ServerInterface, options StdHTTPServerOptions) http.H
 	}
 
 	m.HandleFunc("DELETE "+options.BaseURL+"/event", wrapper.DeleteEvent)
+	m.HandleFunc("GET "+options.BaseURL+"/event", wrapper.ListEvents)
 	m.HandleFunc("POST "+options.BaseURL+"/event", wrapper.CreateEvent)
 	m.HandleFunc("PUT "+options.BaseURL+"/event", wrapper.UpdateEvent)
+	m.HandleFunc("DELETE "+options.BaseURL+"/event/{id}", wrapper.DeleteEventByID)
+	m.HandleFunc("GET "+options.BaseURL+"/event/{id}", wrapper.GetEvent)
+	m.HandleFunc("PATCH "+options.BaseURL+"/event/{id}", wrapper.PatchEvent)
+	m.HandleFunc("GET "+options.BaseURL+"/event/{id}/history", wrapper.GetEventHistory)
+	m.HandleFunc("PUT "+options.BaseURL+"/event/{id}/rsvp", wrapper.RespondToInvitation)
+	m.HandleFunc("GET "+options.BaseURL+"/event/{owner}/export", wrapper.ExportEvents)
 	m.HandleFunc("GET "+options.BaseURL+"/event/{owner}/getDay", wrapper.GetDayEvents)
 	m.HandleFunc("GET "+options.BaseURL+"/event/{owner}/getMonth", wrapper.GetMonthEvents)
 	m.HandleFunc("GET "+options.BaseURL+"/event/{owner}/getWeek", wrapper.GetWeekEvents)
+	m.HandleFunc("POST "+options.BaseURL+"/event/{owner}/import", wrapper.ImportEvents)
+	m.HandleFunc("GET "+options.BaseURL+"/freebusy", wrapper.GetFreeBusy)
+	m.HandleFunc("GET "+options.BaseURL+"/trash", wrapper.ListTrash)
+	m.HandleFunc("POST "+options.BaseURL+"/trash/{id}/restore", wrapper.RestoreEvent)
 
 	return m
 }
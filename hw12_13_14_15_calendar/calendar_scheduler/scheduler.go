@@ -3,25 +3,64 @@ package main
 //nolint:depguard
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"os/signal"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/app"
-	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/kafka"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker/factory"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/health"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/metrics"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/outbox"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
 	memorystorage "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
 	sqlstorage "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage/sql"
 	"github.com/go-co-op/gocron/v2"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 var configFile string
 
+// clearBatchSize is the number of ended events moved to the trash at once.
+const clearBatchSize = 100
+
+// DefaultPurgeRetention is how long the deleted events are kept in the trash unless configured.
+const DefaultPurgeRetention = 30 * 24 * time.Hour
+
+// DefaultLeaderLockID is the Postgres advisory lock of the scheduler leadership unless configured.
+const DefaultLeaderLockID = 0x63616c656e646172 // "calendar"
+
+var (
+	errCronStopped = errors.New("cron scheduler is not running")
+	errJobFailed   = errors.New("last job run failed")
+)
+
+// Elector elects the replica running the scheduler jobs, so the reminders aren't sent twice.
+type Elector interface {
+	gocron.Elector
+	Resign(ctx context.Context) error
+}
+
+// Storage is the event storage with the reminders outbox.
+type Storage interface {
+	app.Storage
+	outbox.Storage
+	GetEventsEndedBefore(ctx context.Context, before time.Time, limit int) ([]storage.Event, error)
+	DeleteEvents(ctx context.Context, ids []string) (int, error)
+	PurgeEvents(ctx context.Context, deletedBefore time.Time) (int, error)
+}
+
 func init() {
 	flag.StringVar(&configFile, "config", "../calendar_scheduler/config.toml", "Path to configuration file")
 }
@@ -42,9 +81,24 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	defer cancel()
 
-	var storage app.Storage
+	if flag.Arg(0) == "migrate" {
+		err := sqlstorage.MigrateCommand(ctx, &config.DB, flag.Args()[1:], logg)
+		if err != nil {
+			cancel()
+			logg.Fatal("database migration failed", zap.Error(err))
+		}
+
+		return
+	}
+
+	var storage Storage
+	var elector Elector
+	cron := &cronState{}
+	checks := []health.Check{{Name: "cron", Probe: cron.Ping, Describe: cron.Describe}}
 	if config.DB.InMemory {
-		storage = memorystorage.New()
+		storageMemory := memorystorage.New()
+		storage = storageMemory
+		elector = storageMemory.NewElector(uuid.NewString())
 	} else {
 		storageSQL := sqlstorage.New()
 		defer storageSQL.Close(ctx)
@@ -55,12 +109,36 @@ func main() {
 		}
 
 		logg.Info("database connection created")
+		if config.DB.Migrate {
+			migrations, err := storageSQL.MigrateUp(ctx)
+			if err != nil {
+				logg.Error("database migration failed", zap.Error(err))
+				return
+			}
+
+			logg.Info("database migrated", zap.Int("applied", len(migrations)))
+		}
+
+		lockID := config.Schedule.LeaderLockID
+		if lockID == 0 {
+			lockID = DefaultLeaderLockID
+		}
+
 		storage = storageSQL
+		elector = storageSQL.NewElector(lockID)
+		checks = append(checks, health.Check{Name: "database", Probe: storageSQL.Ping})
 	}
+	defer elector.Resign(context.Background())
 
-	producer, err := kafka.NewProducer(config.Kafka, logg)
+	publisher, err := factory.NewPublisher(config, logg)
 	if err != nil {
-		logg.Error("kafka producer creation failed", zap.Error(err))
+		logg.Error("message publisher creation failed", zap.Error(err))
+		return
+	}
+	defer publisher.Close()
+
+	if pinger, ok := publisher.(broker.Pinger); ok {
+		checks = append(checks, health.Check{Name: "broker", Probe: pinger.Ping})
 	}
 
 	wg := sync.WaitGroup{}
@@ -68,87 +146,260 @@ func main() {
 
 	go func() {
 		defer wg.Done()
+		defer cancel()
 
-		err := startJob(ctx, storage, logg, producer, config.Schedule)
+		leader := &leaderElector{Elector: elector, logger: logg, cron: cron}
+		err := startJob(ctx, storage, leader, cron, logg, publisher, config.Schedule)
 		if err != nil {
 			logg.Error("cron job creation failed", zap.Error(err))
 		}
 	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		if err := metrics.Serve(ctx, config.Metrics, logg, checks...); err != nil {
+			logg.Error("metrics server failed", zap.Error(err))
+		}
+	}()
+
 	wg.Wait()
 }
 
-//nolint:lll
-func startJob(ctx context.Context, storage app.Storage, logger *zap.Logger, producer *kafka.Producer, config configs.ScheduleConfig) error {
-	scheduler, err := gocron.NewScheduler()
+func startJob(ctx context.Context,
+	storage Storage,
+	elector gocron.Elector,
+	cron *cronState,
+	logger *zap.Logger,
+	publisher broker.Publisher,
+	config configs.ScheduleConfig,
+) error {
+	scheduler, err := gocron.NewScheduler(gocron.WithDistributedElector(elector))
 	if err != nil {
 		return err
 	}
 
 	defer scheduler.Shutdown()
-	_, err = scheduler.NewJob(gocron.CronJob(config.Cron, false), gocron.NewTask(clearEvents, ctx, storage, logger))
+	_, err = scheduler.NewJob(gocron.CronJob(config.Cron, false), gocron.NewTask(clearEvents, ctx, storage, cron, logger))
+	if err != nil {
+		return err
+	}
+
+	retention := config.PurgeRetention
+	if retention <= 0 {
+		retention = DefaultPurgeRetention
+	}
+
+	_, err = scheduler.NewJob(gocron.CronJob(config.Cron, false),
+		gocron.NewTask(purgeEvents, ctx, storage, cron, logger, retention))
+	if err != nil {
+		return err
+	}
+
+	_, err = scheduler.NewJob(gocron.CronJob(config.Cron, false), gocron.NewTask(sendEvents, ctx, storage, cron, logger))
 	if err != nil {
 		return err
 	}
 
-	_, err = scheduler.NewJob(gocron.CronJob(config.Cron, false), gocron.NewTask(sendEvents, ctx, storage, logger, producer))
+	relay := outbox.NewRelay(storage, publisher, logger)
+	_, err = scheduler.NewJob(gocron.CronJob(config.Cron, false), gocron.NewTask(relayEvents, ctx, relay, cron, logger),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule))
 	if err != nil {
 		return err
 	}
 
 	scheduler.Start()
+	cron.running.Store(true)
+	defer cron.running.Store(false)
+
 	<-ctx.Done()
 	return nil
 }
 
-func clearEvents(ctx context.Context, storage app.Storage, logger *zap.Logger) {
-	logger.Info("clear event job: start")
+// cronState reports whether the cron scheduler runs the jobs, a replica which isn't the leader
+// runs the scheduler too and is ready to take over. The leader isn't ready while the last run of a job failed.
+type cronState struct {
+	running atomic.Bool
+	mu      sync.Mutex
+	leader  bool
+	failed  map[string]error
+}
 
-	yearAgo := time.Now().AddDate(-1, 0, 0)
-	events, err := storage.GetEvents(ctx)
-	if err != nil {
+func (s *cronState) Ping(_ context.Context) error {
+	if !s.running.Load() {
+		return errCronStopped
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]string, 0, len(s.failed))
+	for job := range s.failed {
+		jobs = append(jobs, job)
+	}
+	sort.Strings(jobs)
+
+	errs := make([]error, 0, len(jobs))
+	for _, job := range jobs {
+		errs = append(errs, fmt.Errorf("%w: %s: %w", errJobFailed, job, s.failed[job]))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Describe reports whether the replica runs the jobs as the leader or waits as a standby.
+func (s *cronState) Describe() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.leader {
+		return "leader"
+	}
+
+	return "standby"
+}
+
+// setLeader records the leadership, the job results of the previous leadership are forgotten.
+func (s *cronState) setLeader(leader bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.leader != leader {
+		s.failed = nil
+	}
+
+	s.leader = leader
+}
+
+// jobDone counts the job run and keeps its error until the job succeeds.
+func (s *cronState) jobDone(job string, err error) {
+	metrics.SchedulerJobRuns.WithLabelValues(job, metrics.Result(err)).Inc()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil {
+		delete(s.failed, job)
 		return
 	}
 
-	for _, event := range events {
-		if event.StartDate.Before(yearAgo) {
-			logger.Info("clear event job: delete event", zap.Any("event", event))
+	if s.failed == nil {
+		s.failed = make(map[string]error)
+	}
 
-			err := storage.DeleteEvent(ctx, event.ID)
-			if err != nil {
-				logger.Error("clear event job: failed to delete event", zap.Error(err))
-			}
-		}
+	s.failed[job] = err
+}
+
+// leaderElector logs when the replica takes or loses the leadership,
+// the jobs run only on the leader and the other replicas take over when it dies.
+type leaderElector struct {
+	Elector
+	logger *zap.Logger
+	cron   *cronState
+	mu     sync.Mutex
+	leader bool
+}
+
+func (e *leaderElector) IsLeader(ctx context.Context) error {
+	err := e.Elector.IsLeader(ctx)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch {
+	case err == nil && !e.leader:
+		e.logger.Info("scheduler: leadership taken")
+	case err != nil && e.leader:
+		e.logger.Warn("scheduler: leadership lost", zap.Error(err))
 	}
 
+	e.leader = err == nil
+	e.cron.setLeader(e.leader)
+	return err
+}
+
+// clearEvents moves the events which ended over a year ago to the trash.
+func clearEvents(ctx context.Context, storage Storage, cron *cronState, logger *zap.Logger) {
+	logger.Info("clear event job: start")
+
+	err := clearEndedEvents(ctx, storage, logger, time.Now().AddDate(-1, 0, 0))
+	cron.jobDone("clear", err)
+
 	logger.Info("clear event job: end")
 }
 
-func sendEvents(ctx context.Context, storage app.Storage, logger *zap.Logger, producer *kafka.Producer) {
-	logger.Info("send event job: start")
+// clearEndedEvents moves the events ended before the given time to the trash batch by batch.
+func clearEndedEvents(ctx context.Context, storage Storage, logger *zap.Logger, endedBefore time.Time) error {
+	for {
+		events, err := storage.GetEventsEndedBefore(ctx, endedBefore, clearBatchSize)
+		if err != nil {
+			logger.Error("clear event job: failed to get ended events", zap.Error(err))
+			return err
+		}
+
+		ids := make([]string, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+
+		deleted, err := storage.DeleteEvents(ctx, ids)
+		if err != nil {
+			logger.Error("clear event job: failed to move events to trash", zap.Error(err))
+			return err
+		}
+
+		if deleted > 0 {
+			logger.Info("clear event job: events moved to trash", zap.Int("count", deleted))
+		}
 
-	timeNow := time.Now()
-	events, err := storage.GetEvents(ctx)
+		if len(events) < clearBatchSize || deleted == 0 {
+			return nil
+		}
+	}
+}
+
+// purgeEvents removes the events kept in the trash longer than the retention permanently.
+func purgeEvents(ctx context.Context,
+	storage Storage,
+	cron *cronState,
+	logger *zap.Logger,
+	retention time.Duration,
+) {
+	purged, err := storage.PurgeEvents(ctx, time.Now().Add(-retention))
+	cron.jobDone("purge", err)
 	if err != nil {
+		logger.Error("purge event job: failed to purge events", zap.Error(err))
 		return
 	}
 
-	for _, event := range events {
-		if !event.IsSend && event.StartDate.Add(time.Minute*time.Duration(event.RemindAt)).Before(timeNow) {
-			eventLoop := event
-			err := producer.SendEventMessage(&eventLoop)
-			if err != nil {
-				logger.Error("send event job: failed to send event", zap.Error(err))
-				continue
-			}
+	if purged > 0 {
+		logger.Info("purge event job: events purged", zap.Int("count", purged))
+	}
+}
 
-			event.IsSend = true
-			err = storage.UpdateEvent(ctx, &eventLoop)
-			if err != nil {
-				logger.Error("send event job: failed to update event", zap.Error(err))
-				continue
-			}
-		}
+// sendEvents enqueues the due reminders to the outbox, the reminders are published by relayEvents.
+func sendEvents(ctx context.Context, repository outbox.Storage, cron *cronState, logger *zap.Logger) {
+	logger.Info("send event job: start")
+
+	err := outbox.EnqueueReminders(ctx, repository, logger, time.Now())
+	cron.jobDone("send", err)
+	if err != nil {
+		logger.Error("send event job: failed to enqueue reminders", zap.Error(err))
 	}
 
 	logger.Info("send event job: end")
 }
+
+func relayEvents(ctx context.Context, relay *outbox.Relay, cron *cronState, logger *zap.Logger) {
+	published, err := relay.Run(ctx)
+	cron.jobDone("relay", err)
+	metrics.RemindersSent.Add(float64(published))
+	if err != nil {
+		logger.Error("relay event job: failed to publish reminders", zap.Error(err))
+	}
+
+	if published > 0 {
+		logger.Info("relay event job: reminders published", zap.Int("count", published))
+	}
+}
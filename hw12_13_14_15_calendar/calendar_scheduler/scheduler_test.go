@@ -0,0 +1,64 @@
+package main
+
+//nolint:depguard
+import (
+	"context"
+	"testing"
+	"time"
+
+	memorybroker "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker/memory"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/outbox"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestJobs(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	repository := memorystorage.New()
+	messages := memorybroker.New(0)
+	relay := outbox.NewRelay(repository, messages, logger)
+	cron := &cronState{}
+	cron.running.Store(true)
+	cron.setLeader(true)
+
+	event := storage.Event{
+		ID:        "0193c6c8-7b1e-7cde-8d7c-3b1f8f6b2a40",
+		Title:     "standup",
+		Owner:     "test_user",
+		StartDate: time.Now().Add(30 * time.Minute).UTC().Truncate(time.Second),
+		Duration:  time.Hour,
+		Reminders: []storage.EventReminder{{Before: 60}},
+	}
+	require.NoError(t, repository.CreateEvent(ctx, &event))
+
+	t.Run("reminders published", func(t *testing.T) {
+		sendEvents(ctx, repository, cron, logger)
+		relayEvents(ctx, relay, cron, logger)
+
+		require.Equal(t, 1, messages.Len())
+		require.NoError(t, cron.Ping(ctx))
+		require.Equal(t, "leader", cron.Describe())
+	})
+
+	t.Run("failed job reported until leadership changes", func(t *testing.T) {
+		later := event
+		later.ID = "0193c6c8-7b1e-7cde-8d7c-3b1f8f6b2a41"
+		require.NoError(t, repository.CreateEvent(ctx, &later))
+		require.NoError(t, messages.Close())
+
+		sendEvents(ctx, repository, cron, logger)
+		relayEvents(ctx, relay, cron, logger)
+
+		err := cron.Ping(ctx)
+		require.ErrorIs(t, err, errJobFailed)
+		require.ErrorIs(t, err, memorybroker.ErrBrokerClosed)
+
+		cron.setLeader(false)
+		require.NoError(t, cron.Ping(ctx))
+		require.Equal(t, "standby", cron.Describe())
+	})
+}
@@ -0,0 +1,122 @@
+package main
+
+//nolint:depguard
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker/factory"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/kafka"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/sender"
+	memorystorage "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	sqlstorage "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage/sql"
+	"go.uber.org/zap"
+)
+
+var configFile string
+
+func init() {
+	flag.StringVar(&configFile, "config", "../calendar_sender/config.toml", "Path to configuration file")
+}
+
+func main() {
+	flag.Parse()
+
+	config, err := configs.ReadConfig(configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	logg, err := logger.New(config.Logger.Level, config.Logger.Path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer cancel()
+
+	if flag.Arg(0) == "dead-letter" {
+		runDeadLetter(ctx, config.Kafka, logg)
+		return
+	}
+
+	// the channel failures are published to the produce topic and replayed to a single channel,
+	// the consumer dead letters are replayed to every channel, so they can't share a topic
+	if config.Kafka.DeadLetterTopic != "" && config.Kafka.DeadLetterTopic == config.Kafka.ProduceTopic {
+		logg.Error("invalid kafka configuration: dead letter topic must differ from produce topic")
+		return
+	}
+
+	if config.AMQP.DeadLetterQueue != "" && config.AMQP.DeadLetterQueue == config.AMQP.ProduceQueue {
+		logg.Error("invalid amqp configuration: dead letter queue must differ from produce queue")
+		return
+	}
+
+	channels, err := sender.NewChannels(config.Sender, logg)
+	if err != nil {
+		logg.Error("notification channels creation failed", zap.Error(err))
+		return
+	}
+
+	// the deliveries of the in-memory storage are lost on restart, so a redelivered reminder is sent again
+	var deliveries sender.Deliveries
+	if config.DB.InMemory {
+		deliveries = memorystorage.New()
+	} else {
+		storageSQL := sqlstorage.New()
+		defer storageSQL.Close(ctx)
+
+		if err := storageSQL.Connect(ctx, &config.DB); err != nil {
+			logg.Error("database connection failed", zap.Error(err))
+			return
+		}
+
+		logg.Info("database connection created")
+		deliveries = storageSQL
+	}
+
+	deadLetter, err := factory.NewPublisher(config, logg)
+	if err != nil {
+		logg.Error("dead letter publisher creation failed", zap.Error(err))
+		return
+	}
+	defer deadLetter.Close()
+
+	subscriber, err := factory.NewSubscriber(config, logg)
+	if err != nil {
+		logg.Error("message subscriber creation failed", zap.Error(err))
+		return
+	}
+	defer subscriber.Close()
+
+	notificationSender := sender.New(channels, deadLetter, deliveries, config.Sender, logg)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		err := subscriber.Subscribe(ctx, notificationSender.Handle)
+		if err != nil {
+			logg.Error("register message consumer failed", zap.Error(err))
+			return
+		}
+	}()
+
+	wg.Wait()
+}
+
+func runDeadLetter(ctx context.Context, config configs.KafkaConfig, logg *zap.Logger) {
+	err := kafka.DeadLetterCommand(ctx, config, flag.Args()[1:], os.Stdout)
+	if err != nil {
+		logg.Error("dead letter command failed", zap.Error(err))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"flag"
 	"log"
+	"os"
 	"os/signal"
 	"sync"
 	"syscall"
@@ -13,12 +14,15 @@ import (
 
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker/factory"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/health"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/kafka"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/metrics"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
 	memorystorage "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
 	sqlstorage "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage/sql"
-	"github.com/IBM/sarama"
 	"github.com/google/uuid"
 	jsoniter "github.com/json-iterator/go"
 	"go.uber.org/zap"
@@ -46,7 +50,23 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	defer cancel()
 
+	if flag.Arg(0) == "dead-letter" {
+		runDeadLetter(ctx, config.Kafka, logg)
+		return
+	}
+
+	if flag.Arg(0) == "migrate" {
+		err := sqlstorage.MigrateCommand(ctx, &config.DB, flag.Args()[1:], logg)
+		if err != nil {
+			cancel()
+			logg.Fatal("database migration failed", zap.Error(err))
+		}
+
+		return
+	}
+
 	var storage app.Storage
+	var checks []health.Check
 	if config.DB.InMemory {
 		storage = memorystorage.New()
 	} else {
@@ -59,7 +79,29 @@ func main() {
 		}
 
 		logg.Info("database connection created")
+		if config.DB.Migrate {
+			migrations, err := storageSQL.MigrateUp(ctx)
+			if err != nil {
+				logg.Error("database migration failed", zap.Error(err))
+				return
+			}
+
+			logg.Info("database migrated", zap.Int("applied", len(migrations)))
+		}
+
 		storage = storageSQL
+		checks = append(checks, health.Check{Name: "database", Probe: storageSQL.Ping})
+	}
+
+	subscriber, err := factory.NewSubscriber(config, logg)
+	if err != nil {
+		logg.Error("message subscriber creation failed", zap.Error(err))
+		return
+	}
+	defer subscriber.Close()
+
+	if pinger, ok := subscriber.(broker.Pinger); ok {
+		checks = append(checks, health.Check{Name: "broker", Probe: pinger.Ping})
 	}
 
 	wg := sync.WaitGroup{}
@@ -67,28 +109,32 @@ func main() {
 
 	go func() {
 		defer wg.Done()
+		defer cancel()
 
-		consumer := kafka.Consumer{
-			Config:         config.Kafka,
-			Logger:         *logg,
-			Context:        ctx,
-			Storage:        storage,
-			ConsumeMessage: consumeMessage,
-		}
-
-		err := consumer.RegisterMessageConsumer()
+		err := subscriber.Subscribe(ctx, func(ctx context.Context, message *broker.Message) error {
+			return consumeMessage(ctx, storage, message)
+		})
 		if err != nil {
 			logg.Error("register message consumer failed", zap.Error(err))
 			return
 		}
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		if err := metrics.Serve(ctx, config.Metrics, logg, checks...); err != nil {
+			logg.Error("metrics server failed", zap.Error(err))
+		}
+	}()
+
 	wg.Wait()
 }
 
-func consumeMessage(ctx context.Context, repository app.Storage, message *sarama.ConsumerMessage) error {
+func consumeMessage(ctx context.Context, repository app.Storage, message *broker.Message) error {
 	var event storage.Event
-	err := jsoniter.Unmarshal(message.Value, &event)
+	err := jsoniter.Unmarshal(message.Payload, &event)
 	if err != nil {
 		return err
 	}
@@ -98,6 +144,13 @@ func consumeMessage(ctx context.Context, repository app.Storage, message *sarama
 		return err
 	}
 
+	// the event of a message with the reminder ID header gets the ID derived from it,
+	// so a redelivered message doesn't create the event twice
+	messageID := message.Headers[broker.ReminderIDHeader]
+	if event.ID == "" && messageID != "" {
+		event.ID = uuid.NewSHA1(uuid.NameSpaceOID, []byte(messageID)).String()
+	}
+
 	if event.ID == "" {
 		id, err := uuid.NewV7()
 		if err != nil {
@@ -109,6 +162,10 @@ func consumeMessage(ctx context.Context, repository app.Storage, message *sarama
 
 	event.StartDate = event.StartDate.UTC()
 	err = repository.CreateEvent(ctx, &event)
+	if messageID != "" && errors.Is(err, storage.ErrEventAlreadyExist) {
+		return nil
+	}
+
 	if err != nil {
 		return err
 	}
@@ -143,3 +200,10 @@ func validateEvent(event *storage.Event) error {
 
 	return nil
 }
+
+func runDeadLetter(ctx context.Context, config configs.KafkaConfig, logg *zap.Logger) {
+	err := kafka.DeadLetterCommand(ctx, config, flag.Args()[1:], os.Stdout)
+	if err != nil {
+		logg.Error("dead letter command failed", zap.Error(err))
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+//nolint:depguard
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker"
+	memorybroker "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker/memory"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/outbox"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestConsumeReminders(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	scheduled := memorystorage.New()
+	event := storage.Event{
+		ID:        "0193c6c8-7b1e-7cde-8d7c-3b1f8f6b2a30",
+		Title:     "standup",
+		Owner:     "test_user",
+		StartDate: time.Now().Add(30 * time.Minute).UTC().Truncate(time.Second),
+		Duration:  time.Hour,
+		Reminders: []storage.EventReminder{{Before: 60}},
+	}
+	require.NoError(t, scheduled.CreateEvent(ctx, &event))
+	require.NoError(t, outbox.EnqueueReminders(ctx, scheduled, logger, time.Now()))
+
+	// the scheduler relays the reminders to the broker and the storer consumes them in one process
+	consume := func(t *testing.T, stored *memorystorage.Storage, publish func(publisher broker.Publisher)) []error {
+		t.Helper()
+
+		messages := memorybroker.New(0)
+		publish(messages)
+		require.NoError(t, messages.Close())
+
+		errs := make([]error, 0)
+		require.NoError(t, messages.Subscribe(ctx, func(ctx context.Context, message *broker.Message) error {
+			errs = append(errs, consumeMessage(ctx, stored, message))
+			return nil
+		}))
+		return errs
+	}
+
+	stored := memorystorage.New()
+	var relayed *broker.Message
+	errs := consume(t, stored, func(publisher broker.Publisher) {
+		published, err := outbox.NewRelay(scheduled, &recordingPublisher{Publisher: publisher, last: &relayed},
+			logger).Run(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, published)
+	})
+	require.Equal(t, []error{nil}, errs)
+
+	events, err := stored.GetEvents(ctx)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, event.ID, events[0].ID)
+	require.Equal(t, event.Title, events[0].Title)
+
+	errs = consume(t, stored, func(publisher broker.Publisher) {
+		require.NoError(t, publisher.Publish(ctx, relayed))
+	})
+	require.Equal(t, []error{nil}, errs, "a redelivered reminder isn't stored twice")
+
+	events, err = stored.GetEvents(ctx)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+}
+
+// recordingPublisher keeps the last published message to redeliver it.
+type recordingPublisher struct {
+	broker.Publisher
+	last **broker.Message
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, message *broker.Message) error {
+	*p.last = message
+	return p.Publisher.Publish(ctx, message)
+}
@@ -12,7 +12,10 @@ import (
 
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/health"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/identity"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	internalgrpc "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/server/grpc"
 	internalhttp "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/server/http"
 	memorystorage "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
 	sqlstorage "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage/sql"
@@ -47,10 +50,27 @@ func main() {
 		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	defer cancel()
 
+	if flag.Arg(0) == "migrate" {
+		err := sqlstorage.MigrateCommand(ctx, &config.DB, flag.Args()[1:], logg)
+		if err != nil {
+			cancel()
+			logg.Fatal("database migration failed", zap.Error(err))
+		}
+
+		return
+	}
+
+	conflictPolicy, err := app.ParseConflictPolicy(config.App.ConflictPolicy)
+	if err != nil {
+		logg.Error("invalid app configuration", zap.Error(err))
+		return
+	}
+
 	var calendar *app.App
+	var checks []health.Check
 	if config.DB.InMemory {
 		storage := memorystorage.New()
-		calendar = app.New(storage)
+		calendar = app.New(storage, app.WithConflictPolicy(conflictPolicy))
 	} else {
 		storage := sqlstorage.New()
 		defer storage.Close(ctx)
@@ -61,13 +81,26 @@ func main() {
 		}
 
 		logg.Info("database connection created")
-		calendar = app.New(storage)
+		if config.DB.Migrate {
+			migrations, err := storage.MigrateUp(ctx)
+			if err != nil {
+				logg.Error("database migration failed", zap.Error(err))
+				return
+			}
+
+			logg.Info("database migrated", zap.Int("applied", len(migrations)))
+		}
+
+		calendar = app.New(storage, app.WithConflictPolicy(conflictPolicy))
+		checks = append(checks, health.Check{Name: "database", Probe: storage.Ping})
 	}
 
-	server := internalhttp.NewServer(ctx, logg, calendar)
+	authenticator := identity.New(config.Auth)
+	server := internalhttp.NewServer(ctx, logg, calendar, authenticator, checks...)
+	grpcServer := internalgrpc.NewServer(ctx, logg, calendar, authenticator)
 
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(4)
 
 	go func() {
 		defer wg.Done()
@@ -89,5 +122,25 @@ func main() {
 		}
 	}()
 
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+		defer cancel()
+
+		logg.Info("grpc server is stopping...")
+		if err := grpcServer.Stop(ctx); err != nil {
+			logg.Error("failed to stop grpc server: " + err.Error())
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		if err := grpcServer.Start(config.GRPC); err != nil {
+			logg.Error("failed to start grpc server: " + err.Error())
+		}
+	}()
+
 	wg.Wait()
 }
@@ -2,6 +2,7 @@ package configs
 
 import (
 	"fmt"
+	"time"
 
 	//nolint:depguard
 	"github.com/BurntSushi/toml"
@@ -9,11 +10,30 @@ import (
 )
 
 type Config struct {
+	App      AppConfig
+	Auth     AuthConfig
 	Logger   LoggerConf
 	DB       DBConfig
 	HTTP     HTTPConfig
+	GRPC     GRPCConfig
+	Broker   BrokerConfig
 	Kafka    KafkaConfig
+	AMQP     AMQPConfig
 	Schedule ScheduleConfig
+	Sender   SenderConfig
+	Metrics  MetricsConfig
+}
+
+// AppConfig sets the conflict policy for overlapping events of an owner: allow (default), warn or reject.
+type AppConfig struct {
+	ConflictPolicy string
+}
+
+// AuthConfig identifies callers by the subject of a bearer JWT signed with JWTKey (HS256) when it is set,
+// by the Header value (X-User-Id by default) otherwise.
+type AuthConfig struct {
+	Header string
+	JWTKey string
 }
 
 type LoggerConf struct {
@@ -28,6 +48,7 @@ type DBConfig struct {
 	Username string
 	Password string
 	Dbname   string
+	Migrate  bool
 }
 
 type HTTPConfig struct {
@@ -35,16 +56,77 @@ type HTTPConfig struct {
 	Port int
 }
 
+type GRPCConfig struct {
+	Host string
+	Port int
+}
+
+// MetricsConfig is the listener the scheduler and the storer serve /metrics, /healthz and /readyz on,
+// it is off without a port. The calendar serves them on its HTTP listener.
+type MetricsConfig struct {
+	Host string
+	Port int
+}
+
+// KafkaConfig of the consumer retries a failed message MaxRetries times with a RetryBackoff delay doubling up to
+// MaxRetryBackoff, then sends it to DeadLetterTopic.
 type KafkaConfig struct {
-	URL          string
-	Group        string
-	ConsumeTopic string
-	ProduceTopic string
-	ServiceName  string
+	URL             string
+	Group           string
+	ConsumeTopic    string
+	ProduceTopic    string
+	ServiceName     string
+	MaxRetries      int
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	DeadLetterTopic string
+}
+
+// BrokerConfig selects the message broker: kafka (default) or amqp.
+type BrokerConfig struct {
+	Type string
+}
+
+type AMQPConfig struct {
+	URL             string
+	ConsumeQueue    string
+	ProduceQueue    string
+	ServiceName     string
+	DeadLetterQueue string
 }
 
+// ScheduleConfig keeps the deleted events in the trash for PurgeRetention (30 days by default),
+// then the scheduler purges them. The replicas run the jobs only while they hold the leadership:
+// the Postgres advisory lock LeaderLockID or the in-process lock in memory mode.
 type ScheduleConfig struct {
-	Cron string
+	Cron           string
+	PurgeRetention time.Duration
+	LeaderLockID   int64
+}
+
+// SenderConfig lists the notification channels: log, smtp and webhook.
+type SenderConfig struct {
+	Channels    []string
+	MaxAttempts int
+	RetryDelay  time.Duration
+	SMTP        SMTPConfig
+	Webhook     WebhookConfig
+}
+
+// SMTPConfig of the mail server, Timeout bounds the whole delivery of a notification.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Domain   string
+	Timeout  time.Duration
+}
+
+type WebhookConfig struct {
+	URL     string
+	Timeout time.Duration
 }
 
 func ReadConfig(path string) (c Config, err error) {
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+func callerOf(ctx context.Context) (string, error) {
+	caller, ok := identity.Caller(ctx)
+	if !ok {
+		return "", ErrUnauthenticated
+	}
+
+	return caller, nil
+}
+
+// authorize lets the callers access their own events only.
+func authorize(ctx context.Context, owner string) error {
+	caller, err := callerOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	if owner != caller {
+		return fmt.Errorf("%w: events of %s belong to another user", ErrForbidden, owner)
+	}
+
+	return nil
+}
+
+// claimOwner makes the caller the owner of the event, an event can't be given to another user.
+func claimOwner(ctx context.Context, event *storage.Event) error {
+	if event.Owner == "" {
+		caller, err := callerOf(ctx)
+		if err != nil {
+			return err
+		}
+
+		event.Owner = caller
+	}
+
+	return authorize(ctx, event.Owner)
+}
+
+// sharedEvent returns the stored event if it is shared with the caller. The events of others are reported
+// as missing, so the callers can't probe which event ids exist.
+func (a *App) sharedEvent(ctx context.Context, id string) (storage.Event, string, error) {
+	caller, err := callerOf(ctx)
+	if err != nil {
+		return storage.Event{}, "", err
+	}
+
+	event, err := a.storage.GetEvent(ctx, id)
+	if err != nil {
+		return storage.Event{}, "", storageError(err)
+	}
+
+	if !event.SharedWith(caller) {
+		return storage.Event{}, "", storageError(storage.ErrEventDoesNotExist)
+	}
+
+	return event, caller, nil
+}
+
+// ownedEvent returns the stored event if it belongs to the caller,
+// the attendees may see the event but not change it.
+func (a *App) ownedEvent(ctx context.Context, id string) (storage.Event, error) {
+	event, caller, err := a.sharedEvent(ctx, id)
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	if event.Owner != caller {
+		return storage.Event{}, fmt.Errorf("%w: event %s belongs to another user", ErrForbidden, id)
+	}
+
+	return event, nil
+}
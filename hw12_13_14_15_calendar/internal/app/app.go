@@ -2,9 +2,11 @@ package app
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"time"
 
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/ical"
 	//nolint:depguard
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
 	//nolint:depguard
@@ -12,111 +14,409 @@ import (
 )
 
 type App struct {
-	storage Storage
+	storage        Storage
+	conflictPolicy ConflictPolicy
+}
+
+type Option func(*App)
+
+// WithConflictPolicy sets the policy for events overlapping other events of the owner, allow by default.
+func WithConflictPolicy(policy ConflictPolicy) Option {
+	return func(a *App) {
+		a.conflictPolicy = policy
+	}
+}
+
+type ImportedEvent struct {
+	UID string `json:"uid"`
+	ID  string `json:"id"`
+}
+
+type ImportError struct {
+	Index int    `json:"index"`
+	UID   string `json:"uid"`
+	Error string `json:"error"`
+}
+
+type ImportResult struct {
+	Imported []ImportedEvent `json:"imported"`
+	Errors   []ImportError   `json:"errors"`
 }
 
 type Storage interface {
 	CreateEvent(ctx context.Context, event *storage.Event) error
-	UpdateEvent(ctx context.Context, event *storage.Event) error
-	DeleteEvent(ctx context.Context, id string) error
+	CreateEventWithAudit(
+		ctx context.Context,
+		event *storage.Event,
+		entry *storage.AuditEntry,
+		check *storage.ConflictCheck,
+	) error
+	UpdateEventWithAudit(
+		ctx context.Context,
+		event *storage.Event,
+		entry *storage.AuditEntry,
+		check *storage.ConflictCheck,
+	) error
+	DeleteEventWithAudit(ctx context.Context, id string, entry *storage.AuditEntry) error
+	RestoreEventWithAudit(ctx context.Context, id string, entry *storage.AuditEntry) error
+	GetDeletedEvents(ctx context.Context, owner string) ([]storage.Event, error)
+	GetEvent(ctx context.Context, id string) (storage.Event, error)
 	GetEventsByPeriod(ctx context.Context, owner string, startTime time.Time, endTime time.Time) ([]storage.Event, error)
 	GetEvents(ctx context.Context) ([]storage.Event, error)
+	GetOwnerEventsBetween(ctx context.Context, owner string, start, end time.Time) ([]storage.Event, error)
+	GetAcceptedEventsBetween(ctx context.Context, user string, start, end time.Time) ([]storage.Event, error)
+	QueryEvents(ctx context.Context, query storage.EventQuery) (storage.EventPage, error)
+	SetAttendeeStatus(ctx context.Context, eventID, user string, status storage.RSVPStatus) error
+	SharesEvent(ctx context.Context, user, other string) (bool, error)
+	GetAuditEntries(ctx context.Context, eventID string) ([]storage.AuditEntry, error)
 }
 
-func New(storage Storage) *App {
-	return &App{storage: storage}
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 500
+	MaxAttendees     = 100
+)
+
+func New(storage Storage, options ...Option) *App {
+	a := &App{storage: storage, conflictPolicy: ConflictPolicyAllow}
+	for _, option := range options {
+		option(a)
+	}
+
+	return a
 }
 
-func (a *App) CreateEvent(ctx context.Context, event *storage.Event) error {
-	err := a.validateEvent(event)
+// CreateEvent saves the event, the returned events overlap it when the conflict policy is warn.
+// The event is owned by the caller if the owner is omitted.
+func (a *App) CreateEvent(ctx context.Context, event *storage.Event) ([]storage.Event, error) {
+	err := claimOwner(ctx, event)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	err = a.validateEvent(event)
+	if err != nil {
+		return nil, err
 	}
 
 	if event.ID == "" {
 		id, err := uuid.NewV7()
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		event.ID = id.String()
 	}
 
-	event.IsSend = false
+	event.Version = 0
 	event.StartDate = event.StartDate.UTC()
-	err = a.storage.CreateEvent(ctx, event)
+	event.ExDates = normalizeExDates(event.ExDates)
+	event.Attendees = inviteAttendees(event.Attendees, nil)
+	err = scheduleReminders(event, nil, time.Now())
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	var conflicts []storage.Event
+	check, err := a.conflictCheck(event, &conflicts)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := auditEntry(ctx, storage.AuditCreate, nil, event)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.storage.CreateEventWithAudit(ctx, event, entry, check)
+	if err != nil {
+		return nil, storageError(err)
+	}
+
+	return conflicts, nil
 }
 
-func (a *App) UpdateEvent(ctx context.Context, event *storage.Event) error {
+// UpdateEvent saves the event, the returned events overlap it when the conflict policy is warn.
+// The event version must match the stored one, otherwise the update is rejected as a conflict.
+func (a *App) UpdateEvent(ctx context.Context, event *storage.Event) ([]storage.Event, error) {
 	if event.ID == "" {
-		return errors.New("id is required")
+		return nil, validationError("id", "id is required")
+	}
+
+	if event.Version == 0 {
+		return nil, validationError("version", "version is required")
 	}
 
-	err := a.validateEvent(event)
+	err := claimOwner(ctx, event)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	err = a.validateEvent(event)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := a.ownedEvent(ctx, event.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	event.IsSend = false
 	event.StartDate = event.StartDate.UTC()
-	err = a.storage.UpdateEvent(ctx, event)
+	event.ExDates = normalizeExDates(event.ExDates)
+	event.Attendees = inviteAttendees(event.Attendees, stored.Attendees)
+	err = scheduleReminders(event, &stored, time.Now())
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	var conflicts []storage.Event
+	check, err := a.conflictCheck(event, &conflicts)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := auditEntry(ctx, storage.AuditUpdate, &stored, event)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.storage.UpdateEventWithAudit(ctx, event, entry, check)
+	if err != nil {
+		return nil, storageError(err)
+	}
+
+	return conflicts, nil
 }
 
+// GetEvent returns the event owned by the caller or shared with the caller.
+func (a *App) GetEvent(ctx context.Context, id string) (storage.Event, error) {
+	event, _, err := a.sharedEvent(ctx, id)
+	return event, err
+}
+
+// DeleteEvent moves the caller event to the trash, it can be restored until it is purged.
 func (a *App) DeleteEvent(ctx context.Context, id string) error {
-	return a.storage.DeleteEvent(ctx, id)
+	stored, err := a.ownedEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	deletedAt := time.Now().UTC()
+	deleted := stored
+	deleted.DeletedAt = &deletedAt
+	entry, err := auditEntry(ctx, storage.AuditDelete, &stored, &deleted)
+	if err != nil {
+		return err
+	}
+
+	return storageError(a.storage.DeleteEventWithAudit(ctx, id, entry))
+}
+
+func (a *App) GetEventsDay(ctx context.Context, owner string, date time.Time) ([]storage.Event, error) {
+	timeStart := startOfDay(date)
+	return a.getEventsByPeriod(ctx, owner, timeStart, timeStart.AddDate(0, 0, 1))
+}
+
+func (a *App) GetEventsWeek(ctx context.Context, owner string, date time.Time) ([]storage.Event, error) {
+	timeStart := startOfDay(date)
+	timeStart = timeStart.AddDate(0, 0, -(int(timeStart.Weekday())+6)%7)
+	return a.getEventsByPeriod(ctx, owner, timeStart, timeStart.AddDate(0, 0, 7))
+}
+
+func (a *App) GetEventsMonth(ctx context.Context, owner string, date time.Time) ([]storage.Event, error) {
+	timeStart := startOfDay(date)
+	timeStart = timeStart.AddDate(0, 0, 1-timeStart.Day())
+	return a.getEventsByPeriod(ctx, owner, timeStart, timeStart.AddDate(0, 1, 0))
 }
 
-func (a *App) GetEventsDay(ctx context.Context, owner string) ([]storage.Event, error) {
-	timeStart := time.Now().UTC().Truncate(24 * time.Hour)
-	return a.storage.GetEventsByPeriod(ctx, owner, timeStart, timeStart.Add(24*time.Hour))
+// ListEvents returns a page of the caller events matching the query, sorted by start date unless told otherwise.
+func (a *App) ListEvents(ctx context.Context, query storage.EventQuery) (storage.EventPage, error) {
+	if query.Owner == "" {
+		caller, err := callerOf(ctx)
+		if err != nil {
+			return storage.EventPage{}, err
+		}
+
+		query.Owner = caller
+	}
+
+	if err := authorize(ctx, query.Owner); err != nil {
+		return storage.EventPage{}, err
+	}
+
+	switch query.Sort {
+	case "":
+		query.Sort = storage.SortByStartDate
+	case storage.SortByStartDate, storage.SortByTitle:
+	default:
+		return storage.EventPage{}, validationError("sort",
+			fmt.Sprintf("sort must be %s or %s", storage.SortByStartDate, storage.SortByTitle))
+	}
+
+	if query.Limit == 0 {
+		query.Limit = DefaultListLimit
+	}
+
+	if query.Limit < 0 || query.Limit > MaxListLimit {
+		return storage.EventPage{}, validationError("limit", fmt.Sprintf("limit must be between 1 and %d", MaxListLimit))
+	}
+
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return storage.EventPage{}, validationError("from", "from must be before to")
+	}
+
+	query.From = query.From.UTC()
+	query.To = query.To.UTC()
+	page, err := a.storage.QueryEvents(ctx, query)
+	if err != nil {
+		return storage.EventPage{}, storageError(err)
+	}
+
+	return page, nil
+}
+
+// ExportEvents returns the owner events occurring within [start, end),
+// recurring events are returned once with their recurrence rule.
+func (a *App) ExportEvents(ctx context.Context, owner string, start, end time.Time) ([]storage.Event, error) {
+	if err := authorize(ctx, owner); err != nil {
+		return nil, err
+	}
+
+	events, err := a.storage.GetOwnerEventsBetween(ctx, owner, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, storageError(err)
+	}
+
+	result := make([]storage.Event, 0)
+	for _, event := range events {
+		occurrences, err := event.Occurrences(start.UTC(), end.UTC())
+		if err != nil {
+			return nil, err
+		}
+
+		if len(occurrences) > 0 {
+			result = append(result, event)
+		}
+	}
+
+	return result, nil
+}
+
+// ImportEvents creates an owner event for every imported calendar component.
+// The component UID is kept as the event ID if it is a valid UUID.
+func (a *App) ImportEvents(ctx context.Context, owner string, components []ical.Component) (ImportResult, error) {
+	if err := authorize(ctx, owner); err != nil {
+		return ImportResult{}, err
+	}
+
+	result := ImportResult{Imported: make([]ImportedEvent, 0), Errors: make([]ImportError, 0)}
+	for i, component := range components {
+		err := component.Err
+		if err == nil {
+			event := component.Event
+			event.Owner = owner
+			if _, parseErr := uuid.Parse(component.UID); parseErr == nil {
+				event.ID = component.UID
+			}
+
+			_, err = a.CreateEvent(ctx, &event)
+			if err == nil {
+				result.Imported = append(result.Imported, ImportedEvent{UID: component.UID, ID: event.ID})
+				continue
+			}
+		}
+
+		result.Errors = append(result.Errors, ImportError{Index: i, UID: component.UID, Error: err.Error()})
+	}
+
+	return result, nil
 }
 
-func (a *App) GetEventsWeek(ctx context.Context, owner string) ([]storage.Event, error) {
-	timeStart := time.Now().UTC().Truncate(7 * 24 * time.Hour)
-	return a.storage.GetEventsByPeriod(ctx, owner, timeStart, timeStart.Add(7*24*time.Hour))
+// ListingDate returns the day the listing periods are anchored to: the given calendar date
+// (or today, if date is nil) in the given IANA time zone (or UTC, if timeZone is empty).
+func ListingDate(date *time.Time, timeZone string) (time.Time, error) {
+	location := time.UTC
+	if timeZone != "" {
+		var err error
+		location, err = time.LoadLocation(timeZone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time zone: %w", err)
+		}
+	}
+
+	if date == nil {
+		return time.Now().In(location), nil
+	}
+
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location), nil
+}
+
+func (a *App) getEventsByPeriod(ctx context.Context, owner string, start, end time.Time) ([]storage.Event, error) {
+	if err := authorize(ctx, owner); err != nil {
+		return nil, err
+	}
+
+	return a.storage.GetEventsByPeriod(ctx, owner, start.UTC(), end.UTC())
 }
 
-func (a *App) GetEventsMonth(ctx context.Context, owner string) ([]storage.Event, error) {
-	timeNow := time.Now().UTC()
-	timeStart := time.Date(timeNow.Year(), timeNow.Month(), 1, 0, 0, 0, 0, time.UTC)
-	timeEnd := time.Date(timeNow.Year(), timeNow.Month()+1, 1, 0, 0, 0, 0, time.UTC)
-	return a.storage.GetEventsByPeriod(ctx, owner, timeStart, timeEnd)
+func startOfDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
 }
 
 func (a *App) validateEvent(event *storage.Event) error {
 	if event.Owner == "" {
-		return errors.New("owner is required")
+		return validationError("owner", "owner is required")
 	}
 
 	if len(event.Owner) > 256 {
-		return errors.New("owner length can't be greater than 256")
+		return validationError("owner", "owner length can't be greater than 256")
 	}
 
 	if event.Title == "" {
-		return errors.New("title is required")
+		return validationError("title", "title is required")
 	}
 
 	if len(event.Title) > 256 {
-		return errors.New("title length can't be greater than 256")
+		return validationError("title", "title length can't be greater than 256")
 	}
 
 	if event.Duration == 0 {
-		return errors.New("duration is required")
+		return validationError("duration", "duration is required")
+	}
+
+	if event.Duration < 0 {
+		return validationError("duration", "duration must be positive")
 	}
 
 	if event.StartDate.Equal(time.Time{}) {
-		return errors.New("startDate is required")
+		return validationError("startDate", "startDate is required")
+	}
+
+	if err := validateAttendees(event); err != nil {
+		return err
+	}
+
+	if err := validateReminders(event); err != nil {
+		return err
+	}
+
+	if len(event.RRule) > 1024 {
+		return validationError("rrule", "rrule length can't be greater than 1024")
+	}
+
+	if err := event.ValidateRecurrence(); err != nil {
+		return validationError("rrule", err.Error())
 	}
 
 	return nil
 }
+
+func normalizeExDates(exDates []time.Time) []time.Time {
+	result := make([]time.Time, 0, len(exDates))
+	for _, exDate := range exDates {
+		result = append(result, exDate.UTC().Truncate(time.Second))
+	}
+
+	return result
+}
@@ -0,0 +1,79 @@
+package app
+
+//nolint:depguard
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateEvent(t *testing.T) {
+	startDate := time.Date(2024, time.March, 11, 10, 0, 0, 0, time.UTC)
+	valid := func(change func(event *storage.Event)) *storage.Event {
+		event := &storage.Event{
+			ID:        "0193c6c8-7b1e-7cde-8d7c-3b1f8f6b2a10",
+			Title:     "test_title",
+			Owner:     "test_user",
+			StartDate: startDate,
+			Duration:  time.Hour,
+		}
+		change(event)
+		return event
+	}
+
+	tests := []struct {
+		name  string
+		event *storage.Event
+		field string
+	}{
+		{name: "valid", event: valid(func(*storage.Event) {})},
+		{name: "valid recurring", event: valid(func(e *storage.Event) { e.RRule = "FREQ=WEEKLY;COUNT=10" })},
+		{name: "owner missing", event: valid(func(e *storage.Event) { e.Owner = "" }), field: "owner"},
+		{name: "owner too long", event: valid(func(e *storage.Event) { e.Owner = strings.Repeat("u", 257) }), field: "owner"},
+		{name: "title missing", event: valid(func(e *storage.Event) { e.Title = "" }), field: "title"},
+		{name: "title too long", event: valid(func(e *storage.Event) { e.Title = strings.Repeat("t", 257) }), field: "title"},
+		{name: "duration missing", event: valid(func(e *storage.Event) { e.Duration = 0 }), field: "duration"},
+		{name: "duration negative", event: valid(func(e *storage.Event) { e.Duration = -time.Hour }), field: "duration"},
+		{name: "start missing", event: valid(func(e *storage.Event) { e.StartDate = time.Time{} }), field: "startDate"},
+		{
+			name:  "owner invited",
+			event: valid(func(e *storage.Event) { e.Attendees = []storage.Attendee{{User: "test_user"}} }),
+			field: "attendees",
+		},
+		{
+			name:  "reminder after start",
+			event: valid(func(e *storage.Event) { e.Reminders = []storage.EventReminder{{Before: -1}} }),
+			field: "reminders",
+		},
+		{name: "rrule invalid", event: valid(func(e *storage.Event) { e.RRule = "FREQ=SOMETIMES" }), field: "rrule"},
+		{
+			name:  "rrule count oversized",
+			event: valid(func(e *storage.Event) { e.RRule = "FREQ=DAILY;COUNT=100000000" }),
+			field: "rrule",
+		},
+		{
+			name:  "rrule until oversized",
+			event: valid(func(e *storage.Event) { e.RRule = "FREQ=DAILY;UNTIL=21240311T100000Z" }),
+			field: "rrule",
+		},
+	}
+
+	calendar := New(nil)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := calendar.validateEvent(tc.event)
+			if tc.field == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			var validation *ValidationError
+			require.ErrorAs(t, err, &validation)
+			require.ErrorIs(t, err, ErrValidation)
+			require.Equal(t, tc.field, validation.Field)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// RespondToInvitation stores the reply of the caller to the event invitation.
+func (a *App) RespondToInvitation(ctx context.Context,
+	eventID string,
+	status storage.RSVPStatus,
+) (storage.Attendee, error) {
+	caller, err := callerOf(ctx)
+	if err != nil {
+		return storage.Attendee{}, err
+	}
+
+	if !status.Valid() {
+		return storage.Attendee{}, validationError("status", fmt.Sprintf("status must be %s, %s, %s or %s",
+			storage.RSVPNeedsAction, storage.RSVPAccepted, storage.RSVPDeclined, storage.RSVPTentative))
+	}
+
+	// the storage audits the reply in its transaction against the attendees it changed
+	err = a.storage.SetAttendeeStatus(ctx, eventID, caller, status)
+	if err != nil {
+		return storage.Attendee{}, storageError(err)
+	}
+
+	return storage.Attendee{User: caller, Status: status}, nil
+}
+
+// inviteAttendees keeps the replies of the already invited attendees, the new ones haven't answered yet.
+// Only the attendees themselves change their replies.
+func inviteAttendees(attendees, invited []storage.Attendee) []storage.Attendee {
+	result := make([]storage.Attendee, 0, len(attendees))
+	for _, attendee := range attendees {
+		status := storage.RSVPNeedsAction
+		for _, previous := range invited {
+			if previous.User == attendee.User {
+				status = previous.Status
+				break
+			}
+		}
+
+		result = append(result, storage.Attendee{User: attendee.User, Status: status})
+	}
+
+	return result
+}
+
+func validateAttendees(event *storage.Event) error {
+	if len(event.Attendees) > MaxAttendees {
+		return validationError("attendees", fmt.Sprintf("attendees count can't be greater than %d", MaxAttendees))
+	}
+
+	users := make(map[string]bool, len(event.Attendees))
+	for _, attendee := range event.Attendees {
+		switch {
+		case attendee.User == "":
+			return validationError("attendees", "attendee user is required")
+		case len(attendee.User) > 256:
+			return validationError("attendees", "attendee user length can't be greater than 256")
+		case attendee.User == event.Owner:
+			return validationError("attendees", "owner can't be invited to the own event")
+		case users[attendee.User]:
+			return validationError("attendees", fmt.Sprintf("attendee %s is invited twice", attendee.User))
+		}
+
+		users[attendee.User] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// EventHistory returns the audit log of the caller event, the oldest change first.
+// The history of the events in the trash is kept too.
+func (a *App) EventHistory(ctx context.Context, id string) ([]storage.AuditEntry, error) {
+	_, err := a.ownedEvent(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		_, err = a.trashedEvent(ctx, id)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return a.storage.GetAuditEntries(ctx, id)
+}
+
+// auditEntry records the change of the event made by the caller, nil stands for a missing event.
+// The storage writes the entry in the transaction of the change, so the change isn't saved without it.
+func auditEntry(
+	ctx context.Context,
+	action storage.AuditAction,
+	before, after *storage.Event,
+) (*storage.AuditEntry, error) {
+	actor, _ := identity.Caller(ctx)
+	entry, err := storage.NewAuditEntry(action, actor, before, after, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
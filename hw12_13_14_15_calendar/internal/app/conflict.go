@@ -0,0 +1,130 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// ConflictPolicy tells what to do with an event overlapping other events of its owner:
+// allow saves it silently, warn saves it and reports the conflicts, reject refuses to save it.
+type ConflictPolicy string
+
+const (
+	ConflictPolicyAllow  ConflictPolicy = "allow"
+	ConflictPolicyWarn   ConflictPolicy = "warn"
+	ConflictPolicyReject ConflictPolicy = "reject"
+)
+
+// conflictHorizon limits how far ahead the occurrences of a recurring event are checked for conflicts.
+const conflictHorizon = 366 * 24 * time.Hour
+
+// ConflictError is returned by the reject policy, it lists the clashing events.
+type ConflictError struct {
+	Events []storage.Event
+}
+
+func (e *ConflictError) Error() string {
+	ids := make([]string, 0, len(e.Events))
+	for _, event := range e.Events {
+		ids = append(ids, event.ID)
+	}
+
+	return fmt.Sprintf("event overlaps with %d events: %s", len(e.Events), strings.Join(ids, ", "))
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+func ParseConflictPolicy(policy string) (ConflictPolicy, error) {
+	switch ConflictPolicy(policy) {
+	case "":
+		return ConflictPolicyAllow, nil
+	case ConflictPolicyAllow, ConflictPolicyWarn, ConflictPolicyReject:
+		return ConflictPolicy(policy), nil
+	default:
+		return "", fmt.Errorf("conflict policy must be %s, %s or %s",
+			ConflictPolicyAllow, ConflictPolicyWarn, ConflictPolicyReject)
+	}
+}
+
+// conflictCheck applies the conflict policy to the event write, the storage runs the check in the write,
+// so the concurrent writes of the owner can't miss each other's conflicts. The check stores the conflicts
+// to warn about in conflicts, it is nil if the policy allows the conflicts or the event doesn't occur.
+func (a *App) conflictCheck(event *storage.Event, conflicts *[]storage.Event) (*storage.ConflictCheck, error) {
+	if a.conflictPolicy == ConflictPolicyAllow {
+		return nil, nil
+	}
+
+	occurrences, err := event.Occurrences(event.StartDate, event.StartDate.Add(conflictHorizon))
+	if err != nil || len(occurrences) == 0 {
+		return nil, err
+	}
+
+	windowStart := occurrences[0]
+	windowEnd := occurrences[len(occurrences)-1].Add(event.Duration)
+	check := func(candidates []storage.Event) error {
+		found, err := findConflicts(event, occurrences, candidates, windowStart, windowEnd)
+		if err != nil || len(found) == 0 {
+			return err
+		}
+
+		if a.conflictPolicy == ConflictPolicyReject {
+			return &ConflictError{Events: found}
+		}
+
+		*conflicts = found
+		return nil
+	}
+
+	return &storage.ConflictCheck{Start: windowStart, End: windowEnd, Check: check}, nil
+}
+
+// findConflicts returns the candidates with an occurrence overlapping an event occurrence within the window,
+// the [StartDate, StartDate+Duration) intervals of the occurrences are compared.
+func findConflicts(event *storage.Event,
+	occurrences []time.Time,
+	candidates []storage.Event,
+	windowStart time.Time,
+	windowEnd time.Time,
+) ([]storage.Event, error) {
+	conflicts := make([]storage.Event, 0)
+	for _, candidate := range candidates {
+		if candidate.ID == event.ID {
+			continue
+		}
+
+		candidateOccurrences, err := candidate.Occurrences(windowStart.Add(-candidate.Duration), windowEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		if overlaps(occurrences, event.Duration, candidateOccurrences, candidate.Duration) {
+			conflicts = append(conflicts, candidate)
+		}
+	}
+
+	return conflicts, nil
+}
+
+// overlaps reports whether any intervals of the two sorted occurrence lists intersect.
+func overlaps(a []time.Time, aDuration time.Duration, b []time.Time, bDuration time.Duration) bool {
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		aEnd, bEnd := a[i].Add(aDuration), b[j].Add(bDuration)
+		if a[i].Before(bEnd) && b[j].Before(aEnd) {
+			return true
+		}
+
+		if aEnd.After(bEnd) {
+			j++
+		} else {
+			i++
+		}
+	}
+
+	return false
+}
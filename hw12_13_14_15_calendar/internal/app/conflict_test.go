@@ -0,0 +1,118 @@
+package app
+
+//nolint:depguard
+import (
+	"testing"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConflictCheck(t *testing.T) {
+	startDate := time.Date(2024, time.March, 11, 10, 0, 0, 0, time.UTC)
+	event := storage.Event{
+		ID:        "event",
+		Owner:     "test_user",
+		StartDate: startDate,
+		Duration:  time.Hour,
+	}
+	overlapping := storage.Event{ID: "overlapping", StartDate: startDate.Add(30 * time.Minute), Duration: time.Hour}
+	adjacent := storage.Event{ID: "adjacent", StartDate: startDate.Add(time.Hour), Duration: time.Hour}
+	weekly := storage.Event{
+		ID:        "weekly",
+		StartDate: startDate.AddDate(0, 0, -14).Add(-30 * time.Minute),
+		Duration:  time.Hour,
+		RRule:     "FREQ=WEEKLY",
+	}
+	itself := event
+
+	tests := []struct {
+		name       string
+		policy     ConflictPolicy
+		event      storage.Event
+		candidates []storage.Event
+		conflicts  []string
+		rejected   []string
+	}{
+		{name: "allowed", policy: ConflictPolicyAllow, event: event, candidates: []storage.Event{overlapping}},
+		{name: "free", policy: ConflictPolicyWarn, event: event, candidates: []storage.Event{adjacent}},
+		{name: "itself skipped", policy: ConflictPolicyReject, event: event, candidates: []storage.Event{itself}},
+		{
+			name:       "warned",
+			policy:     ConflictPolicyWarn,
+			event:      event,
+			candidates: []storage.Event{overlapping, adjacent, weekly},
+			conflicts:  []string{"overlapping", "weekly"},
+		},
+		{
+			name:       "rejected",
+			policy:     ConflictPolicyReject,
+			event:      event,
+			candidates: []storage.Event{adjacent, weekly},
+			rejected:   []string{"weekly"},
+		},
+		{
+			name:   "recurring event rejected",
+			policy: ConflictPolicyReject,
+			event: storage.Event{
+				ID:        "daily",
+				StartDate: startDate.AddDate(0, 0, -3),
+				Duration:  time.Hour,
+				RRule:     "FREQ=DAILY;COUNT=5",
+			},
+			candidates: []storage.Event{overlapping},
+			rejected:   []string{"overlapping"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calendar := New(nil, WithConflictPolicy(tc.policy))
+			conflicts := make([]storage.Event, 0)
+			check, err := calendar.conflictCheck(&tc.event, &conflicts)
+			require.NoError(t, err)
+			if tc.policy == ConflictPolicyAllow {
+				require.Nil(t, check)
+				return
+			}
+
+			require.NotNil(t, check)
+			err = check.Check(tc.candidates)
+			if tc.rejected != nil {
+				var conflict *ConflictError
+				require.ErrorAs(t, err, &conflict)
+				require.ErrorIs(t, err, ErrConflict)
+				require.Equal(t, tc.rejected, eventIDs(conflict.Events))
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.conflicts, eventIDs(conflicts))
+		})
+	}
+
+	t.Run("event without occurrences", func(t *testing.T) {
+		excluded := event
+		excluded.RRule = "FREQ=DAILY;COUNT=1"
+		excluded.ExDates = []time.Time{startDate}
+
+		calendar := New(nil, WithConflictPolicy(ConflictPolicyReject))
+		check, err := calendar.conflictCheck(&excluded, nil)
+		require.NoError(t, err)
+		require.Nil(t, check)
+	})
+}
+
+func eventIDs(events []storage.Event) []string {
+	if len(events) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+
+	return ids
+}
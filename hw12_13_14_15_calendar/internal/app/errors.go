@@ -0,0 +1,68 @@
+package app
+
+import (
+	"errors"
+
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Kinds of the app errors, an app error matches its kind with errors.Is.
+var (
+	ErrValidation = errors.New("validation failed")
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+
+	ErrUnauthenticated = errors.New("caller is not authenticated")
+	ErrForbidden       = errors.New("access denied")
+)
+
+// ValidationError reports an invalid field of an event or a query.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+func validationError(field, message string) error {
+	return &ValidationError{Field: field, Message: message}
+}
+
+// kindError keeps the message of the wrapped error and matches both the error and its kind.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// storageError classifies the storage errors by kind.
+func storageError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrEventDoesNotExist):
+		return &kindError{kind: ErrNotFound, err: err}
+	case errors.Is(err, storage.ErrEventAlreadyExist), errors.Is(err, storage.ErrEventVersionConflict):
+		return &kindError{kind: ErrConflict, err: err}
+	case errors.Is(err, storage.ErrAttendeeDoesNotExist):
+		return &kindError{kind: ErrForbidden, err: err}
+	case errors.Is(err, storage.ErrInvalidEventID):
+		return validationError("id", err.Error())
+	case errors.Is(err, storage.ErrInvalidCursor):
+		return validationError("cursor", err.Error())
+	default:
+		return err
+	}
+}
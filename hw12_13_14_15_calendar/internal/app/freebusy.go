@@ -0,0 +1,265 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	MaxFreeBusyOwners = 50
+	MaxFreeBusyPeriod = 31 * 24 * time.Hour
+
+	minutesPerDay = 24 * 60
+)
+
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// FreeBusyQuery asks for the free slots of the owners within [From, To).
+// The working hours are given in minutes since midnight of Location, 0 and 1440 cover the whole day.
+type FreeBusyQuery struct {
+	Owners       []string
+	From         time.Time
+	To           time.Time
+	SlotDuration time.Duration
+	WorkdayStart int
+	WorkdayEnd   int
+	Location     *time.Location
+}
+
+type FreeBusy struct {
+	Busy  []Interval `json:"busy"`
+	Slots []Interval `json:"slots"`
+}
+
+// FreeBusy merges the busy intervals of the owners and returns them together with the free intervals
+// of the working hours which are at least SlotDuration long.
+// The caller may ask for their own time and the time of the users they share an event with,
+// the events themselves are not disclosed.
+func (a *App) FreeBusy(ctx context.Context, query FreeBusyQuery) (FreeBusy, error) {
+	caller, err := callerOf(ctx)
+	if err != nil {
+		return FreeBusy{}, err
+	}
+
+	if err := validateFreeBusyQuery(&query); err != nil {
+		return FreeBusy{}, err
+	}
+
+	for _, owner := range query.Owners {
+		if err := a.authorizeFreeBusy(ctx, caller, owner); err != nil {
+			return FreeBusy{}, err
+		}
+	}
+
+	busy := make([]Interval, 0)
+	for _, owner := range query.Owners {
+		intervals, err := a.busyIntervals(ctx, owner, query.From, query.To)
+		if err != nil {
+			return FreeBusy{}, err
+		}
+
+		busy = append(busy, intervals...)
+	}
+
+	busy = mergeIntervals(busy)
+	slots := make([]Interval, 0)
+	for _, workday := range workdays(&query) {
+		for _, free := range subtractIntervals(workday, busy) {
+			if free.End.Sub(free.Start) >= query.SlotDuration {
+				slots = append(slots, free)
+			}
+		}
+	}
+
+	return FreeBusy{Busy: busy, Slots: slots}, nil
+}
+
+// ParseClock parses an HH:MM time of day to minutes since midnight, 24:00 is the end of the day.
+func ParseClock(clock string) (int, error) {
+	if clock == "24:00" {
+		return minutesPerDay, nil
+	}
+
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", clock)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func validateFreeBusyQuery(query *FreeBusyQuery) error {
+	if len(query.Owners) == 0 {
+		return validationError("owners", "owners are required")
+	}
+
+	if len(query.Owners) > MaxFreeBusyOwners {
+		return validationError("owners", fmt.Sprintf("owners count can't be greater than %d", MaxFreeBusyOwners))
+	}
+
+	if !query.From.Before(query.To) {
+		return validationError("from", "from must be before to")
+	}
+
+	if query.To.Sub(query.From) > MaxFreeBusyPeriod {
+		return validationError("to", fmt.Sprintf("period can't be longer than %d days", MaxFreeBusyPeriod/(24*time.Hour)))
+	}
+
+	if query.SlotDuration <= 0 {
+		return validationError("slotMinutes", "slot duration must be positive")
+	}
+
+	if query.WorkdayStart < 0 || query.WorkdayEnd > minutesPerDay || query.WorkdayStart >= query.WorkdayEnd {
+		return validationError("workdayStart", "workday start must be before workday end")
+	}
+
+	if query.Location == nil {
+		query.Location = time.UTC
+	}
+
+	query.From = query.From.UTC()
+	query.To = query.To.UTC()
+	return nil
+}
+
+// authorizeFreeBusy lets the caller see the busy time of the users sharing an event with the caller.
+func (a *App) authorizeFreeBusy(ctx context.Context, caller, owner string) error {
+	if owner == caller {
+		return nil
+	}
+
+	shared, err := a.storage.SharesEvent(ctx, caller, owner)
+	if err != nil {
+		return storageError(err)
+	}
+
+	if !shared {
+		return fmt.Errorf("%w: %s shares no events with %s", ErrForbidden, owner, caller)
+	}
+
+	return nil
+}
+
+// busyIntervals returns the occurrences of the owner events and of the events the owner accepted
+// overlapping [start, end), clipped to it.
+func (a *App) busyIntervals(ctx context.Context, owner string, start, end time.Time) ([]Interval, error) {
+	events, err := a.storage.GetOwnerEventsBetween(ctx, owner, start, end)
+	if err != nil {
+		return nil, storageError(err)
+	}
+
+	accepted, err := a.storage.GetAcceptedEventsBetween(ctx, owner, start, end)
+	if err != nil {
+		return nil, storageError(err)
+	}
+
+	events = append(events, accepted...)
+	intervals := make([]Interval, 0)
+	for _, event := range events {
+		occurrences, err := event.Occurrences(start.Add(-event.Duration), end)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, occurrence := range occurrences {
+			interval := clipInterval(Interval{Start: occurrence, End: occurrence.Add(event.Duration)}, start, end)
+			if interval.Start.Before(interval.End) {
+				intervals = append(intervals, interval)
+			}
+		}
+	}
+
+	return intervals, nil
+}
+
+// workdays returns the working hours of every day of the query period, clipped to the period.
+func workdays(query *FreeBusyQuery) []Interval {
+	from := query.From.In(query.Location)
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, query.Location)
+
+	result := make([]Interval, 0)
+	for ; day.Before(query.To); day = day.AddDate(0, 0, 1) {
+		workday := Interval{
+			Start: atClock(day, query.WorkdayStart),
+			End:   atClock(day, query.WorkdayEnd),
+		}
+
+		workday = clipInterval(workday, query.From, query.To)
+		if workday.Start.Before(workday.End) {
+			result = append(result, workday)
+		}
+	}
+
+	return result
+}
+
+func atClock(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, day.Location()).UTC()
+}
+
+func clipInterval(interval Interval, start, end time.Time) Interval {
+	if interval.Start.Before(start) {
+		interval.Start = start
+	}
+
+	if interval.End.After(end) {
+		interval.End = end
+	}
+
+	return interval
+}
+
+// mergeIntervals sorts the intervals and joins the overlapping and adjacent ones.
+func mergeIntervals(intervals []Interval) []Interval {
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].Start.Before(intervals[j].Start)
+	})
+
+	merged := make([]Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		last := len(merged) - 1
+		if last >= 0 && !interval.Start.After(merged[last].End) {
+			if interval.End.After(merged[last].End) {
+				merged[last].End = interval.End
+			}
+
+			continue
+		}
+
+		merged = append(merged, interval)
+	}
+
+	return merged
+}
+
+// subtractIntervals returns the parts of the interval not covered by the merged busy intervals.
+func subtractIntervals(interval Interval, busy []Interval) []Interval {
+	free := make([]Interval, 0)
+	start := interval.Start
+	for _, b := range busy {
+		if !b.End.After(start) {
+			continue
+		}
+
+		if !b.Start.Before(interval.End) {
+			break
+		}
+
+		if b.Start.After(start) {
+			free = append(free, Interval{Start: start, End: b.Start})
+		}
+
+		start = b.End
+	}
+
+	if start.Before(interval.End) {
+		free = append(free, Interval{Start: start, End: interval.End})
+	}
+
+	return free
+}
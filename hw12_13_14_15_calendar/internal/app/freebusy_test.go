@@ -0,0 +1,133 @@
+package app
+
+//nolint:depguard
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeSlots(t *testing.T) {
+	day := time.Date(2024, time.March, 11, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	interval := func(startHour, startMinute, endHour, endMinute int) Interval {
+		return Interval{Start: at(startHour, startMinute), End: at(endHour, endMinute)}
+	}
+
+	t.Run("merge intervals", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			intervals []Interval
+			merged    []Interval
+		}{
+			{name: "empty", intervals: []Interval{}, merged: []Interval{}},
+			{
+				name:      "disjoint sorted",
+				intervals: []Interval{interval(13, 0, 14, 0), interval(9, 0, 10, 0)},
+				merged:    []Interval{interval(9, 0, 10, 0), interval(13, 0, 14, 0)},
+			},
+			{
+				name:      "overlapping joined",
+				intervals: []Interval{interval(9, 0, 10, 30), interval(10, 0, 11, 0)},
+				merged:    []Interval{interval(9, 0, 11, 0)},
+			},
+			{
+				name:      "adjacent joined",
+				intervals: []Interval{interval(10, 0, 11, 0), interval(9, 0, 10, 0)},
+				merged:    []Interval{interval(9, 0, 11, 0)},
+			},
+			{
+				name:      "contained absorbed",
+				intervals: []Interval{interval(9, 0, 12, 0), interval(10, 0, 11, 0)},
+				merged:    []Interval{interval(9, 0, 12, 0)},
+			},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				require.Equal(t, tc.merged, mergeIntervals(tc.intervals))
+			})
+		}
+	})
+
+	t.Run("subtract intervals", func(t *testing.T) {
+		workday := interval(9, 0, 18, 0)
+		tests := []struct {
+			name string
+			busy []Interval
+			free []Interval
+		}{
+			{name: "free day", busy: nil, free: []Interval{workday}},
+			{name: "busy day", busy: []Interval{interval(8, 0, 19, 0)}, free: []Interval{}},
+			{
+				name: "busy outside",
+				busy: []Interval{interval(7, 0, 8, 0), interval(18, 0, 19, 0)},
+				free: []Interval{workday},
+			},
+			{
+				name: "busy edges",
+				busy: []Interval{interval(8, 0, 10, 0), interval(17, 0, 19, 0)},
+				free: []Interval{interval(10, 0, 17, 0)},
+			},
+			{
+				name: "busy inside",
+				busy: []Interval{interval(10, 0, 11, 0), interval(13, 0, 14, 30)},
+				free: []Interval{interval(9, 0, 10, 0), interval(11, 0, 13, 0), interval(14, 30, 18, 0)},
+			},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				require.Equal(t, tc.free, subtractIntervals(workday, tc.busy))
+			})
+		}
+	})
+
+	t.Run("workdays", func(t *testing.T) {
+		berlin, err := time.LoadLocation("Europe/Berlin")
+		require.NoError(t, err)
+
+		tests := []struct {
+			name     string
+			query    FreeBusyQuery
+			workdays []Interval
+		}{
+			{
+				name:     "whole days",
+				query:    FreeBusyQuery{From: at(12, 0), To: at(36, 0), WorkdayEnd: minutesPerDay, Location: time.UTC},
+				workdays: []Interval{interval(12, 0, 24, 0), interval(24, 0, 36, 0)},
+			},
+			{
+				name: "working hours clipped to the period",
+				query: FreeBusyQuery{
+					From:         at(10, 0),
+					To:           at(34, 0),
+					WorkdayStart: 9 * 60,
+					WorkdayEnd:   17 * 60,
+					Location:     time.UTC,
+				},
+				workdays: []Interval{interval(10, 0, 17, 0), interval(33, 0, 34, 0)},
+			},
+			{
+				name: "working hours of the location",
+				query: FreeBusyQuery{
+					From:         at(0, 0),
+					To:           at(24, 0),
+					WorkdayStart: 9 * 60,
+					WorkdayEnd:   17 * 60,
+					Location:     berlin,
+				},
+				workdays: []Interval{interval(8, 0, 16, 0)},
+			},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				require.Equal(t, tc.workdays, workdays(&tc.query))
+			})
+		}
+	})
+}
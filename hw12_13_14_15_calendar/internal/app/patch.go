@@ -0,0 +1,132 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// PatchEvent applies the JSON Merge Patch (RFC 7386) to the caller event of the given version,
+// the returned events overlap it when the conflict policy is warn. The id, version and reminder state
+// can't be patched, the reminders are rescheduled only if the start date changes or they are replaced.
+func (a *App) PatchEvent(ctx context.Context,
+	id string,
+	version int64,
+	patch map[string]any,
+) (storage.Event, []storage.Event, error) {
+	if version == 0 {
+		return storage.Event{}, nil, validationError("version", "version is required")
+	}
+
+	stored, err := a.ownedEvent(ctx, id)
+	if err != nil {
+		return storage.Event{}, nil, err
+	}
+
+	event, err := mergeEvent(stored, patch)
+	if err != nil {
+		return storage.Event{}, nil, err
+	}
+
+	event.ID = stored.ID
+	event.Version = version
+	err = claimOwner(ctx, &event)
+	if err != nil {
+		return storage.Event{}, nil, err
+	}
+
+	err = a.validateEvent(&event)
+	if err != nil {
+		return storage.Event{}, nil, err
+	}
+
+	event.StartDate = event.StartDate.UTC()
+	event.ExDates = normalizeExDates(event.ExDates)
+	event.Attendees = inviteAttendees(event.Attendees, stored.Attendees)
+	err = scheduleReminders(&event, &stored, time.Now())
+	if err != nil {
+		return storage.Event{}, nil, err
+	}
+
+	var conflicts []storage.Event
+	check, err := a.conflictCheck(&event, &conflicts)
+	if err != nil {
+		return storage.Event{}, nil, err
+	}
+
+	entry, err := auditEntry(ctx, storage.AuditUpdate, &stored, &event)
+	if err != nil {
+		return storage.Event{}, nil, err
+	}
+
+	err = a.storage.UpdateEventWithAudit(ctx, &event, entry, check)
+	if err != nil {
+		return storage.Event{}, nil, storageError(err)
+	}
+
+	return event, conflicts, nil
+}
+
+// mergeEvent applies the patch to the JSON document of the event.
+func mergeEvent(event storage.Event, patch map[string]any) (storage.Event, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	var document map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&document)
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	data, err = json.Marshal(mergePatch(document, patch))
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	var result storage.Event
+	err = json.Unmarshal(data, &result)
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr):
+		return storage.Event{}, validationError(typeErr.Field, fmt.Sprintf("%s must be %s", typeErr.Field, typeErr.Type))
+	case err != nil:
+		return storage.Event{}, validationError("patch", err.Error())
+	}
+
+	return result, nil
+}
+
+// mergePatch merges the patch into the target: null removes the member,
+// objects are merged member by member and any other value replaces the target.
+func mergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any, len(patchObject))
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+
+	return targetObject
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// MaxReminders is the maximum number of the event reminders.
+const MaxReminders = 10
+
+// scheduleReminders keeps the sent state of the reminders the stored event already has.
+// The state is reset if the event start date changes, only the scheduler changes it otherwise.
+// A new relative reminder of a recurring event starts after the occurrences which started by now,
+// so the scheduler doesn't remind of the series history.
+func scheduleReminders(event *storage.Event, stored *storage.Event, timeNow time.Time) error {
+	reminders := make([]storage.EventReminder, 0, len(event.Reminders))
+	for _, reminder := range event.Reminders {
+		scheduled := storage.EventReminder{Before: reminder.Before}
+		if reminder.At != nil {
+			at := reminder.At.UTC().Truncate(time.Second)
+			scheduled.At = &at
+		}
+
+		var previous *storage.EventReminder
+		if stored != nil && stored.StartDate.Equal(event.StartDate) {
+			previous = stored.Reminder(scheduled.Key())
+		}
+
+		switch {
+		case previous != nil:
+			scheduled.IsSend = previous.IsSend
+			scheduled.SentUntil = previous.SentUntil
+		case scheduled.At == nil && event.IsRecurring():
+			started, err := event.PreviousOccurrence(timeNow)
+			if err != nil {
+				return err
+			}
+
+			scheduled.SentUntil = started
+		}
+
+		reminders = append(reminders, scheduled)
+	}
+
+	event.Reminders = reminders
+	return nil
+}
+
+func validateReminders(event *storage.Event) error {
+	if len(event.Reminders) > MaxReminders {
+		return validationError("reminders", fmt.Sprintf("reminders count can't be greater than %d", MaxReminders))
+	}
+
+	keys := make(map[string]bool, len(event.Reminders))
+	for _, reminder := range event.Reminders {
+		switch {
+		case reminder.At != nil && reminder.Before != 0:
+			return validationError("reminders", "reminder is either before the start or at the time")
+		case reminder.At != nil && reminder.At.IsZero():
+			return validationError("reminders", "reminder time is required")
+		case reminder.Before < 0:
+			return validationError("reminders", "reminder can't be after the start")
+		case keys[reminder.Key()]:
+			return validationError("reminders", fmt.Sprintf("reminder %s is set twice", reminder.Key()))
+		}
+
+		keys[reminder.Key()] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// ListTrash returns the deleted events of the caller, the last deleted first.
+func (a *App) ListTrash(ctx context.Context) ([]storage.Event, error) {
+	caller, err := callerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.storage.GetDeletedEvents(ctx, caller)
+}
+
+// RestoreEvent takes the deleted caller event out of the trash.
+func (a *App) RestoreEvent(ctx context.Context, id string) (storage.Event, error) {
+	deleted, err := a.trashedEvent(ctx, id)
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	restored := deleted
+	restored.DeletedAt = nil
+	entry, err := auditEntry(ctx, storage.AuditRestore, &deleted, &restored)
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	err = a.storage.RestoreEventWithAudit(ctx, id, entry)
+	if err != nil {
+		return storage.Event{}, storageError(err)
+	}
+
+	restored.Version++
+	return restored, nil
+}
+
+// trashedEvent returns the deleted caller event.
+func (a *App) trashedEvent(ctx context.Context, id string) (storage.Event, error) {
+	caller, err := callerOf(ctx)
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	deleted, err := a.storage.GetDeletedEvents(ctx, caller)
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	for _, event := range deleted {
+		if event.ID == id {
+			return event, nil
+		}
+	}
+
+	return storage.Event{}, storageError(fmt.Errorf("%w: event %s is not in the trash", storage.ErrEventDoesNotExist, id))
+}
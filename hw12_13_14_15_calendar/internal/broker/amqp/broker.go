@@ -0,0 +1,160 @@
+package amqpbroker
+
+//nolint:depguard
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// Broker publishes to and consumes from durable RabbitMQ queues through the default exchange,
+// the queues are declared on creation.
+type Broker struct {
+	conn    *amqp.Connection
+	channel *amqp.Channel
+	config  configs.AMQPConfig
+	logger  *zap.Logger
+}
+
+func New(config configs.AMQPConfig, logger *zap.Logger) (*Broker, error) {
+	conn, err := amqp.Dial(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("amqp connection failed: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("amqp channel failed: %w", err)
+	}
+
+	if err := channel.Confirm(false); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("amqp confirm mode failed: %w", err)
+	}
+
+	for _, queue := range []string{config.ProduceQueue, config.ConsumeQueue, config.DeadLetterQueue} {
+		if queue == "" {
+			continue
+		}
+
+		if _, err := channel.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("amqp queue %s declaration failed: %w", queue, err)
+		}
+	}
+
+	return &Broker{conn: conn, channel: channel, config: config, logger: logger}, nil
+}
+
+// Publish sends the message as persistent and waits for the broker confirmation.
+func (b *Broker) Publish(ctx context.Context, message *broker.Message) error {
+	return b.publish(ctx, b.config.ProduceQueue, message)
+}
+
+func (b *Broker) publish(ctx context.Context, queue string, message *broker.Message) error {
+	headers := make(amqp.Table, len(message.Headers))
+	for key, value := range message.Headers {
+		headers[key] = value
+	}
+
+	b.logger.Info("send message to amqp", zap.String("queue", queue), zap.String("key", message.Key))
+	confirmation, err := b.channel.PublishWithDeferredConfirmWithContext(ctx, "", queue, false, false,
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			MessageId:    message.Key,
+			Headers:      headers,
+			Body:         message.Payload,
+		})
+	if err != nil {
+		return err
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !acked {
+		return fmt.Errorf("amqp message to %s is not acknowledged", queue)
+	}
+
+	return nil
+}
+
+// Subscribe acknowledges a message once it is handled or dead lettered. A message the handler failed is
+// sent to the dead letter queue, without one it is rejected, so the queue policy decides whether it is dropped
+// or dead lettered by RabbitMQ. A message which can't be dead lettered or was interrupted by the shutdown
+// is requeued.
+func (b *Broker) Subscribe(ctx context.Context, handler broker.Handler) error {
+	deliveries, err := b.channel.ConsumeWithContext(ctx, b.config.ConsumeQueue, b.config.ServiceName,
+		false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	b.logger.Info("message consumer start messaging in queue: " + b.config.ConsumeQueue)
+	for delivery := range deliveries {
+		message := &broker.Message{Key: delivery.MessageId, Payload: delivery.Body, Headers: make(map[string]string)}
+		for key, value := range delivery.Headers {
+			if str, ok := value.(string); ok {
+				message.Headers[key] = str
+			}
+		}
+
+		b.logger.Info("consume message:", zap.ByteString("body:", delivery.Body))
+		if err := b.settle(ctx, delivery, message, handler(ctx, message)); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	return amqp.ErrClosed
+}
+
+func (b *Broker) settle(ctx context.Context, delivery amqp.Delivery, message *broker.Message, err error) error {
+	if err == nil {
+		return delivery.Ack(false)
+	}
+
+	if ctx.Err() != nil {
+		b.logger.Warn("consume message interrupted", zap.Error(err))
+		return delivery.Nack(false, true)
+	}
+
+	b.logger.Error("consume message failed", zap.Error(err))
+	if b.config.DeadLetterQueue == "" {
+		return delivery.Nack(false, false)
+	}
+
+	message.Headers[broker.DeadLetterReasonHeader] = err.Error()
+	message.Headers[broker.DeadLetterTopicHeader] = b.config.ConsumeQueue
+	if err := b.publish(ctx, b.config.DeadLetterQueue, message); err != nil {
+		return errors.Join(err, delivery.Nack(false, true))
+	}
+
+	b.logger.Warn("message dead lettered", zap.String("queue", b.config.DeadLetterQueue), zap.String("key", message.Key))
+	return delivery.Ack(false)
+}
+
+// Ping reports whether the connection and the channel are open, they aren't reopened once closed.
+func (b *Broker) Ping(_ context.Context) error {
+	if b.conn.IsClosed() || b.channel.IsClosed() {
+		return amqp.ErrClosed
+	}
+
+	return nil
+}
+
+func (b *Broker) Close() error {
+	return b.conn.Close()
+}
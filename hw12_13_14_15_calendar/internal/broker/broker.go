@@ -0,0 +1,44 @@
+package broker
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	ReminderIDHeader = "reminder-id"
+
+	// DeadLetterReasonHeader carries the error of a dead lettered message.
+	DeadLetterReasonHeader = "dead-letter-reason"
+	// DeadLetterTopicHeader carries the topic or the queue a dead lettered message was consumed from.
+	DeadLetterTopicHeader = "dead-letter-topic"
+)
+
+var ErrUnknownBroker = errors.New("unknown broker type")
+
+// Message is a broker independent message, Key keeps the messages of one event in order
+// when the broker partitions them.
+type Message struct {
+	Key     string
+	Payload []byte
+	Headers map[string]string
+}
+
+// Handler processes a consumed message, the message is acknowledged after the handler returns.
+type Handler func(ctx context.Context, message *Message) error
+
+type Publisher interface {
+	Publish(ctx context.Context, message *Message) error
+	Close() error
+}
+
+// Pinger is implemented by the publishers and subscribers which report whether their broker connection is ready.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type Subscriber interface {
+	// Subscribe consumes messages until the context is done or the connection fails.
+	Subscribe(ctx context.Context, handler Handler) error
+	Close() error
+}
@@ -0,0 +1,52 @@
+package factory
+
+//nolint:depguard
+import (
+	"errors"
+	"fmt"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker"
+	amqpbroker "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker/amqp"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/kafka"
+	"go.uber.org/zap"
+)
+
+const (
+	TypeKafka  = "kafka"
+	TypeAMQP   = "amqp"
+	TypeMemory = "memory"
+)
+
+// ErrMemoryBroker is returned for the memory broker: its messages never leave the process, while the scheduler,
+// the sender and the storer publish and consume in separate processes, so every published reminder would be lost.
+// The service tests pass the memory broker to the jobs and the consumers directly.
+var ErrMemoryBroker = errors.New("memory broker can't connect separate services")
+
+// NewPublisher creates the publisher of the configured broker.
+func NewPublisher(config configs.Config, logger *zap.Logger) (broker.Publisher, error) {
+	switch config.Broker.Type {
+	case "", TypeKafka:
+		return kafka.NewProducer(config.Kafka, logger)
+	case TypeAMQP:
+		return amqpbroker.New(config.AMQP, logger)
+	case TypeMemory:
+		return nil, ErrMemoryBroker
+	default:
+		return nil, fmt.Errorf("%w: %s", broker.ErrUnknownBroker, config.Broker.Type)
+	}
+}
+
+// NewSubscriber creates the subscriber of the configured broker.
+func NewSubscriber(config configs.Config, logger *zap.Logger) (broker.Subscriber, error) {
+	switch config.Broker.Type {
+	case "", TypeKafka:
+		return kafka.NewConsumer(config.Kafka, logger)
+	case TypeAMQP:
+		return amqpbroker.New(config.AMQP, logger)
+	case TypeMemory:
+		return nil, ErrMemoryBroker
+	default:
+		return nil, fmt.Errorf("%w: %s", broker.ErrUnknownBroker, config.Broker.Type)
+	}
+}
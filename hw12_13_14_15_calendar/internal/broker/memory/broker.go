@@ -0,0 +1,84 @@
+package memorybroker
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker"
+)
+
+const DefaultBufferSize = 1024
+
+var (
+	ErrBrokerFull   = errors.New("broker buffer is full")
+	ErrBrokerClosed = errors.New("broker is closed")
+)
+
+// Broker is an in-process channel broker, it implements both broker.Publisher and broker.Subscriber.
+// Publishing doesn't block, a message is rejected when the buffer is full.
+type Broker struct {
+	mu       sync.RWMutex
+	messages chan *broker.Message
+	closed   bool
+}
+
+func New(bufferSize int) *Broker {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+
+	return &Broker{messages: make(chan *broker.Message, bufferSize)}
+}
+
+func (b *Broker) Publish(ctx context.Context, message *broker.Message) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return ErrBrokerClosed
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case b.messages <- message:
+		return nil
+	default:
+		return ErrBrokerFull
+	}
+}
+
+func (b *Broker) Subscribe(ctx context.Context, handler broker.Handler) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-b.messages:
+			if !ok {
+				return nil
+			}
+
+			//nolint:errcheck
+			handler(ctx, message)
+		}
+	}
+}
+
+// Len returns the number of messages waiting for a subscriber.
+func (b *Broker) Len() int {
+	return len(b.messages)
+}
+
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		close(b.messages)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package memorybroker
+
+//nolint:depguard
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroker(t *testing.T) {
+	t.Run("publish and subscribe", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		memory := New(2)
+		err := memory.Publish(ctx, &broker.Message{Key: "first", Payload: []byte("1")})
+		require.NoError(t, err)
+
+		err = memory.Publish(ctx, &broker.Message{Key: "second", Payload: []byte("2")})
+		require.NoError(t, err)
+
+		err = memory.Publish(ctx, &broker.Message{Key: "third", Payload: []byte("3")})
+		require.ErrorIs(t, err, ErrBrokerFull)
+		require.Equal(t, 2, memory.Len())
+
+		keys := make([]string, 0)
+		err = memory.Subscribe(ctx, func(_ context.Context, message *broker.Message) error {
+			keys = append(keys, message.Key)
+			if len(keys) == 2 {
+				cancel()
+			}
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"first", "second"}, keys)
+		require.Equal(t, 0, memory.Len())
+	})
+
+	t.Run("closed broker", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := New(0)
+		err := memory.Publish(ctx, &broker.Message{Key: "buffered"})
+		require.NoError(t, err)
+
+		require.NoError(t, memory.Close())
+		require.NoError(t, memory.Close())
+
+		err = memory.Publish(ctx, &broker.Message{Key: "rejected"})
+		require.ErrorIs(t, err, ErrBrokerClosed)
+
+		keys := make([]string, 0)
+		err = memory.Subscribe(ctx, func(_ context.Context, message *broker.Message) error {
+			keys = append(keys, message.Key)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"buffered"}, keys)
+	})
+}
@@ -0,0 +1,113 @@
+// Package health serves the liveness and readiness probes of the calendar services.
+// The calendar serves them on its HTTP API, the scheduler and the storer on their metrics listener.
+package health
+
+//nolint:depguard
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// DefaultCheckTimeout bounds every readiness check, a check which doesn't return in time fails.
+const DefaultCheckTimeout = 2 * time.Second
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+var ErrCheckTimeout = errors.New("check timed out")
+
+// Check probes a dependency the service can't serve without, Probe returns nil if the dependency is ready.
+// Describe, if set, reports the state of the ready dependency instead of ok.
+type Check struct {
+	Name     string
+	Probe    func(ctx context.Context) error
+	Describe func() string
+}
+
+// Report is the probe response, Checks holds the status or the error of every readiness check.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Liveness reports the process is up, it checks no dependencies so a broken database
+// doesn't get the service restarted.
+func Liveness() http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, _ *http.Request) {
+		writeReport(resp, http.StatusOK, Report{Status: StatusOK})
+	})
+}
+
+// Readiness runs the checks concurrently and reports 503 Service Unavailable if any of them fails.
+func Readiness(checks ...Check) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		report := Run(req.Context(), checks...)
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+
+		writeReport(resp, status, report)
+	})
+}
+
+// Run runs the checks concurrently, each bounded by DefaultCheckTimeout.
+func Run(ctx context.Context, checks ...Check) Report {
+	ctx, cancel := context.WithTimeout(ctx, DefaultCheckTimeout)
+	defer cancel()
+
+	results := make([]chan error, len(checks))
+	for i, check := range checks {
+		results[i] = make(chan error, 1)
+		go func(result chan<- error) {
+			result <- check.Probe(ctx)
+		}(results[i])
+	}
+
+	report := Report{Status: StatusOK, Checks: make(map[string]string, len(checks))}
+	for i, check := range checks {
+		var err error
+		select {
+		case err = <-results[i]:
+		case <-ctx.Done():
+			err = ErrCheckTimeout
+		}
+
+		switch {
+		case err != nil:
+			report.Status = StatusUnavailable
+			report.Checks[check.Name] = err.Error()
+		case check.Describe != nil:
+			report.Checks[check.Name] = check.Describe()
+		default:
+			report.Checks[check.Name] = StatusOK
+		}
+	}
+
+	return report
+}
+
+// Register serves the liveness probe on /healthz and the readiness probe on /readyz.
+func Register(mux *http.ServeMux, checks ...Check) {
+	mux.Handle("GET /healthz", Liveness())
+	mux.Handle("GET /readyz", Readiness(checks...))
+}
+
+func writeReport(resp http.ResponseWriter, status int, report Report) {
+	result, err := jsoniter.Marshal(report)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	resp.Header().Set("Cache-Control", "no-store")
+	resp.WriteHeader(status)
+	_, _ = resp.Write(result)
+}
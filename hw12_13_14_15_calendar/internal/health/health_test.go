@@ -0,0 +1,78 @@
+package health
+
+//nolint:depguard
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+var errUnreachable = errors.New("database is unreachable")
+
+func TestHealth(t *testing.T) {
+	ready := Check{Name: "ready", Probe: func(context.Context) error { return nil }}
+	failed := Check{Name: "failed", Probe: func(context.Context) error { return errUnreachable }}
+	stop := make(chan struct{})
+	defer close(stop)
+	stuck := Check{Name: "stuck", Probe: func(context.Context) error {
+		<-stop
+		return nil
+	}}
+
+	probe := func(handler http.Handler) (int, Report) {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+
+		var report Report
+		require.NoError(t, jsoniter.Unmarshal(resp.Body.Bytes(), &report))
+		return resp.Code, report
+	}
+
+	t.Run("live without checks", func(t *testing.T) {
+		code, report := probe(Liveness())
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, Report{Status: StatusOK}, report)
+	})
+
+	t.Run("ready if every check passes", func(t *testing.T) {
+		code, report := probe(Readiness(ready))
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, Report{Status: StatusOK, Checks: map[string]string{"ready": StatusOK}}, report)
+	})
+
+	t.Run("ready check described", func(t *testing.T) {
+		described := Check{Name: "described", Probe: ready.Probe, Describe: func() string { return "leader" }}
+		code, report := probe(Readiness(described))
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, Report{Status: StatusOK, Checks: map[string]string{"described": "leader"}}, report)
+
+		described.Probe = failed.Probe
+		code, report = probe(Readiness(described))
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.Equal(t, errUnreachable.Error(), report.Checks["described"])
+	})
+
+	t.Run("unavailable if a check fails", func(t *testing.T) {
+		code, report := probe(Readiness(ready, failed))
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.Equal(t, Report{Status: StatusUnavailable, Checks: map[string]string{
+			"ready":  StatusOK,
+			"failed": errUnreachable.Error(),
+		}}, report)
+	})
+
+	t.Run("unavailable if a check times out", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		report := Run(ctx, ready, stuck)
+		require.Equal(t, StatusUnavailable, report.Status)
+		require.Equal(t, ErrCheckTimeout.Error(), report.Checks["stuck"])
+	})
+}
@@ -0,0 +1,214 @@
+package ical
+
+//nolint:depguard
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	ics "github.com/arran4/golang-ical"
+)
+
+const (
+	productID       = "-//BingaBonga//otus calendar//EN"
+	timestampFormat = "20060102T150405Z"
+)
+
+var (
+	ErrStartDateRequired = errors.New("DTSTART is required")
+	ErrInvalidDuration   = errors.New("invalid DURATION")
+
+	durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+)
+
+// Component is a VEVENT of an imported calendar converted to an event.
+// Err is set if the VEVENT can't be converted.
+type Component struct {
+	UID   string
+	Event storage.Event
+	Err   error
+}
+
+// Encode writes the events as an RFC 5545 VCALENDAR document.
+func Encode(w io.Writer, events []storage.Event) error {
+	calendar := ics.NewCalendar()
+	calendar.SetProductId(productID)
+
+	timeNow := time.Now()
+	for _, event := range events {
+		vEvent := calendar.AddEvent(event.ID)
+		vEvent.SetDtStampTime(timeNow)
+		vEvent.SetStartAt(event.StartDate)
+		vEvent.SetEndAt(event.StartDate.Add(event.Duration))
+		vEvent.SetSummary(event.Title)
+		if event.Description != "" {
+			vEvent.SetDescription(event.Description)
+		}
+
+		if event.IsRecurring() {
+			vEvent.AddRrule(event.RRule)
+		}
+
+		for _, exDate := range event.ExDates {
+			vEvent.AddExdate(exDate.UTC().Format(timestampFormat))
+		}
+	}
+
+	return calendar.SerializeTo(w)
+}
+
+// Decode reads an RFC 5545 VCALENDAR document and converts each VEVENT to an event.
+// Only an unreadable document is reported as an error, a broken VEVENT is reported in its Component.
+func Decode(r io.Reader) ([]Component, error) {
+	calendar, err := ics.ParseCalendar(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid calendar: %w", err)
+	}
+
+	vEvents := calendar.Events()
+	components := make([]Component, 0, len(vEvents))
+	for _, vEvent := range vEvents {
+		event, err := toEvent(vEvent)
+		components = append(components, Component{UID: vEvent.Id(), Event: event, Err: err})
+	}
+
+	return components, nil
+}
+
+func toEvent(vEvent *ics.VEvent) (storage.Event, error) {
+	event := storage.Event{
+		Title:       propertyValue(vEvent, ics.ComponentPropertySummary),
+		Description: propertyValue(vEvent, ics.ComponentPropertyDescription),
+		RRule:       propertyValue(vEvent, ics.ComponentPropertyRrule),
+	}
+
+	if !vEvent.HasProperty(ics.ComponentPropertyDtStart) {
+		return event, ErrStartDateRequired
+	}
+
+	startDate, err := vEvent.GetStartAt()
+	if err != nil {
+		return event, fmt.Errorf("invalid DTSTART: %w", err)
+	}
+	event.StartDate = startDate.UTC()
+
+	event.Duration, err = eventDuration(vEvent, startDate)
+	if err != nil {
+		return event, err
+	}
+
+	for _, property := range vEvent.GetProperties(ics.ComponentPropertyExdate) {
+		exDates, err := parseExDates(property)
+		if err != nil {
+			return event, err
+		}
+
+		event.ExDates = append(event.ExDates, exDates...)
+	}
+
+	return event, nil
+}
+
+func eventDuration(vEvent *ics.VEvent, startDate time.Time) (time.Duration, error) {
+	if vEvent.HasProperty(ics.ComponentPropertyDtEnd) {
+		endDate, err := vEvent.GetEndAt()
+		if err != nil {
+			return 0, fmt.Errorf("invalid DTEND: %w", err)
+		}
+
+		return endDate.Sub(startDate), nil
+	}
+
+	if vEvent.HasProperty(ics.ComponentPropertyDuration) {
+		return parseDuration(propertyValue(vEvent, ics.ComponentPropertyDuration))
+	}
+
+	if isDate(vEvent.GetProperty(ics.ComponentPropertyDtStart)) {
+		return 24 * time.Hour, nil
+	}
+
+	return 0, nil
+}
+
+// parseExDates parses the comma separated EXDATE values, honoring its TZID parameter.
+func parseExDates(property *ics.IANAProperty) ([]time.Time, error) {
+	location := time.UTC
+	if tzID, ok := property.ICalParameters[string(ics.ParameterTzid)]; ok && len(tzID) == 1 {
+		var err error
+		location, err = time.LoadLocation(tzID[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid EXDATE: %w", err)
+		}
+	}
+
+	exDates := make([]time.Time, 0)
+	for _, value := range strings.Split(property.Value, ",") {
+		layout := "20060102T150405"
+		switch {
+		case strings.HasSuffix(value, "Z"):
+			layout = timestampFormat
+		case len(value) == len("20060102"):
+			layout = "20060102"
+		}
+
+		exDate, err := time.ParseInLocation(layout, value, location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EXDATE: %w", err)
+		}
+
+		exDates = append(exDates, exDate.UTC())
+	}
+
+	return exDates, nil
+}
+
+// parseDuration parses an RFC 5545 DURATION value, e.g. PT1H30M or P1W.
+func parseDuration(value string) (time.Duration, error) {
+	matched := durationPattern.FindStringSubmatch(value)
+	if matched == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidDuration, value)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var duration time.Duration
+	for i, unit := range units {
+		if matched[i+2] == "" {
+			continue
+		}
+
+		count, err := strconv.Atoi(matched[i+2])
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidDuration, value)
+		}
+
+		duration += time.Duration(count) * unit
+	}
+
+	if matched[1] == "-" {
+		duration = -duration
+	}
+
+	return duration, nil
+}
+
+func propertyValue(vEvent *ics.VEvent, property ics.ComponentProperty) string {
+	if p := vEvent.GetProperty(property); p != nil {
+		return p.Value
+	}
+
+	return ""
+}
+
+func isDate(property *ics.IANAProperty) bool {
+	if property == nil {
+		return false
+	}
+
+	values := property.ICalParameters[string(ics.ParameterValue)]
+	return len(values) == 1 && values[0] == string(ics.ValueDataTypeDate) || len(property.Value) == len("20060102")
+}
@@ -0,0 +1,87 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	//nolint:depguard
+	"github.com/stretchr/testify/require"
+)
+
+func TestICal(t *testing.T) {
+	t.Run("events encoded and decoded", func(t *testing.T) {
+		event := storage.Event{
+			ID:          "test_id",
+			Title:       "test_title, with comma",
+			StartDate:   time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC),
+			Duration:    90 * time.Minute,
+			Description: "test_description\nsecond line",
+			RRule:       "FREQ=DAILY;COUNT=5",
+			ExDates:     []time.Time{time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)},
+		}
+
+		var buf bytes.Buffer
+		err := Encode(&buf, []storage.Event{event})
+		require.NoError(t, err)
+
+		components, err := Decode(&buf)
+		require.NoError(t, err)
+		require.Len(t, components, 1)
+		require.NoError(t, components[0].Err)
+		require.Equal(t, "test_id", components[0].UID)
+		require.Equal(t, event.Title, components[0].Event.Title)
+		require.Equal(t, event.Description, components[0].Event.Description)
+		require.Equal(t, event.StartDate, components[0].Event.StartDate)
+		require.Equal(t, event.Duration, components[0].Event.Duration)
+		require.Equal(t, event.RRule, components[0].Event.RRule)
+		require.Equal(t, event.ExDates, components[0].Event.ExDates)
+	})
+
+	t.Run("events decoded from other calendars", func(t *testing.T) {
+		document := strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"VERSION:2.0",
+			"PRODID:-//test//test//EN",
+			"BEGIN:VEVENT",
+			"UID:duration",
+			"SUMMARY:duration",
+			"DTSTART;TZID=Europe/Moscow:20240304T100000",
+			"DURATION:PT1H30M",
+			"EXDATE;TZID=Europe/Moscow:20240305T100000,20240306T100000",
+			"END:VEVENT",
+			"BEGIN:VEVENT",
+			"UID:all_day",
+			"SUMMARY:all day",
+			"DTSTART;VALUE=DATE:20240304",
+			"END:VEVENT",
+			"BEGIN:VEVENT",
+			"UID:broken",
+			"SUMMARY:broken",
+			"DTSTART:20240304T100000Z",
+			"DURATION:PT",
+			"END:VEVENT",
+			"END:VCALENDAR",
+		}, "\r\n")
+
+		components, err := Decode(strings.NewReader(document))
+		require.NoError(t, err)
+		require.Len(t, components, 3)
+
+		require.NoError(t, components[0].Err)
+		require.Equal(t, time.Date(2024, time.March, 4, 7, 0, 0, 0, time.UTC), components[0].Event.StartDate)
+		require.Equal(t, 90*time.Minute, components[0].Event.Duration)
+		require.Equal(t, []time.Time{
+			time.Date(2024, time.March, 5, 7, 0, 0, 0, time.UTC),
+			time.Date(2024, time.March, 6, 7, 0, 0, 0, time.UTC),
+		}, components[0].Event.ExDates)
+
+		require.NoError(t, components[1].Err)
+		require.Equal(t, 24*time.Hour, components[1].Event.Duration)
+
+		require.ErrorIs(t, components[2].Err, ErrInvalidDuration)
+	})
+}
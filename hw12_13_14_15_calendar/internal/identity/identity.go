@@ -0,0 +1,96 @@
+package identity
+
+//nolint:depguard
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	DefaultHeader       = "X-User-Id"
+	AuthorizationHeader = "Authorization"
+
+	bearerPrefix = "Bearer "
+)
+
+var (
+	ErrMissingIdentity = errors.New("caller identity is missing")
+	ErrInvalidToken    = errors.New("invalid bearer token")
+)
+
+type callerKey struct{}
+
+// Authenticator identifies the caller of a request by the subject of the bearer JWT signed with the configured
+// HS256 key, or by the identity header value when no key is configured.
+type Authenticator struct {
+	header string
+	key    []byte
+}
+
+func New(config configs.AuthConfig) *Authenticator {
+	header := config.Header
+	if header == "" {
+		header = DefaultHeader
+	}
+
+	return &Authenticator{header: header, key: []byte(config.JWTKey)}
+}
+
+// Authenticate returns the caller ID, get returns the request header value by its name.
+func (a *Authenticator) Authenticate(get func(name string) string) (string, error) {
+	if len(a.key) == 0 {
+		caller := strings.TrimSpace(get(a.header))
+		if caller == "" {
+			return "", ErrMissingIdentity
+		}
+
+		return caller, nil
+	}
+
+	authorization := get(AuthorizationHeader)
+	if authorization == "" {
+		return "", ErrMissingIdentity
+	}
+
+	if !strings.HasPrefix(authorization, bearerPrefix) {
+		return "", ErrInvalidToken
+	}
+
+	return a.verify(strings.TrimPrefix(authorization, bearerPrefix))
+}
+
+// JWT reports whether the callers are identified by bearer tokens.
+func (a *Authenticator) JWT() bool {
+	return len(a.key) > 0
+}
+
+func (a *Authenticator) verify(raw string) (string, error) {
+	token, err := jwt.ParseWithClaims(raw, &jwt.RegisteredClaims{}, func(*jwt.Token) (interface{}, error) {
+		return a.key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	subject, err := token.Claims.GetSubject()
+	if err != nil || subject == "" {
+		return "", fmt.Errorf("%w: subject is required", ErrInvalidToken)
+	}
+
+	return subject, nil
+}
+
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// Caller returns the caller authenticated for the request.
+func Caller(ctx context.Context) (string, bool) {
+	caller, ok := ctx.Value(callerKey{}).(string)
+	return caller, ok && caller != ""
+}
@@ -0,0 +1,84 @@
+package identity
+
+//nolint:depguard
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func signedToken(t *testing.T, method jwt.SigningMethod, key []byte, claims jwt.RegisteredClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Run("header", func(t *testing.T) {
+		authenticator := New(configs.AuthConfig{})
+		header := http.Header{}
+
+		_, err := authenticator.Authenticate(header.Get)
+		require.ErrorIs(t, err, ErrMissingIdentity)
+
+		header.Set(DefaultHeader, " test_user ")
+		caller, err := authenticator.Authenticate(header.Get)
+		require.NoError(t, err)
+		require.Equal(t, "test_user", caller)
+
+		authenticator = New(configs.AuthConfig{Header: "X-Owner"})
+		_, err = authenticator.Authenticate(header.Get)
+		require.ErrorIs(t, err, ErrMissingIdentity)
+	})
+
+	t.Run("jwt", func(t *testing.T) {
+		key := []byte("secret")
+		authenticator := New(configs.AuthConfig{JWTKey: string(key)})
+		expiresAt := jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+		header := http.Header{}
+		header.Set(DefaultHeader, "test_user")
+		_, err := authenticator.Authenticate(header.Get)
+		require.ErrorIs(t, err, ErrMissingIdentity)
+
+		header.Set(AuthorizationHeader, "Bearer "+signedToken(t, jwt.SigningMethodHS256, key,
+			jwt.RegisteredClaims{Subject: "test_user", ExpiresAt: expiresAt}))
+		caller, err := authenticator.Authenticate(header.Get)
+		require.NoError(t, err)
+		require.Equal(t, "test_user", caller)
+
+		for name, token := range map[string]string{
+			"basic scheme": "Basic dGVzdDp0ZXN0",
+			"wrong key": "Bearer " + signedToken(t, jwt.SigningMethodHS256, []byte("other"),
+				jwt.RegisteredClaims{Subject: "test_user", ExpiresAt: expiresAt}),
+			"wrong method": "Bearer " + signedToken(t, jwt.SigningMethodHS512, key,
+				jwt.RegisteredClaims{Subject: "test_user", ExpiresAt: expiresAt}),
+			"expired": "Bearer " + signedToken(t, jwt.SigningMethodHS256, key,
+				jwt.RegisteredClaims{Subject: "test_user", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}),
+			"no expiration": "Bearer " + signedToken(t, jwt.SigningMethodHS256, key,
+				jwt.RegisteredClaims{Subject: "test_user"}),
+			"no subject": "Bearer " + signedToken(t, jwt.SigningMethodHS256, key,
+				jwt.RegisteredClaims{ExpiresAt: expiresAt}),
+		} {
+			header.Set(AuthorizationHeader, token)
+			_, err := authenticator.Authenticate(header.Get)
+			require.ErrorIs(t, err, ErrInvalidToken, name)
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		_, ok := Caller(context.Background())
+		require.False(t, ok)
+
+		caller, ok := Caller(WithCaller(context.Background(), "test_user"))
+		require.True(t, ok)
+		require.Equal(t, "test_user", caller)
+	})
+}
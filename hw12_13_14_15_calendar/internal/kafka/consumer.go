@@ -3,29 +3,130 @@ package kafka
 //nolint:depguard
 import (
 	"context"
+	"errors"
+	"sync/atomic"
+	"time"
 
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
-	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/metrics"
 	"github.com/IBM/sarama"
 	"go.uber.org/zap"
 )
 
+const (
+	DefaultRetryBackoff = time.Second
+	// DefaultMaxRetryBackoff keeps a retried message well within the consumer group rebalance timeout.
+	DefaultMaxRetryBackoff = 30 * time.Second
+)
+
+var ErrNoSession = errors.New("kafka consumer group has no active session")
+
 type Consumer struct {
-	Logger         zap.Logger
-	Config         configs.KafkaConfig
-	ConsumeMessage func(context.Context, app.Storage, *sarama.ConsumerMessage) error
-	Storage        app.Storage
-	Context        context.Context
+	consumerGroup sarama.ConsumerGroup
+	deadLetter    sarama.SyncProducer
+	logger        *zap.Logger
+	config        configs.KafkaConfig
+	// session is set while the consumer group member holds a session, it is unset during the rebalances.
+	session *atomic.Bool
+}
+
+// NewConsumer creates the consumer group, a dead letter producer is created if the dead letter topic is set.
+func NewConsumer(config configs.KafkaConfig, logger *zap.Logger) (*Consumer, error) {
+	consumerGroup, err := sarama.NewConsumerGroup([]string{config.URL}, config.Group, getConsumerConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	consumer := &Consumer{consumerGroup: consumerGroup, config: config, logger: logger, session: &atomic.Bool{}}
+	if config.DeadLetterTopic != "" {
+		consumer.deadLetter, err = sarama.NewSyncProducer([]string{config.URL}, getProducerConfig(config))
+		if err != nil {
+			consumerGroup.Close()
+			return nil, err
+		}
+	}
+
+	return consumer, nil
+}
+
+// Subscribe consumes the consume topic until the context is done.
+func (consumer *Consumer) Subscribe(ctx context.Context, handler broker.Handler) error {
+	consumer.logger.Info("message consumer start messaging in address: " + consumer.config.URL)
+	groupHandler := consumerGroupHandler{
+		logger:     consumer.logger,
+		handler:    handler,
+		deadLetter: consumer.deadLetter,
+		config:     consumer.config,
+		session:    consumer.session,
+	}
+	for {
+		err := consumer.consumerGroup.Consume(ctx, []string{consumer.config.ConsumeTopic}, groupHandler)
+		if ctx.Err() != nil || errors.Is(err, sarama.ErrClosedConsumerGroup) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
 }
 
-func (consumer Consumer) Setup(_ sarama.ConsumerGroupSession) error   { return nil }
-func (consumer Consumer) Cleanup(_ sarama.ConsumerGroupSession) error { return nil }
-func (consumer Consumer) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+// Ping reports whether the consumer group member holds a session with its claims.
+func (consumer *Consumer) Ping(_ context.Context) error {
+	if !consumer.session.Load() {
+		return ErrNoSession
+	}
+
+	return nil
+}
+
+func (consumer *Consumer) Close() error {
+	err := consumer.consumerGroup.Close()
+	if consumer.deadLetter != nil {
+		err = errors.Join(err, consumer.deadLetter.Close())
+	}
+
+	return err
+}
+
+type consumerGroupHandler struct {
+	logger     *zap.Logger
+	handler    broker.Handler
+	deadLetter sarama.SyncProducer
+	config     configs.KafkaConfig
+	session    *atomic.Bool
+}
+
+func (h consumerGroupHandler) Setup(_ sarama.ConsumerGroupSession) error {
+	h.session.Store(true)
+	return nil
+}
+
+func (h consumerGroupHandler) Cleanup(_ sarama.ConsumerGroupSession) error {
+	h.session.Store(false)
+	return nil
+}
+
+// ConsumeClaim marks a message once it is handled or dead lettered, a message which can't be dead lettered
+// stops the claim, so it is consumed again after the rebalance. A message the handler failed because
+// the session ended isn't marked either, it is consumed again after the restart.
+func (h consumerGroupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	for msg := range claim.Messages() {
-		consumer.Logger.Info("consume message:", zap.ByteString("body:", msg.Value))
-		err := consumer.ConsumeMessage(consumer.Context, consumer.Storage, msg)
+		h.logger.Info("consume message:", zap.ByteString("body:", msg.Value))
+		metrics.KafkaMessagesConsumed.WithLabelValues(msg.Topic).Inc()
+		err := h.handle(sess.Context(), msg)
+		if err != nil && sess.Context().Err() != nil {
+			h.logger.Warn("consume message interrupted", zap.Error(err))
+			return nil
+		}
+
 		if err != nil {
-			consumer.Logger.Error("consume message failed", zap.Error(err))
+			metrics.KafkaConsumerErrors.WithLabelValues(msg.Topic).Inc()
+			h.logger.Error("consume message failed", zap.Error(err))
+			if err := h.sendDeadLetter(msg, err); err != nil {
+				return err
+			}
 		}
 
 		sess.MarkMessage(msg, "")
@@ -33,26 +134,87 @@ func (consumer Consumer) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sa
 	return nil
 }
 
-func (consumer Consumer) getConsumerGroup() (sarama.ConsumerGroup, error) {
-	return sarama.NewConsumerGroup([]string{consumer.Config.URL}, consumer.Config.Group, consumer.getConsumerConfig())
+func (h consumerGroupHandler) handle(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	for retry := 0; ; retry++ {
+		err := h.handler(ctx, toMessage(msg))
+		if err == nil || retry >= h.config.MaxRetries {
+			return err
+		}
+
+		h.logger.Warn("consume message retry", zap.Int("retry", retry+1), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(h.retryDelay(retry)):
+		}
+	}
 }
 
-func (consumer Consumer) RegisterMessageConsumer() error {
-	consumerGroup, err := consumer.getConsumerGroup()
+// retryDelay doubles the backoff with every retry up to the max backoff, so a large MaxRetries doesn't
+// stall the partition past the consumer group session.
+func (h consumerGroupHandler) retryDelay(retry int) time.Duration {
+	backoff, limit := h.config.RetryBackoff, h.config.MaxRetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	if limit <= 0 {
+		limit = DefaultMaxRetryBackoff
+	}
+	for ; retry > 0 && backoff < limit; retry-- {
+		backoff *= 2
+	}
+
+	return min(backoff, limit)
+}
+
+// sendDeadLetter sends the original message to the dead letter topic with the error and source topic headers.
+// Without a dead letter topic the message is dropped.
+func (h consumerGroupHandler) sendDeadLetter(msg *sarama.ConsumerMessage, reason error) error {
+	if h.deadLetter == nil {
+		return nil
+	}
+
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+2)
+	for _, header := range msg.Headers {
+		key := string(header.Key)
+		if key != broker.DeadLetterReasonHeader && key != broker.DeadLetterTopicHeader {
+			headers = append(headers, *header)
+		}
+	}
+
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte(broker.DeadLetterReasonHeader), Value: []byte(reason.Error())},
+		sarama.RecordHeader{Key: []byte(broker.DeadLetterTopicHeader), Value: []byte(msg.Topic)},
+	)
+
+	_, _, err := h.deadLetter.SendMessage(&sarama.ProducerMessage{
+		Topic:   h.config.DeadLetterTopic,
+		Key:     sarama.ByteEncoder(msg.Key),
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: headers,
+	})
 	if err != nil {
 		return err
 	}
 
-	consumer.Logger.Info("message consumer start messaging in address: " + consumer.Config.URL)
-	for {
-		err := consumerGroup.Consume(consumer.Context, []string{consumer.Config.ConsumeTopic}, consumer)
-		if err != nil {
-			return err
-		}
+	h.logger.Warn("message dead lettered", zap.String("topic", h.config.DeadLetterTopic), zap.ByteString("key", msg.Key))
+	return nil
+}
+
+func toMessage(msg *sarama.ConsumerMessage) *broker.Message {
+	message := &broker.Message{
+		Key:     string(msg.Key),
+		Payload: msg.Value,
+		Headers: make(map[string]string, len(msg.Headers)),
 	}
+	for _, header := range msg.Headers {
+		message.Headers[string(header.Key)] = string(header.Value)
+	}
+
+	return message
 }
 
-func (consumer Consumer) getConsumerConfig() *sarama.Config {
+func getConsumerConfig() *sarama.Config {
 	config := sarama.NewConfig()
 	config.Version = sarama.DefaultVersion // specify appropriate Kafka version
 	config.Consumer.Offsets.Initial = sarama.OffsetOldest
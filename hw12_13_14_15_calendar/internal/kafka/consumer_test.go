@@ -0,0 +1,248 @@
+package kafka
+
+//nolint:depguard
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker"
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var errConsume = errors.New("consume failed")
+
+type testSession struct {
+	sarama.ConsumerGroupSession
+	ctx    context.Context
+	marked []int64
+}
+
+func (s *testSession) Context() context.Context { return s.ctx }
+
+func (s *testSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.marked = append(s.marked, msg.Offset)
+}
+
+type testClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *testClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+func newTestClaim(messages ...*sarama.ConsumerMessage) *testClaim {
+	claim := &testClaim{messages: make(chan *sarama.ConsumerMessage, len(messages))}
+	for _, msg := range messages {
+		claim.messages <- msg
+	}
+	close(claim.messages)
+
+	return claim
+}
+
+func TestConsumer(t *testing.T) {
+	config := configs.KafkaConfig{MaxRetries: 2, RetryBackoff: time.Millisecond, DeadLetterTopic: "dead-letter"}
+	testMessage := &sarama.ConsumerMessage{
+		Topic:   "events",
+		Offset:  1,
+		Key:     []byte("test_id"),
+		Value:   []byte(`{"id":"test_id"}`),
+		Headers: []*sarama.RecordHeader{{Key: []byte(broker.ReminderIDHeader), Value: []byte("test_id/1")}},
+	}
+
+	t.Run("message retried", func(t *testing.T) {
+		calls := 0
+		handler := consumerGroupHandler{
+			logger: zap.NewNop(),
+			config: config,
+			handler: func(_ context.Context, message *broker.Message) error {
+				calls++
+				require.Equal(t, "test_id", message.Key)
+				require.Equal(t, "test_id/1", message.Headers[broker.ReminderIDHeader])
+				if calls < 3 {
+					return errConsume
+				}
+
+				return nil
+			},
+		}
+
+		session := &testSession{ctx: context.Background()}
+		err := handler.ConsumeClaim(session, newTestClaim(testMessage))
+		require.NoError(t, err)
+		require.Equal(t, 3, calls)
+		require.Equal(t, []int64{1}, session.marked)
+	})
+
+	t.Run("retry backoff capped", func(t *testing.T) {
+		handler := consumerGroupHandler{
+			config: configs.KafkaConfig{MaxRetries: 100, RetryBackoff: time.Second, MaxRetryBackoff: 5 * time.Second},
+		}
+		delays := make([]time.Duration, 0)
+		for _, retry := range []int{0, 1, 2, 3, 99} {
+			delays = append(delays, handler.retryDelay(retry))
+		}
+		require.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second,
+			5 * time.Second}, delays)
+
+		handler.config = configs.KafkaConfig{}
+		require.Equal(t, DefaultRetryBackoff, handler.retryDelay(0))
+		require.Equal(t, DefaultMaxRetryBackoff, handler.retryDelay(1000))
+	})
+
+	t.Run("message dead lettered", func(t *testing.T) {
+		producer := mocks.NewSyncProducer(t, nil)
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+			require.Equal(t, "dead-letter", msg.Topic)
+			value, _ := msg.Value.Encode()
+			require.Equal(t, testMessage.Value, value)
+
+			headers := make(map[string]string)
+			for _, header := range msg.Headers {
+				headers[string(header.Key)] = string(header.Value)
+			}
+			require.Equal(t, map[string]string{
+				broker.ReminderIDHeader:       "test_id/1",
+				broker.DeadLetterReasonHeader: errConsume.Error(),
+				broker.DeadLetterTopicHeader:  "events",
+			}, headers)
+			return nil
+		})
+		defer producer.Close()
+
+		calls := 0
+		handler := consumerGroupHandler{
+			logger:     zap.NewNop(),
+			config:     config,
+			deadLetter: producer,
+			handler: func(_ context.Context, _ *broker.Message) error {
+				calls++
+				return errConsume
+			},
+		}
+
+		session := &testSession{ctx: context.Background()}
+		err := handler.ConsumeClaim(session, newTestClaim(testMessage))
+		require.NoError(t, err)
+		require.Equal(t, 3, calls)
+		require.Equal(t, []int64{1}, session.marked)
+	})
+
+	t.Run("message not marked on shutdown", func(t *testing.T) {
+		producer := mocks.NewSyncProducer(t, nil)
+		defer producer.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		handler := consumerGroupHandler{
+			logger:     zap.NewNop(),
+			config:     config,
+			deadLetter: producer,
+			handler: func(ctx context.Context, _ *broker.Message) error {
+				cancel()
+				return ctx.Err()
+			},
+		}
+
+		session := &testSession{ctx: ctx}
+		err := handler.ConsumeClaim(session, newTestClaim(testMessage, &sarama.ConsumerMessage{Offset: 2}))
+		require.NoError(t, err)
+		require.Empty(t, session.marked)
+	})
+
+	t.Run("message not marked if dead letter failed", func(t *testing.T) {
+		producer := mocks.NewSyncProducer(t, nil)
+		producer.ExpectSendMessageAndFail(sarama.ErrOutOfBrokers)
+		defer producer.Close()
+
+		handler := consumerGroupHandler{
+			logger:     zap.NewNop(),
+			config:     configs.KafkaConfig{DeadLetterTopic: "dead-letter"},
+			deadLetter: producer,
+			handler: func(_ context.Context, _ *broker.Message) error {
+				return errConsume
+			},
+		}
+
+		session := &testSession{ctx: context.Background()}
+		err := handler.ConsumeClaim(session, newTestClaim(testMessage, &sarama.ConsumerMessage{Offset: 2}))
+		require.ErrorIs(t, err, sarama.ErrOutOfBrokers)
+		require.Empty(t, session.marked)
+	})
+
+	t.Run("ready while holding a session", func(t *testing.T) {
+		consumer := &Consumer{session: &atomic.Bool{}}
+		handler := consumerGroupHandler{session: consumer.session}
+		require.ErrorIs(t, consumer.Ping(context.Background()), ErrNoSession)
+
+		session := &testSession{ctx: context.Background()}
+		require.NoError(t, handler.Setup(session))
+		require.NoError(t, consumer.Ping(context.Background()))
+
+		require.NoError(t, handler.Cleanup(session))
+		require.ErrorIs(t, consumer.Ping(context.Background()), ErrNoSession)
+	})
+}
+
+func TestDeadLetter(t *testing.T) {
+	deadLetters := []DeadLetter{
+		{
+			Partition: 0,
+			Offset:    3,
+			Key:       []byte("test_id"),
+			Payload:   []byte(`{"id":"test_id"}`),
+			Headers: []*sarama.RecordHeader{
+				{Key: []byte(broker.ReminderIDHeader), Value: []byte("test_id/1")},
+				{Key: []byte(broker.DeadLetterReasonHeader), Value: []byte(errConsume.Error())},
+				{Key: []byte(broker.DeadLetterTopicHeader), Value: []byte("events")},
+			},
+		},
+		{Partition: 1, Offset: 0, Key: []byte("other_id")},
+	}
+
+	t.Run("choose dead letters", func(t *testing.T) {
+		chosen, err := chooseDeadLetters(deadLetters, []string{"all"})
+		require.NoError(t, err)
+		require.Len(t, chosen, 2)
+
+		chosen, err = chooseDeadLetters(deadLetters, []string{"1/0"})
+		require.NoError(t, err)
+		require.Len(t, chosen, 1)
+		require.Equal(t, "1/0", chosen[0].ID())
+
+		_, err = chooseDeadLetters(deadLetters, []string{"0/4"})
+		require.ErrorIs(t, err, ErrDeadLetterNotFound)
+
+		_, err = chooseDeadLetters(deadLetters, []string{"first"})
+		require.ErrorIs(t, err, ErrInvalidDeadLetterCommand)
+	})
+
+	t.Run("replay message", func(t *testing.T) {
+		require.Equal(t, errConsume.Error(), deadLetters[0].Header(broker.DeadLetterReasonHeader))
+
+		msg := replayMessage(&deadLetters[0], "events")
+		require.Equal(t, "events", msg.Topic)
+		require.Equal(t, []sarama.RecordHeader{{Key: []byte(broker.ReminderIDHeader), Value: []byte("test_id/1")}},
+			msg.Headers)
+
+		value, _ := msg.Value.Encode()
+		require.Equal(t, deadLetters[0].Payload, value)
+	})
+
+	t.Run("invalid command", func(t *testing.T) {
+		ctx := context.Background()
+		for _, args := range [][]string{nil, {"list", "all"}, {"replay"}, {"purge"}} {
+			err := DeadLetterCommand(ctx, configs.KafkaConfig{DeadLetterTopic: "dead-letter"}, args, nil)
+			require.ErrorIs(t, err, ErrInvalidDeadLetterCommand)
+		}
+
+		err := DeadLetterCommand(ctx, configs.KafkaConfig{}, []string{"list"}, nil)
+		require.ErrorIs(t, err, ErrDeadLetterTopicRequired)
+	})
+}
@@ -0,0 +1,212 @@
+package kafka
+
+//nolint:depguard
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker"
+	"github.com/IBM/sarama"
+)
+
+var (
+	ErrInvalidDeadLetterCommand = errors.New(
+		"invalid dead-letter command, expected: dead-letter list | dead-letter replay all|<partition>/<offset>...")
+	ErrDeadLetterTopicRequired = errors.New("dead letter topic is not configured")
+	ErrDeadLetterNotFound      = errors.New("dead letter not found")
+)
+
+// DeadLetter is a message of the dead letter topic, it is identified by its partition and offset.
+type DeadLetter struct {
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Payload   []byte
+	Headers   []*sarama.RecordHeader
+	Timestamp time.Time
+}
+
+func (d *DeadLetter) ID() string {
+	return fmt.Sprintf("%d/%d", d.Partition, d.Offset)
+}
+
+func (d *DeadLetter) Header(key string) string {
+	for _, header := range d.Headers {
+		if string(header.Key) == key {
+			return string(header.Value)
+		}
+	}
+
+	return ""
+}
+
+// DeadLetterCommand runs the dead-letter subcommand and writes its report to w:
+// "list" prints the dead letters, "replay" sends the chosen ones (all of them with "all") back to the consume topic.
+// The dead letter topic is append only, a replayed message stays there.
+func DeadLetterCommand(ctx context.Context, config configs.KafkaConfig, args []string, w io.Writer) error {
+	if len(args) == 0 || (args[0] == "list" && len(args) > 1) || (args[0] == "replay" && len(args) < 2) ||
+		(args[0] != "list" && args[0] != "replay") {
+		return ErrInvalidDeadLetterCommand
+	}
+
+	if config.DeadLetterTopic == "" {
+		return ErrDeadLetterTopicRequired
+	}
+
+	client, err := sarama.NewClient([]string{config.URL}, getProducerConfig(config))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	deadLetters, err := readDeadLetters(ctx, client, config.DeadLetterTopic)
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "list" {
+		for _, deadLetter := range deadLetters {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", deadLetter.ID(), deadLetter.Timestamp.UTC().Format(time.RFC3339),
+				deadLetter.Key, deadLetter.Header(broker.DeadLetterReasonHeader), deadLetter.Payload)
+		}
+
+		return nil
+	}
+
+	chosen, err := chooseDeadLetters(deadLetters, args[1:])
+	if err != nil {
+		return err
+	}
+
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		return err
+	}
+	defer producer.Close()
+
+	for _, deadLetter := range chosen {
+		if _, _, err := producer.SendMessage(replayMessage(&deadLetter, config.ConsumeTopic)); err != nil {
+			return fmt.Errorf("dead letter %s replay failed: %w", deadLetter.ID(), err)
+		}
+
+		fmt.Fprintf(w, "%s\treplayed to %s\n", deadLetter.ID(), config.ConsumeTopic)
+	}
+
+	return nil
+}
+
+// readDeadLetters reads every partition of the topic from the oldest message to the current high watermark.
+func readDeadLetters(ctx context.Context, client sarama.Client, topic string) ([]DeadLetter, error) {
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return nil, err
+	}
+	defer consumer.Close()
+
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetters := make([]DeadLetter, 0)
+	for _, partition := range partitions {
+		oldest, err := client.GetOffset(topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return nil, err
+		}
+
+		newest, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return nil, err
+		}
+
+		if oldest >= newest {
+			continue
+		}
+
+		partitionConsumer, err := consumer.ConsumePartition(topic, partition, oldest)
+		if err != nil {
+			return nil, err
+		}
+
+		for next := oldest; next < newest; {
+			select {
+			case <-ctx.Done():
+				partitionConsumer.Close()
+				return nil, ctx.Err()
+			case msg := <-partitionConsumer.Messages():
+				next = msg.Offset + 1
+				deadLetters = append(deadLetters, DeadLetter{
+					Partition: msg.Partition,
+					Offset:    msg.Offset,
+					Key:       msg.Key,
+					Payload:   msg.Value,
+					Headers:   msg.Headers,
+					Timestamp: msg.Timestamp,
+				})
+			}
+		}
+
+		if err := partitionConsumer.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	return deadLetters, nil
+}
+
+func chooseDeadLetters(deadLetters []DeadLetter, ids []string) ([]DeadLetter, error) {
+	if len(ids) == 1 && ids[0] == "all" {
+		return deadLetters, nil
+	}
+
+	byID := make(map[string]DeadLetter, len(deadLetters))
+	for _, deadLetter := range deadLetters {
+		byID[deadLetter.ID()] = deadLetter
+	}
+
+	chosen := make([]DeadLetter, 0, len(ids))
+	for _, id := range ids {
+		partition, offset, ok := strings.Cut(id, "/")
+		if _, err := strconv.ParseInt(partition, 10, 32); !ok || err != nil {
+			return nil, ErrInvalidDeadLetterCommand
+		}
+
+		if _, err := strconv.ParseInt(offset, 10, 64); err != nil {
+			return nil, ErrInvalidDeadLetterCommand
+		}
+
+		deadLetter, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrDeadLetterNotFound, id)
+		}
+
+		chosen = append(chosen, deadLetter)
+	}
+
+	return chosen, nil
+}
+
+// replayMessage restores the original message, the dead letter headers are dropped.
+func replayMessage(deadLetter *DeadLetter, topic string) *sarama.ProducerMessage {
+	headers := make([]sarama.RecordHeader, 0, len(deadLetter.Headers))
+	for _, header := range deadLetter.Headers {
+		key := string(header.Key)
+		if key != broker.DeadLetterReasonHeader && key != broker.DeadLetterTopicHeader {
+			headers = append(headers, *header)
+		}
+	}
+
+	return &sarama.ProducerMessage{
+		Topic:   topic,
+		Key:     sarama.ByteEncoder(deadLetter.Key),
+		Value:   sarama.ByteEncoder(deadLetter.Payload),
+		Headers: headers,
+	}
+}
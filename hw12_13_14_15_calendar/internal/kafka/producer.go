@@ -2,45 +2,122 @@ package kafka
 
 //nolint:depguard
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"sync"
+	"time"
 
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
-	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/metrics"
 	"github.com/IBM/sarama"
 	"go.uber.org/zap"
 )
 
+// PingInterval is how long the result of a metadata refresh answers the pings.
+const PingInterval = 5 * time.Second
+
 type Producer struct {
+	client   sarama.Client
 	producer sarama.SyncProducer
 	logger   *zap.Logger
 	config   configs.KafkaConfig
+	ping     *metadataPing
+}
+
+// metadataPing shares one metadata refresh between the concurrent pings and keeps its result for PingInterval.
+type metadataPing struct {
+	mu        sync.Mutex
+	err       error
+	checkedAt time.Time
+	inFlight  chan struct{}
 }
 
 func NewProducer(config configs.KafkaConfig, logger *zap.Logger) (*Producer, error) {
-	producer, err := sarama.NewSyncProducer([]string{config.URL}, getProducerConfig(config))
+	client, err := sarama.NewClient([]string{config.URL}, getProducerConfig(config))
+	if err != nil {
+		return nil, err
+	}
+
+	producer, err := sarama.NewSyncProducerFromClient(client)
 	if err != nil {
+		client.Close()
 		return nil, err
 	}
 
-	return &Producer{producer: producer, config: config, logger: logger}, nil
+	return &Producer{client: client, producer: producer, config: config, logger: logger, ping: &metadataPing{}}, nil
 }
 
-func (producer Producer) SendEventMessage(event *storage.Event) error {
-	producer.logger.Info("send event to kafka", zap.Any("event", &event))
+// Publish sends the message to the produce topic keyed by the message key.
+func (producer Producer) Publish(_ context.Context, message *broker.Message) error {
+	producer.logger.Info("send message to kafka", zap.String("key", message.Key))
+
+	headers := make([]sarama.RecordHeader, 0, len(message.Headers))
+	for key, value := range message.Headers {
+		headers = append(headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+	}
 
-	jsonData, _ := json.Marshal(event)
 	msg := &sarama.ProducerMessage{
-		Key:   sarama.StringEncoder(event.ID),
-		Topic: producer.config.ProduceTopic,
-		Value: sarama.ByteEncoder(jsonData),
+		Key:     sarama.StringEncoder(message.Key),
+		Topic:   producer.config.ProduceTopic,
+		Value:   sarama.ByteEncoder(message.Payload),
+		Headers: headers,
 	}
 
 	_, _, err := producer.producer.SendMessage(msg)
-	if err != nil {
-		return err
+	metrics.KafkaMessagesProduced.WithLabelValues(producer.config.ProduceTopic, metrics.Result(err)).Inc()
+	return err
+}
+
+// Ping refreshes the produce topic metadata, so it fails if the cluster or the topic leader can't be reached.
+// The pings wait for the refresh in flight instead of starting another one and reuse its result for PingInterval.
+func (producer Producer) Ping(ctx context.Context) error {
+	if producer.client.Closed() {
+		return sarama.ErrClosedClient
 	}
 
-	return nil
+	ping := producer.ping
+	ping.mu.Lock()
+	if ping.inFlight == nil && time.Since(ping.checkedAt) < PingInterval {
+		defer ping.mu.Unlock()
+		return ping.err
+	}
+
+	if ping.inFlight == nil {
+		ping.inFlight = make(chan struct{})
+		go producer.refreshMetadata()
+	}
+	inFlight := ping.inFlight
+	ping.mu.Unlock()
+
+	select {
+	case <-inFlight:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	ping.mu.Lock()
+	defer ping.mu.Unlock()
+
+	return ping.err
+}
+
+func (producer Producer) refreshMetadata() {
+	err := producer.client.RefreshMetadata(producer.config.ProduceTopic)
+
+	ping := producer.ping
+	ping.mu.Lock()
+	defer ping.mu.Unlock()
+
+	ping.err = err
+	ping.checkedAt = time.Now()
+	close(ping.inFlight)
+	ping.inFlight = nil
+}
+
+// Close closes the producer and then its client, the producer doesn't own the client.
+func (producer Producer) Close() error {
+	return errors.Join(producer.producer.Close(), producer.client.Close())
 }
 
 func getProducerConfig(kafkaConfig configs.KafkaConfig) *sarama.Config {
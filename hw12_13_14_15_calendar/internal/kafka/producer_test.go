@@ -0,0 +1,48 @@
+package kafka
+
+//nolint:depguard
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/require"
+)
+
+var errNoLeader = errors.New("topic leader not available")
+
+// refreshClient counts the metadata refreshes, a refresh returns once it is released.
+type refreshClient struct {
+	sarama.Client
+	refreshes atomic.Int32
+	release   chan struct{}
+}
+
+func (c *refreshClient) Closed() bool { return false }
+
+func (c *refreshClient) RefreshMetadata(...string) error {
+	c.refreshes.Add(1)
+	<-c.release
+	return errNoLeader
+}
+
+func TestProducerPing(t *testing.T) {
+	client := &refreshClient{release: make(chan struct{})}
+	producer := Producer{client: client, config: configs.KafkaConfig{ProduceTopic: "events"}, ping: &metadataPing{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, producer.Ping(ctx), context.DeadlineExceeded)
+
+	result := make(chan error, 1)
+	go func() { result <- producer.Ping(context.Background()) }()
+	close(client.release)
+	require.ErrorIs(t, <-result, errNoLeader)
+
+	require.ErrorIs(t, producer.Ping(context.Background()), errNoLeader)
+	require.Equal(t, int32(1), client.refreshes.Load())
+}
@@ -0,0 +1,138 @@
+// Package metrics defines the Prometheus metrics of the calendar services.
+// The calendar serves them on the /metrics path of its HTTP API, the scheduler and the storer
+// serve them together with their health probes on their own metrics listener.
+package metrics
+
+//nolint:depguard
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/health"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const namespace = "calendar"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP API requests by method, route and status code.",
+	}, []string{"method", "route", "code"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP API request latency by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	StorageOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "operation_duration_seconds",
+		Help:      "Storage operation latency by operation, in both the SQL and the in-memory backend.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	SchedulerJobRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "job_runs_total",
+		Help:      "Scheduler job runs by job and result.",
+	}, []string{"job", "result"})
+
+	RemindersSent = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "reminders_sent_total",
+		Help:      "Reminders published from the outbox to the broker.",
+	})
+
+	KafkaMessagesProduced = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "messages_produced_total",
+		Help:      "Messages produced to Kafka by topic and result.",
+	}, []string{"topic", "result"})
+
+	KafkaMessagesConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "messages_consumed_total",
+		Help:      "Messages consumed from Kafka by topic.",
+	}, []string{"topic"})
+
+	KafkaConsumerErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "consumer_errors_total",
+		Help:      "Consumed Kafka messages the handler failed after all the retries, by topic.",
+	}, []string{"topic"})
+)
+
+// Result labels the outcome of an operation.
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	return "ok"
+}
+
+// ObserveStorage records the latency of the storage operation started at the given time,
+// it is deferred by the operation: defer metrics.ObserveStorage("CreateEvent", time.Now()).
+func ObserveStorage(operation string, start time.Time) {
+	StorageOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Serve serves the metrics and the health probes running the checks on the configured address
+// until the context is done, it returns at once if the listener isn't configured.
+func Serve(ctx context.Context, config configs.MetricsConfig, logger *zap.Logger, checks ...health.Check) error {
+	if config.Port == 0 {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", Handler())
+	health.Register(mux, checks...)
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", config.Host, config.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error("failed to stop metrics server", zap.Error(err))
+		}
+	}()
+
+	logger.Info("metrics server is running on address: " + server.Addr)
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package outbox
+
+//nolint:depguard
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	"go.uber.org/zap"
+)
+
+// DefaultBatchSize is the number of pending messages published by one relay run.
+const DefaultBatchSize = 100
+
+type Storage interface {
+	GetEventsDueForReminder(ctx context.Context, before time.Time, limit int) ([]storage.Event, error)
+	UpdateEventWithOutbox(ctx context.Context, event *storage.Event, messages []storage.OutboxMessage) error
+	GetPendingOutboxMessages(ctx context.Context, limit int) ([]storage.OutboxMessage, error)
+	MarkOutboxMessageSent(ctx context.Context, id int64, sentAt time.Time) error
+}
+
+// EnqueueReminders stores a reminder message for every due occurrence of the events
+// in the same transaction as the event state change. An event failing to enqueue doesn't stop the others,
+// the failures are returned together.
+func EnqueueReminders(ctx context.Context, repository Storage, logger *zap.Logger, timeNow time.Time) error {
+	events, err := repository.GetEventsDueForReminder(ctx, timeNow, 0)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, 0)
+	for _, event := range events {
+		if err := enqueueEvent(ctx, repository, event, timeNow); err != nil {
+			logger.Error("enqueue reminders failed", zap.String("event", event.ID), zap.Error(err))
+			errs = append(errs, fmt.Errorf("event %s: %w", event.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// enqueueEvent enqueues the due reminders of the event and stores their sent state.
+func enqueueEvent(ctx context.Context, repository Storage, event storage.Event, timeNow time.Time) error {
+	reminders := make([]storage.EventReminder, len(event.Reminders))
+	copy(reminders, event.Reminders)
+	event.Reminders = reminders
+
+	messages := make([]storage.OutboxMessage, 0)
+	for i := range event.Reminders {
+		dueMessages, err := enqueueReminder(event, &event.Reminders[i], timeNow)
+		if err != nil {
+			return err
+		}
+
+		messages = append(messages, dueMessages...)
+	}
+
+	return repository.UpdateEventWithOutbox(ctx, &event, messages)
+}
+
+// enqueueReminder creates the messages of every occurrence the reminder is due for and updates its sent state.
+func enqueueReminder(event storage.Event,
+	reminder *storage.EventReminder,
+	timeNow time.Time,
+) ([]storage.OutboxMessage, error) {
+	if reminder.IsSend {
+		return nil, nil
+	}
+
+	if reminder.At != nil {
+		if !reminder.At.Before(timeNow) {
+			return nil, nil
+		}
+
+		occurrence, err := event.ReminderOccurrence(*reminder.At)
+		if err != nil {
+			return nil, err
+		}
+
+		reminder.IsSend = true
+		if occurrence.IsZero() {
+			return nil, nil
+		}
+
+		return reminderMessages(event, reminder, occurrence)
+	}
+
+	remindBefore := time.Minute * time.Duration(reminder.Before)
+	if !event.IsRecurring() {
+		if !event.StartDate.Add(-remindBefore).Before(timeNow) {
+			return nil, nil
+		}
+
+		reminder.IsSend = true
+		return reminderMessages(event, reminder, event.StartDate)
+	}
+
+	from := event.StartDate.Truncate(time.Second)
+	if !reminder.SentUntil.IsZero() {
+		from = reminder.SentUntil.Add(time.Second)
+	}
+
+	occurrences, err := event.Occurrences(from, timeNow.Add(remindBefore))
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]storage.OutboxMessage, 0, len(occurrences))
+	for _, occurrence := range occurrences {
+		occurrenceMessages, err := reminderMessages(event, reminder, occurrence)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, occurrenceMessages...)
+	}
+
+	if len(occurrences) > 0 {
+		reminder.SentUntil = occurrences[len(occurrences)-1]
+	}
+
+	nextOccurrence, err := event.NextOccurrence(reminder.SentUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	reminder.IsSend = nextOccurrence.IsZero()
+	return messages, nil
+}
+
+// reminderMessages creates the reminder messages of the event occurrence for the owner and the accepted attendees.
+func reminderMessages(event storage.Event,
+	reminder *storage.EventReminder,
+	occurrence time.Time,
+) ([]storage.OutboxMessage, error) {
+	recipients := event.Recipients()
+	messages := make([]storage.OutboxMessage, 0, len(recipients))
+	for _, recipient := range recipients {
+		message, err := storage.NewReminderMessage(event, reminder, occurrence, recipient)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,293 @@
+package outbox
+
+//nolint:depguard
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker"
+	memorybroker "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker/memory"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var (
+	errPublish = errors.New("broker is unavailable")
+	errUpdate  = errors.New("event update failed")
+)
+
+// failingStorage fails to store the outbox messages of the failID event.
+type failingStorage struct {
+	*memorystorage.Storage
+	failID string
+}
+
+func (s *failingStorage) UpdateEventWithOutbox(ctx context.Context,
+	event *storage.Event,
+	messages []storage.OutboxMessage,
+) error {
+	if event.ID == s.failID {
+		return errUpdate
+	}
+
+	return s.Storage.UpdateEventWithOutbox(ctx, event, messages)
+}
+
+// testPublisher publishes to the memory broker and fails the failAt message once.
+type testPublisher struct {
+	*memorybroker.Broker
+	published int
+	failAt    int
+}
+
+func (p *testPublisher) Publish(ctx context.Context, message *broker.Message) error {
+	if p.failAt > 0 && p.published+1 == p.failAt {
+		p.failAt = 0
+		return errPublish
+	}
+
+	p.published++
+	return p.Broker.Publish(ctx, message)
+}
+
+func TestOutbox(t *testing.T) {
+	logger := zap.NewNop()
+	timeNow := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	t.Run("one-off reminder enqueued once", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := memorystorage.New()
+		err := memory.CreateEvent(ctx, &storage.Event{
+			ID:        "due",
+			Title:     "test_title",
+			Owner:     "test_user",
+			StartDate: timeNow.Add(-time.Hour),
+			Duration:  30,
+			Reminders: []storage.EventReminder{{}},
+		})
+		require.NoError(t, err)
+
+		err = memory.CreateEvent(ctx, &storage.Event{
+			ID:        "future",
+			Title:     "test_title",
+			Owner:     "test_user",
+			StartDate: timeNow.Add(time.Hour),
+			Duration:  30,
+			Reminders: []storage.EventReminder{{}},
+		})
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			err = EnqueueReminders(ctx, memory, logger, timeNow)
+			require.NoError(t, err)
+		}
+
+		messages, err := memory.GetPendingOutboxMessages(ctx, 0)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.Equal(t, "due", messages[0].EventID)
+
+		events, err := memory.GetEvents(ctx)
+		require.NoError(t, err)
+		for _, event := range events {
+			require.Equal(t, event.ID == "due", event.Reminders[0].IsSend, event.ID)
+		}
+	})
+
+	t.Run("reminders enqueued for accepted attendees", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := memorystorage.New()
+		err := memory.CreateEvent(ctx, &storage.Event{
+			ID:        "meeting",
+			Title:     "test_title",
+			Owner:     "test_user",
+			StartDate: timeNow.Add(-time.Hour),
+			Duration:  30,
+			Reminders: []storage.EventReminder{{}},
+			Attendees: []storage.Attendee{
+				{User: "accepted_user", Status: storage.RSVPAccepted},
+				{User: "tentative_user", Status: storage.RSVPTentative},
+				{User: "declined_user", Status: storage.RSVPDeclined},
+			},
+		})
+		require.NoError(t, err)
+
+		err = EnqueueReminders(ctx, memory, logger, timeNow)
+		require.NoError(t, err)
+
+		messages, err := memory.GetPendingOutboxMessages(ctx, 0)
+		require.NoError(t, err)
+		require.Len(t, messages, 2)
+		require.Equal(t, "test_user", messages[0].Recipient)
+		require.Equal(t, "accepted_user", messages[1].Recipient)
+		require.Contains(t, string(messages[1].Payload), `"recipient":"accepted_user"`)
+	})
+
+	t.Run("recurring reminders enqueued once per occurrence", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := memorystorage.New()
+		err := memory.CreateEvent(ctx, &storage.Event{
+			ID:        "daily",
+			Title:     "test_title",
+			Owner:     "test_user",
+			StartDate: timeNow.AddDate(0, 0, -2).Add(-time.Hour),
+			Duration:  30,
+			RRule:     "FREQ=DAILY",
+			Reminders: []storage.EventReminder{{}},
+		})
+		require.NoError(t, err)
+
+		err = EnqueueReminders(ctx, memory, logger, timeNow)
+		require.NoError(t, err)
+
+		err = EnqueueReminders(ctx, memory, logger, timeNow.Add(time.Minute))
+		require.NoError(t, err)
+
+		messages, err := memory.GetPendingOutboxMessages(ctx, 0)
+		require.NoError(t, err)
+		require.Len(t, messages, 3)
+		require.Equal(t, timeNow.Add(-time.Hour), messages[2].Occurrence)
+
+		events, err := memory.GetEvents(ctx)
+		require.NoError(t, err)
+		require.False(t, events[0].Reminders[0].IsSend)
+		require.Equal(t, timeNow.Add(-time.Hour), events[0].Reminders[0].SentUntil)
+
+		err = EnqueueReminders(ctx, memory, logger, timeNow.AddDate(0, 0, 1))
+		require.NoError(t, err)
+
+		messages, err = memory.GetPendingOutboxMessages(ctx, 0)
+		require.NoError(t, err)
+		require.Len(t, messages, 4)
+	})
+
+	t.Run("relative and absolute reminders enqueued separately", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		remindAt := timeNow.Add(-10 * time.Minute)
+		memory := memorystorage.New()
+		err := memory.CreateEvent(ctx, &storage.Event{
+			ID:        "meeting",
+			Title:     "test_title",
+			Owner:     "test_user",
+			StartDate: timeNow.Add(30 * time.Minute),
+			Duration:  30,
+			Reminders: []storage.EventReminder{{Before: 60}, {Before: 15}, {At: &remindAt}},
+		})
+		require.NoError(t, err)
+
+		err = EnqueueReminders(ctx, memory, logger, timeNow)
+		require.NoError(t, err)
+
+		messages, err := memory.GetPendingOutboxMessages(ctx, 0)
+		require.NoError(t, err)
+		require.Len(t, messages, 2)
+		require.Equal(t, "before:60", messages[0].Reminder)
+		require.Equal(t, "at:"+remindAt.Format(time.RFC3339), messages[1].Reminder)
+		require.Equal(t, timeNow.Add(30*time.Minute), messages[1].Occurrence)
+
+		events, err := memory.GetEvents(ctx)
+		require.NoError(t, err)
+		require.True(t, events[0].Reminders[0].IsSend)
+		require.False(t, events[0].Reminders[1].IsSend)
+		require.True(t, events[0].Reminders[2].IsSend)
+
+		err = EnqueueReminders(ctx, memory, logger, timeNow.Add(20*time.Minute))
+		require.NoError(t, err)
+
+		messages, err = memory.GetPendingOutboxMessages(ctx, 0)
+		require.NoError(t, err)
+		require.Len(t, messages, 3)
+		require.Equal(t, "before:15", messages[2].Reminder)
+	})
+
+	t.Run("relay publishes every message once", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := memorystorage.New()
+		err := memory.CreateEvent(ctx, &storage.Event{
+			ID:        "daily",
+			Title:     "test_title",
+			Owner:     "test_user",
+			StartDate: timeNow.AddDate(0, 0, -4).Add(-time.Hour),
+			Duration:  30,
+			RRule:     "FREQ=DAILY",
+			Reminders: []storage.EventReminder{{}},
+		})
+		require.NoError(t, err)
+
+		err = EnqueueReminders(ctx, memory, logger, timeNow)
+		require.NoError(t, err)
+
+		publisher := &testPublisher{Broker: memorybroker.New(10), failAt: 3}
+		relay := NewRelay(memory, publisher, logger)
+		relay.batchSize = 2
+
+		published, err := relay.Run(ctx)
+		require.ErrorIs(t, err, errPublish)
+		require.Equal(t, 2, published)
+
+		published, err = relay.Run(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 3, published)
+
+		published, err = relay.Run(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0, published)
+
+		require.NoError(t, publisher.Close())
+		occurrences := make([]string, 0)
+		err = publisher.Subscribe(ctx, func(_ context.Context, message *broker.Message) error {
+			require.Equal(t, "daily", message.Key)
+			occurrences = append(occurrences, message.Headers[broker.ReminderIDHeader])
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, occurrences, 5)
+		require.Equal(t, "daily/"+timeNow.AddDate(0, 0, -4).Add(-time.Hour).Format(time.RFC3339)+"/test_user/before:0",
+			occurrences[0])
+		require.Equal(t, "daily/"+timeNow.Add(-time.Hour).Format(time.RFC3339)+"/test_user/before:0", occurrences[4])
+
+		messages, err := memory.GetPendingOutboxMessages(ctx, 0)
+		require.NoError(t, err)
+		require.Empty(t, messages)
+	})
+
+	t.Run("failed events reported", func(t *testing.T) {
+		ctx := context.Background()
+
+		memory := memorystorage.New()
+		for _, id := range []string{"broken", "healthy"} {
+			err := memory.CreateEvent(ctx, &storage.Event{
+				ID:        id,
+				Title:     "test_title",
+				Owner:     "test_user",
+				StartDate: timeNow.Add(time.Minute),
+				Duration:  30,
+				Reminders: []storage.EventReminder{{Before: 10}},
+			})
+			require.NoError(t, err)
+		}
+
+		err := EnqueueReminders(ctx, &failingStorage{Storage: memory, failID: "broken"}, logger, timeNow)
+		require.ErrorIs(t, err, errUpdate)
+		require.ErrorContains(t, err, "event broken")
+
+		messages, err := memory.GetPendingOutboxMessages(ctx, 0)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.Equal(t, "healthy", messages[0].EventID)
+	})
+}
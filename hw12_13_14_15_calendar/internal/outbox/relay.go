@@ -0,0 +1,62 @@
+package outbox
+
+//nolint:depguard
+import (
+	"context"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Relay publishes the pending outbox messages in the order they were stored.
+// A message is marked as sent only after the publisher acknowledged it, so a crash between
+// publishing and marking republishes it, the reminder ID of the message lets consumers drop such duplicates.
+type Relay struct {
+	repository Storage
+	publisher  broker.Publisher
+	logger     *zap.Logger
+	batchSize  int
+}
+
+func NewRelay(repository Storage, publisher broker.Publisher, logger *zap.Logger) *Relay {
+	return &Relay{repository: repository, publisher: publisher, logger: logger, batchSize: DefaultBatchSize}
+}
+
+// Run publishes pending messages until none are left and returns the number of published ones.
+// It stops at the first failed message to keep the reminders order.
+func (r *Relay) Run(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		messages, err := r.repository.GetPendingOutboxMessages(ctx, r.batchSize)
+		if err != nil {
+			return published, err
+		}
+
+		for _, message := range messages {
+			if err := r.publisher.Publish(ctx, toBrokerMessage(&message)); err != nil {
+				return published, err
+			}
+
+			if err := r.repository.MarkOutboxMessageSent(ctx, message.ID, time.Now()); err != nil {
+				return published, err
+			}
+
+			published++
+		}
+
+		if len(messages) < r.batchSize || ctx.Err() != nil {
+			return published, ctx.Err()
+		}
+	}
+}
+
+// toBrokerMessage keys the message by its event to keep the event reminders in order.
+func toBrokerMessage(message *storage.OutboxMessage) *broker.Message {
+	return &broker.Message{
+		Key:     message.EventID,
+		Payload: message.Payload,
+		Headers: map[string]string{broker.ReminderIDHeader: message.ReminderID()},
+	}
+}
@@ -0,0 +1,246 @@
+package sender
+
+//nolint:depguard
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	jsoniter "github.com/json-iterator/go"
+	"go.uber.org/zap"
+)
+
+const (
+	ChannelLog     = "log"
+	ChannelSMTP    = "smtp"
+	ChannelWebhook = "webhook"
+)
+
+// DefaultSMTPTimeout bounds the delivery of a mail unless configured.
+const DefaultSMTPTimeout = 10 * time.Second
+
+var (
+	ErrUnknownChannel = errors.New("unknown notification channel")
+	ErrNoRecipient    = errors.New("notification user has no email")
+	ErrInvalidHeader  = errors.New("mail header contains a line break")
+)
+
+// Channel delivers notifications to users.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, notification *Notification) error
+}
+
+// NewChannels creates the configured channels, the log channel is used if none is configured.
+func NewChannels(config configs.SenderConfig, logger *zap.Logger) ([]Channel, error) {
+	names := config.Channels
+	if len(names) == 0 {
+		names = []string{ChannelLog}
+	}
+
+	channels := make([]Channel, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case ChannelLog:
+			channels = append(channels, NewLogChannel(logger))
+		case ChannelSMTP:
+			channels = append(channels, NewSMTPChannel(config.SMTP))
+		case ChannelWebhook:
+			channels = append(channels, NewWebhookChannel(config.Webhook))
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnknownChannel, name)
+		}
+	}
+
+	return channels, nil
+}
+
+// LogChannel writes notifications to the service log, the log is written to STDOUT in containers.
+type LogChannel struct {
+	logger *zap.Logger
+}
+
+func NewLogChannel(logger *zap.Logger) *LogChannel {
+	return &LogChannel{logger: logger}
+}
+
+func (c *LogChannel) Name() string { return ChannelLog }
+
+func (c *LogChannel) Send(_ context.Context, notification *Notification) error {
+	c.logger.Info("notification", zap.String("user", notification.User), zap.String("event", notification.EventID),
+		zap.String("text", notification.String()))
+	return nil
+}
+
+// SMTPChannel emails notifications, a user without an email gets the configured domain appended.
+type SMTPChannel struct {
+	config configs.SMTPConfig
+}
+
+func NewSMTPChannel(config configs.SMTPConfig) *SMTPChannel {
+	return &SMTPChannel{config: config}
+}
+
+func (c *SMTPChannel) Name() string { return ChannelSMTP }
+
+// Send delivers the mail within the configured timeout, the connection is closed once the context is done,
+// so a silent server doesn't block the consumer.
+func (c *SMTPChannel) Send(ctx context.Context, notification *Notification) error {
+	to := notification.User
+	if !strings.Contains(to, "@") {
+		if c.config.Domain == "" {
+			return fmt.Errorf("%w: %s", ErrNoRecipient, to)
+		}
+
+		to += "@" + c.config.Domain
+	}
+
+	var auth smtp.Auth
+	if c.config.Username != "" {
+		auth = smtp.PlainAuth("", c.config.Username, c.config.Password, c.config.Host)
+	}
+
+	body, err := c.message(to, notification)
+	if err != nil {
+		return err
+	}
+
+	timeout := c.config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultSMTPTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(c.config.Host, strconv.Itoa(c.config.Port))
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	err = c.sendMail(conn, auth, to, body)
+	if err != nil && ctx.Err() != nil {
+		return errors.Join(ctx.Err(), err)
+	}
+
+	return err
+}
+
+// sendMail sends the mail over the connection like smtp.SendMail does, the connection is closed at the end.
+func (c *SMTPChannel) sendMail(conn net.Conn, auth smtp.Auth, to string, body []byte) error {
+	client, err := smtp.NewClient(conn, c.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: c.config.Host, MinVersion: tls.VersionTLS12})
+		if err != nil {
+			return err
+		}
+	}
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(c.config.From); err != nil {
+		return err
+	}
+
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(body); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// message builds the mail, the addresses with a line break are rejected and the line breaks are stripped
+// from the event title, so a title can't inject headers or recipients. The subject is Q-encoded.
+func (c *SMTPChannel) message(to string, notification *Notification) ([]byte, error) {
+	for _, address := range []string{c.config.From, to} {
+		if strings.ContainsAny(address, "\r\n") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidHeader, address)
+		}
+	}
+
+	title := strings.NewReplacer("\r", " ", "\n", " ").Replace(notification.Title)
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		c.config.From, to, mime.QEncoding.Encode("utf-8", title), notification.String())
+	return []byte(body), nil
+}
+
+// WebhookChannel posts notifications as JSON, any non 2xx response is a failure.
+type WebhookChannel struct {
+	config configs.WebhookConfig
+	client *http.Client
+}
+
+func NewWebhookChannel(config configs.WebhookConfig) *WebhookChannel {
+	return &WebhookChannel{config: config, client: &http.Client{Timeout: config.Timeout}}
+}
+
+func (c *WebhookChannel) Name() string { return ChannelWebhook }
+
+func (c *WebhookChannel) Send(ctx context.Context, notification *Notification) error {
+	body, err := jsoniter.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package sender
+
+//nolint:depguard
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	jsoniter "github.com/json-iterator/go"
+)
+
+var ErrInvalidReminder = errors.New("invalid reminder")
+
+// Notification is the reminder delivered to the user, it isn't stored.
+type Notification struct {
+	EventID string    `json:"eventId"`
+	Title   string    `json:"title"`
+	Date    time.Time `json:"date"`
+	User    string    `json:"user"`
+}
+
+// NewNotification renders the notification of the reminder published by the scheduler,
+// the reminders without a recipient are delivered to the event owner.
+func NewNotification(payload []byte) (Notification, error) {
+	var reminder storage.Reminder
+	if err := jsoniter.Unmarshal(payload, &reminder); err != nil {
+		return Notification{}, fmt.Errorf("%w: %w", ErrInvalidReminder, err)
+	}
+
+	if reminder.ID == "" || reminder.Owner == "" {
+		return Notification{}, fmt.Errorf("%w: event id and owner are required", ErrInvalidReminder)
+	}
+
+	user := reminder.Recipient
+	if user == "" {
+		user = reminder.Owner
+	}
+
+	return Notification{EventID: reminder.ID, Title: reminder.Title, Date: reminder.StartDate, User: user}, nil
+}
+
+func (n *Notification) String() string {
+	return fmt.Sprintf("Reminder: %q starts at %s", n.Title, n.Date.Format(time.RFC1123))
+}
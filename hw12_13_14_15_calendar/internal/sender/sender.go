@@ -0,0 +1,158 @@
+package sender
+
+//nolint:depguard
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultMaxAttempts = 3
+	DefaultRetryDelay  = time.Second
+
+	DeadLetterChannelHeader = "dead-letter-channel"
+)
+
+// Deliveries records the reminders delivered through every channel by the reminder ID of the message,
+// so a redelivered reminder isn't sent through the channels which delivered it already.
+type Deliveries interface {
+	IsReminderDelivered(ctx context.Context, reminderID, channel string) (bool, error)
+	MarkReminderDelivered(ctx context.Context, reminderID, channel string) error
+}
+
+// Sender delivers the consumed reminders through every channel.
+// A failed delivery is retried with a doubling delay, a reminder which still isn't delivered
+// is published to the dead letter publisher once per failed channel.
+type Sender struct {
+	channels    []Channel
+	deadLetter  broker.Publisher
+	deliveries  Deliveries
+	logger      *zap.Logger
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+func New(
+	channels []Channel,
+	deadLetter broker.Publisher,
+	deliveries Deliveries,
+	config configs.SenderConfig,
+	logger *zap.Logger,
+) *Sender {
+	sender := &Sender{
+		channels:    channels,
+		deadLetter:  deadLetter,
+		deliveries:  deliveries,
+		logger:      logger,
+		maxAttempts: config.MaxAttempts,
+		retryDelay:  config.RetryDelay,
+	}
+
+	if sender.maxAttempts <= 0 {
+		sender.maxAttempts = DefaultMaxAttempts
+	}
+
+	if sender.retryDelay <= 0 {
+		sender.retryDelay = DefaultRetryDelay
+	}
+
+	return sender
+}
+
+// Handle delivers the reminder, it fails only if a failed reminder can't be dead lettered
+// or the deliveries can't be read.
+// A dead lettered reminder with the channel header is delivered through that channel only.
+// A reminder with the reminder ID header is delivered through a channel once, so redelivering it
+// sends it only through the channels which failed.
+func (s *Sender) Handle(ctx context.Context, message *broker.Message) error {
+	notification, err := NewNotification(message.Payload)
+	if err != nil {
+		return s.sendDeadLetter(ctx, message, "", err)
+	}
+
+	reminderID := message.Headers[broker.ReminderIDHeader]
+	var result error
+	for _, channel := range s.channels {
+		if name := message.Headers[DeadLetterChannelHeader]; name != "" && name != channel.Name() {
+			continue
+		}
+
+		if reminderID != "" {
+			delivered, err := s.deliveries.IsReminderDelivered(ctx, reminderID, channel.Name())
+			if err != nil {
+				return err
+			}
+
+			if delivered {
+				s.logger.Info("notification already delivered", zap.String("channel", channel.Name()),
+					zap.String("reminder", reminderID))
+				continue
+			}
+		}
+
+		err := s.send(ctx, channel, &notification)
+		if err == nil {
+			if reminderID == "" {
+				continue
+			}
+
+			if err := s.deliveries.MarkReminderDelivered(ctx, reminderID, channel.Name()); err != nil {
+				s.logger.Error("notification delivery record failed", zap.String("channel", channel.Name()),
+					zap.String("reminder", reminderID), zap.Error(err))
+			}
+
+			continue
+		}
+
+		s.logger.Error("notification delivery failed", zap.String("channel", channel.Name()),
+			zap.String("event", notification.EventID), zap.Error(err))
+		result = errors.Join(result, s.sendDeadLetter(ctx, message, channel.Name(), err))
+	}
+
+	return result
+}
+
+func (s *Sender) send(ctx context.Context, channel Channel, notification *Notification) error {
+	delay := s.retryDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = channel.Send(ctx, notification)
+		if err == nil || attempt == s.maxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+			delay *= 2
+		}
+	}
+}
+
+func (s *Sender) sendDeadLetter(ctx context.Context, message *broker.Message, channel string, reason error) error {
+	headers := make(map[string]string, len(message.Headers)+2)
+	for key, value := range message.Headers {
+		headers[key] = value
+	}
+
+	headers[broker.DeadLetterReasonHeader] = reason.Error()
+	if channel != "" {
+		headers[DeadLetterChannelHeader] = channel
+	}
+
+	err := s.deadLetter.Publish(ctx, &broker.Message{Key: message.Key, Payload: message.Payload, Headers: headers})
+	if err != nil {
+		return fmt.Errorf("dead letter publishing failed: %w", err)
+	}
+
+	s.logger.Warn("reminder dead lettered", zap.String("key", message.Key), zap.String("channel", channel),
+		zap.String("reason", reason.Error()))
+	return nil
+}
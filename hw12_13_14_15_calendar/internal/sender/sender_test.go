@@ -0,0 +1,289 @@
+package sender
+
+//nolint:depguard
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker"
+	memorybroker "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/broker/memory"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var errDelivery = errors.New("delivery failed")
+
+type testChannel struct {
+	name     string
+	failures int
+	sent     []Notification
+	attempts int
+}
+
+func (c *testChannel) Name() string { return c.name }
+
+func (c *testChannel) Send(_ context.Context, notification *Notification) error {
+	c.attempts++
+	if c.attempts <= c.failures {
+		return errDelivery
+	}
+
+	c.sent = append(c.sent, *notification)
+	return nil
+}
+
+func TestSender(t *testing.T) {
+	logger := zap.NewNop()
+	config := configs.SenderConfig{MaxAttempts: 3, RetryDelay: time.Millisecond}
+	event := storage.Event{
+		ID:        "test_id",
+		Title:     "test_title",
+		Owner:     "test_user",
+		StartDate: time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC),
+	}
+	payload, _ := json.Marshal(&event)
+
+	deadLetters := func(t *testing.T, deadLetter *memorybroker.Broker) []*broker.Message {
+		t.Helper()
+
+		require.NoError(t, deadLetter.Close())
+		messages := make([]*broker.Message, 0)
+		err := deadLetter.Subscribe(context.Background(), func(_ context.Context, message *broker.Message) error {
+			messages = append(messages, message)
+			return nil
+		})
+		require.NoError(t, err)
+
+		return messages
+	}
+
+	t.Run("notification delivered after retries", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		channel := &testChannel{name: "test", failures: 2}
+		deadLetter := memorybroker.New(10)
+		sender := New([]Channel{channel}, deadLetter, memorystorage.New(), config, logger)
+
+		err := sender.Handle(ctx, &broker.Message{Key: event.ID, Payload: payload})
+		require.NoError(t, err)
+		require.Equal(t, 3, channel.attempts)
+		require.Equal(t, []Notification{{EventID: "test_id", Title: "test_title", Date: event.StartDate, User: "test_user"}},
+			channel.sent)
+		require.Empty(t, deadLetters(t, deadLetter))
+	})
+
+	t.Run("notification delivered to reminder recipient", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		channel := &testChannel{name: "test"}
+		sender := New([]Channel{channel}, memorybroker.New(10), memorystorage.New(), config, logger)
+
+		reminder, err := storage.NewReminderMessage(event, &storage.EventReminder{}, event.StartDate, "attendee_user")
+		require.NoError(t, err)
+
+		err = sender.Handle(ctx, &broker.Message{Key: event.ID, Payload: reminder.Payload})
+		require.NoError(t, err)
+		require.Equal(t, "attendee_user", channel.sent[0].User)
+	})
+
+	t.Run("redelivered reminder sent once", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		failed := &testChannel{name: "failed", failures: 3}
+		delivered := &testChannel{name: "delivered"}
+		deadLetter := memorybroker.New(10)
+		sender := New([]Channel{failed, delivered}, deadLetter, memorystorage.New(), config, logger)
+
+		message := &broker.Message{
+			Key:     event.ID,
+			Payload: payload,
+			Headers: map[string]string{broker.ReminderIDHeader: "test_id/1"},
+		}
+		for i := 0; i < 2; i++ {
+			require.NoError(t, sender.Handle(ctx, message))
+		}
+
+		require.Len(t, delivered.sent, 1)
+		require.Len(t, failed.sent, 1)
+		require.Len(t, deadLetters(t, deadLetter), 1)
+
+		require.NoError(t, sender.Handle(ctx, message))
+		require.Len(t, delivered.sent, 1)
+		require.Len(t, failed.sent, 1)
+	})
+
+	t.Run("failed channel dead lettered", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		failed := &testChannel{name: "failed", failures: 5}
+		delivered := &testChannel{name: "delivered"}
+		deadLetter := memorybroker.New(10)
+		sender := New([]Channel{failed, delivered}, deadLetter, memorystorage.New(), config, logger)
+
+		err := sender.Handle(ctx, &broker.Message{Key: event.ID, Payload: payload})
+		require.NoError(t, err)
+		require.Equal(t, 3, failed.attempts)
+		require.Len(t, delivered.sent, 1)
+
+		messages := deadLetters(t, deadLetter)
+		require.Len(t, messages, 1)
+		require.Equal(t, event.ID, messages[0].Key)
+		require.Equal(t, payload, messages[0].Payload)
+		require.Equal(t, "failed", messages[0].Headers[DeadLetterChannelHeader])
+		require.Equal(t, errDelivery.Error(), messages[0].Headers[broker.DeadLetterReasonHeader])
+
+		failed.failures = 0
+		err = sender.Handle(ctx, messages[0])
+		require.NoError(t, err)
+		require.Len(t, failed.sent, 1)
+		require.Len(t, delivered.sent, 1)
+	})
+
+	t.Run("invalid reminder dead lettered", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		channel := &testChannel{name: "test"}
+		deadLetter := memorybroker.New(10)
+		sender := New([]Channel{channel}, deadLetter, memorystorage.New(), config, logger)
+
+		err := sender.Handle(ctx, &broker.Message{Key: "broken", Payload: []byte("{")})
+		require.NoError(t, err)
+		require.Zero(t, channel.attempts)
+
+		messages := deadLetters(t, deadLetter)
+		require.Len(t, messages, 1)
+		require.Empty(t, messages[0].Headers[DeadLetterChannelHeader])
+		require.Contains(t, messages[0].Headers[broker.DeadLetterReasonHeader], ErrInvalidReminder.Error())
+	})
+
+	t.Run("dead letter publishing failed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		deadLetter := memorybroker.New(10)
+		require.NoError(t, deadLetter.Close())
+		sender := New([]Channel{&testChannel{name: "test", failures: 5}}, deadLetter, memorystorage.New(),
+			config, logger)
+
+		err := sender.Handle(ctx, &broker.Message{Key: event.ID, Payload: payload})
+		require.ErrorIs(t, err, memorybroker.ErrBrokerClosed)
+	})
+
+	t.Run("webhook channel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var received Notification
+		status := http.StatusOK
+		server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			_ = json.Unmarshal(body, &received)
+			resp.WriteHeader(status)
+		}))
+		defer server.Close()
+
+		channel := NewWebhookChannel(configs.WebhookConfig{URL: server.URL, Timeout: time.Second})
+		notification := Notification{EventID: "test_id", Title: "test_title", Date: event.StartDate, User: "test_user"}
+		err := channel.Send(ctx, &notification)
+		require.NoError(t, err)
+		require.Equal(t, notification, received)
+
+		status = http.StatusBadGateway
+		err = channel.Send(ctx, &notification)
+		require.Error(t, err)
+	})
+
+	t.Run("channels from config", func(t *testing.T) {
+		channels, err := NewChannels(configs.SenderConfig{}, logger)
+		require.NoError(t, err)
+		require.Len(t, channels, 1)
+		require.Equal(t, ChannelLog, channels[0].Name())
+
+		_, err = NewChannels(configs.SenderConfig{Channels: []string{ChannelLog, "pigeon"}}, logger)
+		require.ErrorIs(t, err, ErrUnknownChannel)
+
+		err = NewSMTPChannel(configs.SMTPConfig{}).Send(context.Background(), &Notification{User: "test_user"})
+		require.ErrorIs(t, err, ErrNoRecipient)
+	})
+
+	t.Run("smtp delivery bounded", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+
+		// the server accepts the connections and never greets
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+
+				go func() {
+					_, _ = io.Copy(io.Discard, conn)
+					conn.Close()
+				}()
+			}
+		}()
+
+		addr := listener.Addr().(*net.TCPAddr)
+		config := configs.SMTPConfig{Host: "127.0.0.1", Port: addr.Port, From: "calendar@example.com"}
+		notification := &Notification{User: "test_user@example.com", Title: "test_title", Date: event.StartDate}
+
+		config.Timeout = 50 * time.Millisecond
+		started := time.Now()
+		err = NewSMTPChannel(config).Send(context.Background(), notification)
+		require.True(t, errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded), err)
+		require.Less(t, time.Since(started), time.Second)
+
+		config.Timeout = time.Minute
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		started = time.Now()
+		err = NewSMTPChannel(config).Send(ctx, notification)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Less(t, time.Since(started), time.Second)
+	})
+
+	t.Run("smtp headers not injected", func(t *testing.T) {
+		channel := NewSMTPChannel(configs.SMTPConfig{From: "calendar@example.com"})
+		body, err := channel.message("test_user@example.com", &Notification{
+			User:  "test_user",
+			Title: "test_title\r\nBcc: x@evil",
+			Date:  event.StartDate,
+		})
+		require.NoError(t, err)
+
+		message, err := mail.ReadMessage(bytes.NewReader(body))
+		require.NoError(t, err)
+		require.Empty(t, message.Header.Get("Bcc"))
+		require.Len(t, message.Header, 5)
+		require.Equal(t, "test_user@example.com", message.Header.Get("To"))
+
+		subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+		require.NoError(t, err)
+		require.Equal(t, "test_title  Bcc: x@evil", subject)
+
+		_, err = channel.message("test_user@example.com\r\nBcc: x@evil", &Notification{Title: "test_title"})
+		require.ErrorIs(t, err, ErrInvalidHeader)
+	})
+}
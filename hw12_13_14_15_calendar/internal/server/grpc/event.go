@@ -0,0 +1,106 @@
+package internalgrpc
+
+//nolint:depguard
+import (
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/api/pb"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func fromPBEvent(event *pb.Event) *storage.Event {
+	result := &storage.Event{
+		ID:          event.GetId(),
+		Title:       event.GetTitle(),
+		Duration:    time.Duration(event.GetDuration()),
+		Description: event.GetDescription(),
+		Owner:       event.GetOwner(),
+		RRule:       event.GetRrule(),
+		Version:     event.GetVersion(),
+	}
+
+	if event.GetStartDate() != nil {
+		result.StartDate = event.GetStartDate().AsTime()
+	}
+
+	for _, exDate := range event.GetExDates() {
+		result.ExDates = append(result.ExDates, exDate.AsTime())
+	}
+
+	for _, reminder := range event.GetReminders() {
+		eventReminder := storage.EventReminder{Before: reminder.GetBefore()}
+		if reminder.GetAt() != nil {
+			at := reminder.GetAt().AsTime()
+			eventReminder.At = &at
+		}
+
+		result.Reminders = append(result.Reminders, eventReminder)
+	}
+
+	for _, attendee := range event.GetAttendees() {
+		result.Attendees = append(result.Attendees, storage.Attendee{
+			User:   attendee.GetUser(),
+			Status: storage.RSVPStatus(attendee.GetStatus()),
+		})
+	}
+
+	return result
+}
+
+func toPBEvent(event *storage.Event) *pb.Event {
+	exDates := make([]*timestamppb.Timestamp, 0, len(event.ExDates))
+	for _, exDate := range event.ExDates {
+		exDates = append(exDates, timestamppb.New(exDate))
+	}
+
+	attendees := make([]*pb.Attendee, 0, len(event.Attendees))
+	for _, attendee := range event.Attendees {
+		attendees = append(attendees, toPBAttendee(attendee))
+	}
+
+	reminders := make([]*pb.Reminder, 0, len(event.Reminders))
+	for _, reminder := range event.Reminders {
+		reminders = append(reminders, toPBReminder(reminder))
+	}
+
+	return &pb.Event{
+		Id:          event.ID,
+		Title:       event.Title,
+		StartDate:   timestamppb.New(event.StartDate),
+		Duration:    int64(event.Duration),
+		Description: event.Description,
+		Owner:       event.Owner,
+		Rrule:       event.RRule,
+		ExDates:     exDates,
+		Attendees:   attendees,
+		Version:     event.Version,
+		Reminders:   reminders,
+	}
+}
+
+func toPBReminder(reminder storage.EventReminder) *pb.Reminder {
+	result := &pb.Reminder{Before: reminder.Before, IsSend: reminder.IsSend}
+	if reminder.At != nil {
+		result.At = timestamppb.New(*reminder.At)
+	}
+
+	if !reminder.SentUntil.IsZero() {
+		result.SentUntil = timestamppb.New(reminder.SentUntil)
+	}
+
+	return result
+}
+
+func toPBAttendee(attendee storage.Attendee) *pb.Attendee {
+	return &pb.Attendee{User: attendee.User, Status: string(attendee.Status)}
+}
+
+func toPBEvents(events []storage.Event) []*pb.Event {
+	result := make([]*pb.Event, 0, len(events))
+	for i := range events {
+		result = append(result, toPBEvent(&events[i]))
+	}
+
+	return result
+}
@@ -0,0 +1,55 @@
+package internalgrpc
+
+//nolint:depguard
+import (
+	"context"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/identity"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) loggingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		startTime := time.Now()
+		resp, err := handler(ctx, req)
+
+		address := ""
+		if p, ok := peer.FromContext(ctx); ok {
+			address = p.Addr.String()
+		}
+
+		s.logger.Info("Grpc Request INFO",
+			zap.String("IP", address),
+			zap.Time("Time", startTime),
+			zap.String("Method", info.FullMethod),
+			zap.String("Code", status.Code(err).String()),
+			zap.Duration("Duration", time.Since(startTime)))
+		return resp, err
+	}
+}
+
+// identityInterceptor rejects the calls of unidentified callers and passes the caller to the handlers,
+// the caller is read from the request metadata the same way as from the HTTP headers.
+func (s *Server) identityInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		caller, err := s.authenticator.Authenticate(func(name string) string {
+			if values := md.Get(name); len(values) > 0 {
+				return values[0]
+			}
+
+			return ""
+		})
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		return handler(identity.WithCaller(ctx, caller), req)
+	}
+}
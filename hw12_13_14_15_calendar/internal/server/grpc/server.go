@@ -0,0 +1,233 @@
+package internalgrpc
+
+//nolint:depguard
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/api/pb"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Server struct {
+	pb.UnimplementedEventServiceServer
+
+	server        *grpc.Server
+	logger        *zap.Logger
+	app           *app.App
+	authenticator *identity.Authenticator
+	ctx           context.Context
+}
+
+func NewServer(ctx context.Context, logger *zap.Logger, app *app.App, authenticator *identity.Authenticator) *Server {
+	return &Server{ctx: ctx, logger: logger, app: app, authenticator: authenticator}
+}
+
+func (s *Server) Start(config configs.GRPCConfig) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", config.Host, config.Port))
+	if err != nil {
+		return err
+	}
+
+	s.server = grpc.NewServer(grpc.ChainUnaryInterceptor(s.loggingInterceptor(), s.identityInterceptor()))
+	pb.RegisterEventServiceServer(s.server, s)
+
+	s.logger.Info("grpc server is running on address: " + listener.Addr().String())
+	err = s.server.Serve(listener)
+	if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+
+	<-s.ctx.Done()
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	if s.server == nil {
+		return errors.New("grpc server is already stopped")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		s.server.GracefulStop()
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+func (s *Server) CreateEvent(ctx context.Context, req *pb.CreateEventRequest) (*pb.CreateEventResponse, error) {
+	if req.GetEvent() == nil {
+		return nil, status.Error(codes.InvalidArgument, "event is required")
+	}
+
+	event := fromPBEvent(req.GetEvent())
+	conflicts, err := s.app.CreateEvent(ctx, event)
+	if err != nil {
+		s.logger.Error("create event save failed", zap.Error(err))
+		return nil, toStatusError(err)
+	}
+
+	return &pb.CreateEventResponse{Event: toPBEvent(event), Conflicts: toPBEvents(conflicts)}, nil
+}
+
+func (s *Server) UpdateEvent(ctx context.Context, req *pb.UpdateEventRequest) (*pb.UpdateEventResponse, error) {
+	if req.GetEvent() == nil {
+		return nil, status.Error(codes.InvalidArgument, "event is required")
+	}
+
+	event := fromPBEvent(req.GetEvent())
+	conflicts, err := s.app.UpdateEvent(ctx, event)
+	if err != nil {
+		s.logger.Error("update event save failed", zap.Error(err))
+		return nil, toStatusError(err)
+	}
+
+	return &pb.UpdateEventResponse{Event: toPBEvent(event), Conflicts: toPBEvents(conflicts)}, nil
+}
+
+func (s *Server) DeleteEvent(ctx context.Context, req *pb.DeleteEventRequest) (*pb.DeleteEventResponse, error) {
+	if req.GetId() == "" {
+		s.logger.Error("delete event id is required")
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	err := s.app.DeleteEvent(ctx, req.GetId())
+	if err != nil {
+		s.logger.Error("delete event failed", zap.Error(err))
+		return nil, toStatusError(err)
+	}
+
+	return &pb.DeleteEventResponse{Id: req.GetId()}, nil
+}
+
+func (s *Server) GetDayEvents(ctx context.Context, req *pb.GetEventsRequest) (*pb.GetEventsResponse, error) {
+	if req.GetOwner() == "" {
+		s.logger.Error("get events day owner is required")
+		return nil, status.Error(codes.InvalidArgument, "owner is required")
+	}
+
+	date, err := listingDate(req)
+	if err != nil {
+		s.logger.Error("get events day date is invalid", zap.Error(err))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	events, err := s.app.GetEventsDay(ctx, req.GetOwner(), date)
+	if err != nil {
+		s.logger.Error("get events day failed", zap.Error(err))
+		return nil, toStatusError(err)
+	}
+
+	return &pb.GetEventsResponse{Events: toPBEvents(events)}, nil
+}
+
+func (s *Server) GetWeekEvents(ctx context.Context, req *pb.GetEventsRequest) (*pb.GetEventsResponse, error) {
+	if req.GetOwner() == "" {
+		s.logger.Error("get events week owner is required")
+		return nil, status.Error(codes.InvalidArgument, "owner is required")
+	}
+
+	date, err := listingDate(req)
+	if err != nil {
+		s.logger.Error("get events week date is invalid", zap.Error(err))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	events, err := s.app.GetEventsWeek(ctx, req.GetOwner(), date)
+	if err != nil {
+		s.logger.Error("get events week failed", zap.Error(err))
+		return nil, toStatusError(err)
+	}
+
+	return &pb.GetEventsResponse{Events: toPBEvents(events)}, nil
+}
+
+func (s *Server) GetMonthEvents(ctx context.Context, req *pb.GetEventsRequest) (*pb.GetEventsResponse, error) {
+	if req.GetOwner() == "" {
+		s.logger.Error("get events month owner is required")
+		return nil, status.Error(codes.InvalidArgument, "owner is required")
+	}
+
+	date, err := listingDate(req)
+	if err != nil {
+		s.logger.Error("get events month date is invalid", zap.Error(err))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	events, err := s.app.GetEventsMonth(ctx, req.GetOwner(), date)
+	if err != nil {
+		s.logger.Error("get events month failed", zap.Error(err))
+		return nil, toStatusError(err)
+	}
+
+	return &pb.GetEventsResponse{Events: toPBEvents(events)}, nil
+}
+
+func (s *Server) RespondToInvitation(ctx context.Context,
+	req *pb.RespondToInvitationRequest,
+) (*pb.RespondToInvitationResponse, error) {
+	if req.GetId() == "" {
+		s.logger.Error("respond to invitation id is required")
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	attendee, err := s.app.RespondToInvitation(ctx, req.GetId(), storage.RSVPStatus(req.GetStatus()))
+	if err != nil {
+		s.logger.Error("respond to invitation failed", zap.Error(err))
+		return nil, toStatusError(err)
+	}
+
+	return &pb.RespondToInvitationResponse{Attendee: toPBAttendee(attendee)}, nil
+}
+
+func listingDate(req *pb.GetEventsRequest) (time.Time, error) {
+	if req.GetDate() == "" {
+		return app.ListingDate(nil, req.GetTimeZone())
+	}
+
+	date, err := time.Parse(time.DateOnly, req.GetDate())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date: %w", err)
+	}
+
+	return app.ListingDate(&date, req.GetTimeZone())
+}
+
+func toStatusError(err error) error {
+	var conflictErr *app.ConflictError
+	switch {
+	case errors.As(err, &conflictErr):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, app.ErrValidation):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, app.ErrUnauthenticated):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, app.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, storage.ErrEventDoesNotExist):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, storage.ErrEventAlreadyExist):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, storage.ErrEventVersionConflict):
+		return status.Error(codes.Aborted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
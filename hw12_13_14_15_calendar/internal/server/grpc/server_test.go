@@ -0,0 +1,213 @@
+package internalgrpc
+
+//nolint:depguard
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/api/pb"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/logger"
+	memorystorage "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newTestClient(t *testing.T) pb.EventServiceClient {
+	t.Helper()
+
+	var level zapcore.Level
+	logg, _ := logger.New(level, os.TempDir()+"/test.log")
+
+	server := NewServer(context.Background(), logg, app.New(memorystorage.New()), identity.New(configs.AuthConfig{}))
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(server.loggingInterceptor(), server.identityInterceptor()))
+	pb.RegisterEventServiceServer(grpcServer, server)
+
+	listener := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewEventServiceClient(conn)
+}
+
+//nolint:funlen
+func TestServer(t *testing.T) {
+	testEvent := &pb.Event{
+		Id:          "test_id",
+		Title:       "test_title",
+		Owner:       "test_user",
+		StartDate:   timestamppb.Now(),
+		Duration:    30,
+		Description: "test_description",
+		Reminders:   []*pb.Reminder{{Before: 100}},
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-user-id", testEvent.Owner)
+
+	t.Run("Create event", func(t *testing.T) {
+		client := newTestClient(t)
+
+		resp, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: testEvent})
+		require.NoError(t, err)
+		require.Equal(t, testEvent.Id, resp.GetEvent().GetId())
+		require.Equal(t, testEvent.Title, resp.GetEvent().GetTitle())
+		require.Equal(t, testEvent.Owner, resp.GetEvent().GetOwner())
+		require.Equal(t, testEvent.StartDate.AsTime(), resp.GetEvent().GetStartDate().AsTime())
+		require.Equal(t, testEvent.Duration, resp.GetEvent().GetDuration())
+		require.Equal(t, testEvent.Description, resp.GetEvent().GetDescription())
+		require.Equal(t, int64(100), resp.GetEvent().GetReminders()[0].GetBefore())
+	})
+
+	t.Run("Create event validation failed", func(t *testing.T) {
+		client := newTestClient(t)
+
+		_, err := client.CreateEvent(ctx, &pb.CreateEventRequest{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Update event", func(t *testing.T) {
+		client := newTestClient(t)
+
+		created, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: testEvent})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), created.GetEvent().GetVersion())
+
+		updatedEvent := &pb.Event{
+			Id:          testEvent.Id,
+			Title:       "test_title2",
+			Owner:       testEvent.Owner,
+			StartDate:   timestamppb.New(time.Now()),
+			Duration:    32,
+			Description: "test_description2",
+			Reminders:   []*pb.Reminder{{Before: 120}},
+			Version:     created.GetEvent().GetVersion(),
+		}
+
+		resp, err := client.UpdateEvent(ctx, &pb.UpdateEventRequest{Event: updatedEvent})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), resp.GetEvent().GetVersion())
+		require.Equal(t, updatedEvent.Id, resp.GetEvent().GetId())
+		require.Equal(t, updatedEvent.Title, resp.GetEvent().GetTitle())
+		require.Equal(t, updatedEvent.Owner, resp.GetEvent().GetOwner())
+		require.Equal(t, updatedEvent.Duration, resp.GetEvent().GetDuration())
+		require.Equal(t, updatedEvent.Description, resp.GetEvent().GetDescription())
+		require.Equal(t, int64(120), resp.GetEvent().GetReminders()[0].GetBefore())
+
+		_, err = client.UpdateEvent(ctx, &pb.UpdateEventRequest{Event: updatedEvent})
+		require.Equal(t, codes.Aborted, status.Code(err))
+	})
+
+	t.Run("Delete event", func(t *testing.T) {
+		client := newTestClient(t)
+
+		_, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: testEvent})
+		require.NoError(t, err)
+
+		resp, err := client.DeleteEvent(ctx, &pb.DeleteEventRequest{Id: testEvent.Id})
+		require.NoError(t, err)
+		require.Equal(t, testEvent.Id, resp.GetId())
+
+		_, err = client.DeleteEvent(ctx, &pb.DeleteEventRequest{Id: testEvent.Id})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Get day, week and month events", func(t *testing.T) {
+		client := newTestClient(t)
+
+		_, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: testEvent})
+		require.NoError(t, err)
+
+		day, err := client.GetDayEvents(ctx, &pb.GetEventsRequest{Owner: testEvent.Owner})
+		require.NoError(t, err)
+		require.Len(t, day.GetEvents(), 1)
+
+		week, err := client.GetWeekEvents(ctx, &pb.GetEventsRequest{Owner: testEvent.Owner})
+		require.NoError(t, err)
+		require.Len(t, week.GetEvents(), 1)
+
+		month, err := client.GetMonthEvents(ctx, &pb.GetEventsRequest{Owner: testEvent.Owner})
+		require.NoError(t, err)
+		require.Len(t, month.GetEvents(), 1)
+
+		_, err = client.GetDayEvents(ctx, &pb.GetEventsRequest{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Access control", func(t *testing.T) {
+		client := newTestClient(t)
+
+		_, err := client.CreateEvent(context.Background(), &pb.CreateEventRequest{Event: testEvent})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		_, err = client.CreateEvent(ctx, &pb.CreateEventRequest{Event: testEvent})
+		require.NoError(t, err)
+
+		otherCtx := metadata.AppendToOutgoingContext(context.Background(), "x-user-id", "test_user2")
+		_, err = client.DeleteEvent(otherCtx, &pb.DeleteEventRequest{Id: testEvent.Id})
+		require.Equal(t, codes.NotFound, status.Code(err), "the events of others are not revealed")
+
+		_, err = client.GetDayEvents(otherCtx, &pb.GetEventsRequest{Owner: testEvent.Owner})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		transferred := &pb.Event{
+			Id:        testEvent.Id,
+			Title:     testEvent.Title,
+			Owner:     "test_user2",
+			StartDate: testEvent.StartDate,
+			Duration:  testEvent.Duration,
+			Version:   1,
+		}
+		_, err = client.UpdateEvent(ctx, &pb.UpdateEventRequest{Event: transferred})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Respond to invitation", func(t *testing.T) {
+		client := newTestClient(t)
+
+		invitation := &pb.Event{
+			Id:        testEvent.Id,
+			Title:     testEvent.Title,
+			Owner:     testEvent.Owner,
+			StartDate: testEvent.StartDate,
+			Duration:  testEvent.Duration,
+			Attendees: []*pb.Attendee{{User: "test_user2"}},
+		}
+		resp, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: invitation})
+		require.NoError(t, err)
+		require.Equal(t, "needs-action", resp.GetEvent().GetAttendees()[0].GetStatus())
+
+		attendeeCtx := metadata.AppendToOutgoingContext(context.Background(), "x-user-id", "test_user2")
+		rsvp, err := client.RespondToInvitation(attendeeCtx,
+			&pb.RespondToInvitationRequest{Id: testEvent.Id, Status: "accepted"})
+		require.NoError(t, err)
+		require.Equal(t, "test_user2", rsvp.GetAttendee().GetUser())
+		require.Equal(t, "accepted", rsvp.GetAttendee().GetStatus())
+
+		_, err = client.RespondToInvitation(ctx, &pb.RespondToInvitationRequest{Id: testEvent.Id, Status: "accepted"})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = client.RespondToInvitation(attendeeCtx, &pb.RespondToInvitationRequest{Id: testEvent.Id, Status: "maybe"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
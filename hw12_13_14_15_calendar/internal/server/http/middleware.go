@@ -1,17 +1,44 @@
 package internalhttp
 
+//nolint:depguard
 import (
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/metrics"
 	"go.uber.org/zap"
 )
 
-func (s *Server) loggingMiddleware() func(http.Handler) http.Handler {
+// statusWriter remembers the status code of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// loggingMiddleware logs the requests and counts them by the mux route, so the metrics
+// don't grow with the event ids in the paths.
+func (s *Server) loggingMiddleware(mux *http.ServeMux) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			startTime := time.Now()
-			next.ServeHTTP(w, r)
+			writer := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(writer, r)
+
+			_, pattern := mux.Handler(r)
+			if _, path, ok := strings.Cut(pattern, " "); ok {
+				pattern = path
+			}
+
+			metrics.HTTPRequests.WithLabelValues(r.Method, pattern, strconv.Itoa(writer.status)).Inc()
+			metrics.HTTPRequestDuration.WithLabelValues(r.Method, pattern).Observe(time.Since(startTime).Seconds())
 			s.logger.Info("Rest Request INFO",
 				zap.String("IP", r.RemoteAddr),
 				zap.Time("Time", startTime),
@@ -32,3 +59,23 @@ func (s *Server) contentTypeJSONMiddleware() func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// identityMiddleware rejects the requests of unidentified callers and passes the caller to the handlers.
+func (s *Server) identityMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			caller, err := s.authenticator.Authenticate(r.Header.Get)
+			if err != nil {
+				s.logger.Error("request authentication failed", zap.Error(err))
+				if s.authenticator.JWT() {
+					w.Header().Set("WWW-Authenticate", "Bearer")
+				}
+
+				s.writeProblem(w, newProblem(http.StatusUnauthorized, err.Error()))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(identity.WithCaller(r.Context(), caller)))
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package internalhttp
+
+//nolint:depguard
+import (
+	"errors"
+	"net/http"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/api"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	jsoniter "github.com/json-iterator/go"
+	"go.uber.org/zap"
+)
+
+const problemContentType = "application/problem+json"
+
+// problem is an RFC 9457 problem details body.
+type problem struct {
+	Type          string          `json:"type"`
+	Title         string          `json:"title"`
+	Status        int             `json:"status"`
+	Detail        string          `json:"detail,omitempty"`
+	InvalidParams []invalidParam  `json:"invalidParams,omitempty"`
+	Conflicts     []storage.Event `json:"conflicts,omitempty"`
+}
+
+type invalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+func newProblem(status int, detail string) problem {
+	return problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+// errorProblem maps the app error kinds to the documented status codes,
+// the details of unexpected errors are only logged.
+func errorProblem(err error) problem {
+	var validationErr *app.ValidationError
+	var conflictErr *app.ConflictError
+	switch {
+	case errors.As(err, &validationErr):
+		result := newProblem(http.StatusUnprocessableEntity, validationErr.Message)
+		result.InvalidParams = []invalidParam{{Name: validationErr.Field, Reason: validationErr.Message}}
+		return result
+	case errors.As(err, &conflictErr):
+		result := newProblem(http.StatusConflict, conflictErr.Error())
+		result.Conflicts = conflictErr.Events
+		return result
+	case errors.Is(err, storage.ErrEventVersionConflict):
+		return newProblem(http.StatusPreconditionFailed, err.Error())
+	case errors.Is(err, app.ErrConflict):
+		return newProblem(http.StatusConflict, err.Error())
+	case errors.Is(err, app.ErrUnauthenticated):
+		return newProblem(http.StatusUnauthorized, err.Error())
+	case errors.Is(err, app.ErrForbidden):
+		return newProblem(http.StatusForbidden, err.Error())
+	case errors.Is(err, app.ErrNotFound):
+		return newProblem(http.StatusNotFound, err.Error())
+	default:
+		return newProblem(http.StatusInternalServerError, "")
+	}
+}
+
+func (s *Server) writeError(resp http.ResponseWriter, err error) {
+	s.writeProblem(resp, errorProblem(err))
+}
+
+func (s *Server) writeProblem(resp http.ResponseWriter, body problem) {
+	result, err := jsoniter.Marshal(body)
+	if err != nil {
+		s.logger.Error("problem marshal failed", zap.Error(err))
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp.Header().Set("Content-Type", problemContentType)
+	resp.WriteHeader(body.Status)
+	_, err = resp.Write(result)
+	if err != nil {
+		s.logger.Error("problem response write failed", zap.Error(err))
+	}
+}
+
+// paramError answers the requests with malformed parameters rejected by the generated wrappers,
+// updates without If-Match are answered with 428.
+func (s *Server) paramError(resp http.ResponseWriter, _ *http.Request, err error) {
+	s.logger.Error("request params are invalid", zap.Error(err))
+	var headerErr *api.RequiredHeaderError
+	if errors.As(err, &headerErr) && headerErr.ParamName == "If-Match" {
+		s.writeProblem(resp, newProblem(http.StatusPreconditionRequired, err.Error()))
+		return
+	}
+
+	s.writeProblem(resp, newProblem(http.StatusBadRequest, err.Error()))
+}
@@ -2,40 +2,70 @@ package internalhttp
 
 //nolint:depguard
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/api"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/health"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/ical"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/metrics"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
 	jsoniter "github.com/json-iterator/go"
+	openapitypes "github.com/oapi-codegen/runtime/types"
 	"go.uber.org/zap"
 )
 
 type Server struct {
-	server *http.Server
-	logger *zap.Logger
-	app    *app.App
-	ctx    context.Context
+	server        *http.Server
+	logger        *zap.Logger
+	app           *app.App
+	authenticator *identity.Authenticator
+	checks        []health.Check
+	ctx           context.Context
 }
 
-func NewServer(ctx context.Context, logger *zap.Logger, app *app.App) *Server {
-	return &Server{ctx: ctx, logger: logger, app: app}
+// NewServer creates the server, the checks are the dependencies /readyz probes.
+func NewServer(
+	ctx context.Context,
+	logger *zap.Logger,
+	app *app.App,
+	authenticator *identity.Authenticator,
+	checks ...health.Check,
+) *Server {
+	return &Server{ctx: ctx, logger: logger, app: app, authenticator: authenticator, checks: checks}
 }
 
-func (s *Server) Start(config configs.HTTPConfig) error {
+// Handler routes the API requests through the middlewares, the requests are served on behalf of their callers.
+// The metrics and the health probes are served to anyone.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	health.Register(mux, s.checks...)
 	options := api.StdHTTPServerOptions{
-		BaseRouter:  http.NewServeMux(),
-		Middlewares: []api.MiddlewareFunc{s.contentTypeJSONMiddleware(), s.loggingMiddleware()},
+		BaseRouter: mux,
+		Middlewares: []api.MiddlewareFunc{
+			s.identityMiddleware(), s.contentTypeJSONMiddleware(), s.loggingMiddleware(mux),
+		},
+		ErrorHandlerFunc: s.paramError,
 	}
-	handler := api.HandlerWithOptions(s, options)
+
+	return api.HandlerWithOptions(s, options)
+}
+
+func (s *Server) Start(config configs.HTTPConfig) error {
 	s.server = &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", config.Host, config.Port),
-		Handler:      handler,
+		Handler:      s.Handler(),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
@@ -58,64 +88,258 @@ func (s *Server) Stop(ctx context.Context) error {
 	return s.server.Shutdown(ctx)
 }
 
+func (s *Server) ListEvents(resp http.ResponseWriter, req *http.Request, params api.ListEventsParams) {
+	query, err := eventQuery(params)
+	if err != nil {
+		s.logger.Error("list events params are invalid", zap.Error(err))
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	page, err := s.app.ListEvents(req.Context(), query)
+	if err != nil {
+		s.logger.Error("list events failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	result, err := jsoniter.Marshal(page)
+	if err != nil {
+		s.logger.Error("list events marshal failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	_, err = resp.Write(result)
+	if err != nil {
+		s.logger.Error("list events response write failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+}
+
 func (s *Server) CreateEvent(resp http.ResponseWriter, req *http.Request) { //nolint:dupl
 	var event storage.Event
 	err := jsoniter.NewDecoder(req.Body).Decode(&event)
 	if err != nil {
 		s.logger.Error("create event decode failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusBadRequest)
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, err.Error()))
 		return
 	}
 
-	err = s.app.CreateEvent(s.ctx, &event)
+	conflicts, err := s.app.CreateEvent(req.Context(), &event)
 	if err != nil {
 		s.logger.Error("create event save failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 
+	setConflictsHeader(resp, conflicts)
+	resp.Header().Set("ETag", eventETag(event))
+
 	result, err := jsoniter.Marshal(event)
 	if err != nil {
 		s.logger.Error("create event marshal failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 
 	_, err = resp.Write(result)
 	if err != nil {
 		s.logger.Error("create event response write failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 }
 
-func (s *Server) UpdateEvent(resp http.ResponseWriter, req *http.Request) { //nolint:dupl
+func (s *Server) UpdateEvent(resp http.ResponseWriter, req *http.Request, params api.UpdateEventParams) {
 	var event storage.Event
 	err := jsoniter.NewDecoder(req.Body).Decode(&event)
 	if err != nil {
 		s.logger.Error("update event decode failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusBadRequest)
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, err.Error()))
 		return
 	}
 
-	err = s.app.UpdateEvent(s.ctx, &event)
+	event.Version, err = parseETag(params.IfMatch)
+	if err != nil {
+		s.logger.Error("update event if-match is invalid", zap.Error(err))
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	conflicts, err := s.app.UpdateEvent(req.Context(), &event)
 	if err != nil {
 		s.logger.Error("update event save failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 
+	setConflictsHeader(resp, conflicts)
+	resp.Header().Set("ETag", eventETag(event))
+
 	result, err := jsoniter.Marshal(event)
 	if err != nil {
 		s.logger.Error("update event marshal failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 
 	_, err = resp.Write(result)
 	if err != nil {
 		s.logger.Error("update event response write failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
+		return
+	}
+}
+
+func (s *Server) GetEvent(resp http.ResponseWriter, req *http.Request, id string) {
+	event, err := s.app.GetEvent(req.Context(), id)
+	if err != nil {
+		s.logger.Error("get event failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	resp.Header().Set("ETag", eventETag(event))
+
+	result, err := jsoniter.Marshal(event)
+	if err != nil {
+		s.logger.Error("get event marshal failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	_, err = resp.Write(result)
+	if err != nil {
+		s.logger.Error("get event response write failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+}
+
+func (s *Server) DeleteEventByID(resp http.ResponseWriter, req *http.Request, id string) {
+	err := s.app.DeleteEvent(req.Context(), id)
+	if err != nil {
+		s.logger.Error("delete event failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) GetEventHistory(resp http.ResponseWriter, req *http.Request, id string) {
+	entries, err := s.app.EventHistory(req.Context(), id)
+	if err != nil {
+		s.logger.Error("get event history failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	result, err := jsoniter.Marshal(entries)
+	if err != nil {
+		s.logger.Error("get event history marshal failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	_, err = resp.Write(result)
+	if err != nil {
+		s.logger.Error("get event history response write failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+}
+
+func (s *Server) ListTrash(resp http.ResponseWriter, req *http.Request) {
+	events, err := s.app.ListTrash(req.Context())
+	if err != nil {
+		s.logger.Error("list trash failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	result, err := jsoniter.Marshal(events)
+	if err != nil {
+		s.logger.Error("list trash marshal failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	_, err = resp.Write(result)
+	if err != nil {
+		s.logger.Error("list trash response write failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+}
+
+func (s *Server) RestoreEvent(resp http.ResponseWriter, req *http.Request, id string) {
+	event, err := s.app.RestoreEvent(req.Context(), id)
+	if err != nil {
+		s.logger.Error("restore event failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	resp.Header().Set("ETag", eventETag(event))
+
+	result, err := jsoniter.Marshal(event)
+	if err != nil {
+		s.logger.Error("restore event marshal failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	_, err = resp.Write(result)
+	if err != nil {
+		s.logger.Error("restore event response write failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+}
+
+// PatchEvent updates the supplied event fields only, the request body is a JSON Merge Patch.
+func (s *Server) PatchEvent(resp http.ResponseWriter, req *http.Request, id string, params api.PatchEventParams) {
+	var patch map[string]any
+	decoder := jsoniter.NewDecoder(req.Body)
+	decoder.UseNumber()
+	err := decoder.Decode(&patch)
+	if err != nil {
+		s.logger.Error("patch event decode failed", zap.Error(err))
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	version, err := parseETag(params.IfMatch)
+	if err != nil {
+		s.logger.Error("patch event if-match is invalid", zap.Error(err))
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	event, conflicts, err := s.app.PatchEvent(req.Context(), id, version, patch)
+	if err != nil {
+		s.logger.Error("patch event save failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	setConflictsHeader(resp, conflicts)
+	resp.Header().Set("ETag", eventETag(event))
+
+	result, err := jsoniter.Marshal(event)
+	if err != nil {
+		s.logger.Error("patch event marshal failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	_, err = resp.Write(result)
+	if err != nil {
+		s.logger.Error("patch event response write failed", zap.Error(err))
+		s.writeError(resp, err)
 		return
 	}
 }
@@ -125,121 +349,431 @@ func (s *Server) DeleteEvent(resp http.ResponseWriter, req *http.Request) {
 	err := jsoniter.NewDecoder(req.Body).Decode(&event)
 	if err != nil {
 		s.logger.Error("delete event decode failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusBadRequest)
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, err.Error()))
 		return
 	}
 
 	if event.ID == "" {
 		s.logger.Error("delete event id is required")
-		http.Error(resp, "id is required", http.StatusBadRequest)
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, "id is required"))
 		return
 	}
 
-	err = s.app.DeleteEvent(s.ctx, event.ID)
+	err = s.app.DeleteEvent(req.Context(), event.ID)
 	if err != nil {
 		s.logger.Error("delete event failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 
 	result, err := jsoniter.Marshal(event)
 	if err != nil {
 		s.logger.Error("delete event marshal failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 
 	_, err = resp.Write(result)
 	if err != nil {
 		s.logger.Error("delete event response write failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 }
 
-func (s *Server) GetDayEvents(resp http.ResponseWriter, _ *http.Request, owner string) { //nolint:dupl
+func (s *Server) RespondToInvitation(resp http.ResponseWriter, req *http.Request, id string) {
+	var rsvp api.RSVP
+	err := jsoniter.NewDecoder(req.Body).Decode(&rsvp)
+	if err != nil {
+		s.logger.Error("respond to invitation decode failed", zap.Error(err))
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	attendee, err := s.app.RespondToInvitation(req.Context(), id, storage.RSVPStatus(rsvp.Status))
+	if err != nil {
+		s.logger.Error("respond to invitation failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	result, err := jsoniter.Marshal(attendee)
+	if err != nil {
+		s.logger.Error("respond to invitation marshal failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	_, err = resp.Write(result)
+	if err != nil {
+		s.logger.Error("respond to invitation response write failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+}
+
+func (s *Server) GetDayEvents(resp http.ResponseWriter, req *http.Request, owner string, params api.GetDayEventsParams) { //nolint:dupl
 	if owner == "" {
 		s.logger.Error("get events day owner is required")
-		http.Error(resp, "owner is required", http.StatusBadRequest)
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, "owner is required"))
 		return
 	}
 
-	events, err := s.app.GetEventsDay(s.ctx, owner)
+	date, err := listingDate(params.Date, params.TimeZone)
+	if err != nil {
+		s.logger.Error("get events day date is invalid", zap.Error(err))
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	events, err := s.app.GetEventsDay(req.Context(), owner, date)
 	if err != nil {
 		s.logger.Error("get events day failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 
 	result, err := jsoniter.Marshal(events)
 	if err != nil {
 		s.logger.Error("get events day marshal failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 
 	_, err = resp.Write(result)
 	if err != nil {
 		s.logger.Error("get events day response write failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 }
 
-func (s *Server) GetWeekEvents(resp http.ResponseWriter, _ *http.Request, owner string) { //nolint:dupl
+func (s *Server) GetWeekEvents(resp http.ResponseWriter, req *http.Request, owner string, params api.GetWeekEventsParams) { //nolint:dupl
 	if owner == "" {
 		s.logger.Error("get events week owner is required")
-		http.Error(resp, "owner is required", http.StatusBadRequest)
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, "owner is required"))
+		return
+	}
+
+	date, err := listingDate(params.Date, params.TimeZone)
+	if err != nil {
+		s.logger.Error("get events week date is invalid", zap.Error(err))
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, err.Error()))
 		return
 	}
 
-	events, err := s.app.GetEventsWeek(s.ctx, owner)
+	events, err := s.app.GetEventsWeek(req.Context(), owner, date)
 	if err != nil {
 		s.logger.Error("get events week failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 
 	result, err := jsoniter.Marshal(events)
 	if err != nil {
 		s.logger.Error("get events week marshal failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 
 	_, err = resp.Write(result)
 	if err != nil {
 		s.logger.Error("get events week response write failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 }
 
-func (s *Server) GetMonthEvents(resp http.ResponseWriter, _ *http.Request, owner string) { //nolint: dupl
+func (s *Server) GetMonthEvents(resp http.ResponseWriter, req *http.Request, owner string, params api.GetMonthEventsParams) { //nolint:dupl
 	if owner == "" {
 		s.logger.Error("get events month owner is required")
-		http.Error(resp, "owner is required", http.StatusBadRequest)
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, "owner is required"))
 		return
 	}
 
-	events, err := s.app.GetEventsMonth(s.ctx, owner)
+	date, err := listingDate(params.Date, params.TimeZone)
+	if err != nil {
+		s.logger.Error("get events month date is invalid", zap.Error(err))
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	events, err := s.app.GetEventsMonth(req.Context(), owner, date)
 	if err != nil {
 		s.logger.Error("get events month failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 
 	result, err := jsoniter.Marshal(events)
 	if err != nil {
 		s.logger.Error("get events month marshal failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
 		return
 	}
 
 	_, err = resp.Write(result)
 	if err != nil {
 		s.logger.Error("get events month response write failed", zap.Error(err))
-		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		s.writeError(resp, err)
+		return
+	}
+}
+
+func (s *Server) ExportEvents(
+	resp http.ResponseWriter, req *http.Request, owner string, params api.ExportEventsParams,
+) {
+	if !params.From.Before(params.To) {
+		s.logger.Error("export events period is invalid")
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, "from must be before to"))
+		return
+	}
+
+	events, err := s.app.ExportEvents(req.Context(), owner, params.From, params.To)
+	if err != nil {
+		s.logger.Error("export events failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	var result bytes.Buffer
+	err = ical.Encode(&result, events)
+	if err != nil {
+		s.logger.Error("export events encode failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	resp.Header().Set("Content-Disposition", `attachment; filename="calendar.ics"`)
+	_, err = resp.Write(result.Bytes())
+	if err != nil {
+		s.logger.Error("export events response write failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+}
+
+func (s *Server) GetFreeBusy(resp http.ResponseWriter, req *http.Request, params api.GetFreeBusyParams) {
+	query, err := freeBusyQuery(params)
+	if err != nil {
+		s.logger.Error("get free busy params are invalid", zap.Error(err))
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	freeBusy, err := s.app.FreeBusy(req.Context(), query)
+	if err != nil {
+		s.logger.Error("get free busy failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	result, err := jsoniter.Marshal(freeBusy)
+	if err != nil {
+		s.logger.Error("get free busy marshal failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	_, err = resp.Write(result)
+	if err != nil {
+		s.logger.Error("get free busy response write failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+}
+
+// MaxImportSize limits the size of an imported calendar document.
+const MaxImportSize = 1 << 20
+
+func (s *Server) ImportEvents(resp http.ResponseWriter, req *http.Request, owner string) {
+	body, err := io.ReadAll(http.MaxBytesReader(resp, req.Body, MaxImportSize))
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		s.logger.Error("import events body is too large", zap.Error(err))
+		s.writeProblem(resp, newProblem(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("calendar can't be larger than %d bytes", MaxImportSize)))
+		return
+	case err != nil:
+		s.logger.Error("import events read failed", zap.Error(err))
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	components, err := ical.Decode(bytes.NewReader(body))
+	if err != nil {
+		s.logger.Error("import events decode failed", zap.Error(err))
+		s.writeProblem(resp, newProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	importResult, err := s.app.ImportEvents(req.Context(), owner, components)
+	if err != nil {
+		s.logger.Error("import events failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+
+	for _, importError := range importResult.Errors {
+		s.logger.Error("import event failed", zap.String("uid", importError.UID), zap.String("error", importError.Error))
+	}
+
+	result, err := jsoniter.Marshal(importResult)
+	if err != nil {
+		s.logger.Error("import events marshal failed", zap.Error(err))
+		s.writeError(resp, err)
 		return
 	}
+
+	_, err = resp.Write(result)
+	if err != nil {
+		s.logger.Error("import events response write failed", zap.Error(err))
+		s.writeError(resp, err)
+		return
+	}
+}
+
+// eventETag is the strong entity tag of the event version.
+func eventETag(event storage.Event) string {
+	return strconv.Quote(strconv.FormatInt(event.Version, 10))
+}
+
+func parseETag(tag string) (int64, error) {
+	value, err := strconv.Unquote(strings.TrimSpace(tag))
+	if err != nil {
+		return 0, fmt.Errorf("If-Match must be a quoted event version, got %s", tag)
+	}
+
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("If-Match must be a quoted event version, got %s", tag)
+	}
+
+	return version, nil
+}
+
+func setConflictsHeader(resp http.ResponseWriter, conflicts []storage.Event) {
+	if len(conflicts) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(conflicts))
+	for _, event := range conflicts {
+		ids = append(ids, event.ID)
+	}
+
+	resp.Header().Set("X-Conflicting-Events", strings.Join(ids, ","))
+}
+
+func listingDate(date *openapitypes.Date, timeZone *string) (time.Time, error) {
+	var day *time.Time
+	if date != nil {
+		day = &date.Time
+	}
+
+	var zone string
+	if timeZone != nil {
+		zone = *timeZone
+	}
+
+	return app.ListingDate(day, zone)
+}
+
+func eventQuery(params api.ListEventsParams) (storage.EventQuery, error) {
+	var query storage.EventQuery
+	if params.Owner != nil {
+		query.Owner = *params.Owner
+	}
+
+	if params.Title != nil {
+		query.Title = *params.Title
+	}
+
+	if params.Description != nil {
+		query.Description = *params.Description
+	}
+
+	if params.From != nil {
+		query.From = *params.From
+	}
+
+	if params.To != nil {
+		query.To = *params.To
+	}
+
+	if params.Sort != nil {
+		switch *params.Sort {
+		case api.StartDate, api.Title:
+			query.Sort = storage.EventSort(*params.Sort)
+		default:
+			return storage.EventQuery{}, fmt.Errorf("sort must be %s or %s", api.StartDate, api.Title)
+		}
+	}
+
+	if params.Order != nil {
+		switch *params.Order {
+		case api.Asc:
+		case api.Desc:
+			query.Desc = true
+		default:
+			return storage.EventQuery{}, fmt.Errorf("order must be %s or %s", api.Asc, api.Desc)
+		}
+	}
+
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > app.MaxListLimit {
+			return storage.EventQuery{}, fmt.Errorf("limit must be between 1 and %d", app.MaxListLimit)
+		}
+
+		query.Limit = *params.Limit
+	}
+
+	if params.Cursor != nil && *params.Cursor != "" {
+		cursor, err := storage.DecodeCursor(*params.Cursor)
+		if err != nil {
+			return storage.EventQuery{}, err
+		}
+
+		query.Cursor = cursor
+	}
+
+	return query, nil
+}
+
+func freeBusyQuery(params api.GetFreeBusyParams) (app.FreeBusyQuery, error) {
+	query := app.FreeBusyQuery{
+		Owners:       params.Owners,
+		From:         params.From,
+		To:           params.To,
+		SlotDuration: 30 * time.Minute,
+		WorkdayEnd:   24 * 60,
+		Location:     time.UTC,
+	}
+
+	if params.SlotMinutes != nil {
+		query.SlotDuration = time.Duration(*params.SlotMinutes) * time.Minute
+	}
+
+	var err error
+	if params.WorkdayStart != nil {
+		if query.WorkdayStart, err = app.ParseClock(*params.WorkdayStart); err != nil {
+			return app.FreeBusyQuery{}, err
+		}
+	}
+
+	if params.WorkdayEnd != nil {
+		if query.WorkdayEnd, err = app.ParseClock(*params.WorkdayEnd); err != nil {
+			return app.FreeBusyQuery{}, err
+		}
+	}
+
+	if params.TimeZone != nil && *params.TimeZone != "" {
+		if query.Location, err = time.LoadLocation(*params.TimeZone); err != nil {
+			return app.FreeBusyQuery{}, fmt.Errorf("invalid time zone: %w", err)
+		}
+	}
+
+	return query, nil
 }
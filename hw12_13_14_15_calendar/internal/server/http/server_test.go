@@ -5,27 +5,44 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/api"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/health"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/identity"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/logger"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
 	memorystorage "github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zapcore"
 )
 
+// asUser serves the requests without an identity header on behalf of the user.
+func asUser(handler http.Handler, user string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(identity.DefaultHeader) == "" {
+			r.Header.Set(identity.DefaultHeader, user)
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
 //nolint:funlen
 func TestStorage(t *testing.T) {
 	var level zapcore.Level
 	logg, _ := logger.New(level, os.TempDir()+"/test.log")
+	authenticator := identity.New(configs.AuthConfig{})
 
 	testEvent := &storage.Event{
 		ID:          "test_id",
@@ -34,7 +51,7 @@ func TestStorage(t *testing.T) {
 		StartDate:   time.Now(),
 		Duration:    30,
 		Description: "test_description",
-		RemindAt:    100,
+		Reminders:   []storage.EventReminder{{Before: 100}},
 	}
 	testEventMarshal, _ := json.Marshal(&testEvent)
 
@@ -42,7 +59,7 @@ func TestStorage(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 
 		calendar := app.New(memorystorage.New())
-		server := NewServer(ctx, logg, calendar)
+		server := NewServer(ctx, logg, calendar, authenticator)
 		require.NotNil(t, server)
 
 		go func() {
@@ -58,10 +75,10 @@ func TestStorage(t *testing.T) {
 		defer cancel()
 
 		calendar := app.New(memorystorage.New())
-		server := NewServer(ctx, logg, calendar)
+		server := NewServer(ctx, logg, calendar, authenticator)
 		require.NotNil(t, server)
 
-		handler := api.HandlerFromMux(server, http.NewServeMux())
+		handler := asUser(server.Handler(), testEvent.Owner)
 		require.NotNil(t, handler)
 
 		ts := httptest.NewServer(handler)
@@ -85,7 +102,7 @@ func TestStorage(t *testing.T) {
 		require.Equal(t, testEvent.StartDate.UTC(), respEvent.StartDate)
 		require.Equal(t, testEvent.Duration, respEvent.Duration)
 		require.Equal(t, testEvent.Description, respEvent.Description)
-		require.Equal(t, testEvent.RemindAt, respEvent.RemindAt)
+		require.Equal(t, testEvent.Reminders, respEvent.Reminders)
 	})
 
 	t.Run("Update event", func(t *testing.T) {
@@ -93,10 +110,10 @@ func TestStorage(t *testing.T) {
 		defer cancel()
 
 		calendar := app.New(memorystorage.New())
-		server := NewServer(ctx, logg, calendar)
+		server := NewServer(ctx, logg, calendar, authenticator)
 		require.NotNil(t, server)
 
-		handler := api.HandlerFromMux(server, http.NewServeMux())
+		handler := asUser(server.Handler(), testEvent.Owner)
 		require.NotNil(t, handler)
 
 		ts := httptest.NewServer(handler)
@@ -110,15 +127,18 @@ func TestStorage(t *testing.T) {
 		updatedEvent := &storage.Event{
 			ID:          testEvent.ID,
 			Title:       "test_title2",
-			Owner:       "test_user2",
+			Owner:       testEvent.Owner,
 			StartDate:   updatedTime,
 			Duration:    32,
 			Description: "test_description2",
-			RemindAt:    120,
+			Reminders:   []storage.EventReminder{{Before: 120}},
 		}
 		updatedEventMarshal, _ := json.Marshal(&updatedEvent)
 
+		require.Equal(t, `"1"`, respCreate.Header().Get("ETag"))
+
 		reqUpdate := httptest.NewRequest("PUT", "/event", bytes.NewBuffer(updatedEventMarshal))
+		reqUpdate.Header.Set("If-Match", respCreate.Header().Get("ETag"))
 		respUpdate := httptest.NewRecorder()
 		handler.ServeHTTP(respUpdate, reqUpdate)
 
@@ -129,13 +149,309 @@ func TestStorage(t *testing.T) {
 		require.NoError(t, err)
 
 		require.Equal(t, respUpdate.Code, 200)
+		require.Equal(t, `"2"`, respUpdate.Header().Get("ETag"))
+		require.Equal(t, int64(2), respEvent.Version)
 		require.Equal(t, updatedEvent.ID, respEvent.ID)
 		require.Equal(t, updatedEvent.Title, respEvent.Title)
 		require.Equal(t, updatedEvent.Owner, respEvent.Owner)
 		require.Equal(t, updatedEvent.StartDate.UTC(), respEvent.StartDate)
 		require.Equal(t, updatedEvent.Duration, respEvent.Duration)
 		require.Equal(t, updatedEvent.Description, respEvent.Description)
-		require.Equal(t, updatedEvent.RemindAt, respEvent.RemindAt)
+		require.Equal(t, updatedEvent.Reminders, respEvent.Reminders)
+	})
+
+	t.Run("Get and delete event by id", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		calendar := app.New(memorystorage.New())
+		handler := NewServer(ctx, logg, calendar, authenticator).Handler()
+		serve := func(user, method, target string, body []byte) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, target, bytes.NewBuffer(body))
+			req.Header.Set(identity.DefaultHeader, user)
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+			return resp
+		}
+
+		event := *testEvent
+		event.Attendees = []storage.Attendee{{User: "bob"}}
+		eventMarshal, _ := json.Marshal(&event)
+		require.Equal(t, http.StatusOK, serve(event.Owner, "POST", "/event", eventMarshal).Code)
+
+		for _, user := range []string{event.Owner, "bob"} {
+			respGet := serve(user, "GET", "/event/"+event.ID, nil)
+			var fetched storage.Event
+			err := json.Unmarshal(respGet.Body.Bytes(), &fetched)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, respGet.Code, user)
+			require.Equal(t, `"1"`, respGet.Header().Get("ETag"))
+			require.Equal(t, event.ID, fetched.ID)
+			require.Equal(t, event.Title, fetched.Title)
+			require.Equal(t, event.StartDate.UTC(), fetched.StartDate)
+		}
+
+		require.Equal(t, http.StatusNotFound, serve("carol", "GET", "/event/"+event.ID, nil).Code,
+			"the events not shared with the caller are not revealed")
+		require.Equal(t, http.StatusNotFound, serve(event.Owner, "GET", "/event/missing_id", nil).Code)
+		require.Equal(t, http.StatusForbidden, serve("bob", "DELETE", "/event/"+event.ID, nil).Code)
+		require.Equal(t, http.StatusNotFound, serve(event.Owner, "DELETE", "/event/missing_id", nil).Code)
+
+		respDelete := serve(event.Owner, "DELETE", "/event/"+event.ID, nil)
+		require.Equal(t, http.StatusNoContent, respDelete.Code)
+		require.Empty(t, respDelete.Body.Bytes())
+		require.Equal(t, http.StatusNotFound, serve(event.Owner, "GET", "/event/"+event.ID, nil).Code)
+	})
+
+	t.Run("Trash", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		calendar := app.New(memorystorage.New())
+		handler := NewServer(ctx, logg, calendar, authenticator).Handler()
+		serve := func(user, method, target string, body []byte) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, target, bytes.NewBuffer(body))
+			req.Header.Set(identity.DefaultHeader, user)
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+			return resp
+		}
+		trash := func(user string) []storage.Event {
+			respTrash := serve(user, "GET", "/trash", nil)
+			require.Equal(t, http.StatusOK, respTrash.Code)
+
+			var events []storage.Event
+			err := json.Unmarshal(respTrash.Body.Bytes(), &events)
+			require.NoError(t, err)
+			return events
+		}
+
+		require.Equal(t, http.StatusOK, serve(testEvent.Owner, "POST", "/event", testEventMarshal).Code)
+		require.Empty(t, trash(testEvent.Owner))
+		require.Equal(t, http.StatusNoContent, serve(testEvent.Owner, "DELETE", "/event/"+testEvent.ID, nil).Code)
+
+		deleted := trash(testEvent.Owner)
+		require.Len(t, deleted, 1)
+		require.Equal(t, testEvent.ID, deleted[0].ID)
+		require.NotNil(t, deleted[0].DeletedAt)
+		require.Empty(t, trash("test_user2"))
+
+		respList := serve(testEvent.Owner, "GET", "/event", nil)
+		var page storage.EventPage
+		err := json.Unmarshal(respList.Body.Bytes(), &page)
+		require.NoError(t, err)
+		require.Empty(t, page.Events)
+		require.Equal(t, http.StatusNotFound, serve(testEvent.Owner, "GET", "/event/"+testEvent.ID, nil).Code)
+		require.Equal(t, http.StatusConflict, serve(testEvent.Owner, "POST", "/event", testEventMarshal).Code)
+
+		restorePath := "/trash/" + testEvent.ID + "/restore"
+		require.Equal(t, http.StatusNotFound, serve("test_user2", "POST", restorePath, nil).Code)
+
+		respRestore := serve(testEvent.Owner, "POST", restorePath, nil)
+		var restored storage.Event
+		err = json.Unmarshal(respRestore.Body.Bytes(), &restored)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, respRestore.Code)
+		require.Equal(t, `"3"`, respRestore.Header().Get("ETag"))
+		require.Nil(t, restored.DeletedAt)
+		require.Empty(t, trash(testEvent.Owner))
+		require.Equal(t, http.StatusNotFound, serve(testEvent.Owner, "POST", restorePath, nil).Code)
+		require.Equal(t, http.StatusOK, serve(testEvent.Owner, "GET", "/event/"+testEvent.ID, nil).Code)
+	})
+
+	t.Run("Event history", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		calendar := app.New(memorystorage.New())
+		handler := NewServer(ctx, logg, calendar, authenticator).Handler()
+		serve := func(user, method, target string, body []byte) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, target, bytes.NewBuffer(body))
+			req.Header.Set(identity.DefaultHeader, user)
+			req.Header.Set("If-Match", `"1"`)
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+			return resp
+		}
+
+		event := *testEvent
+		event.Attendees = []storage.Attendee{{User: "bob"}}
+		eventMarshal, _ := json.Marshal(&event)
+		require.Equal(t, http.StatusOK, serve(event.Owner, "POST", "/event", eventMarshal).Code)
+
+		updated := event
+		updated.Title = "test_title2"
+		updatedMarshal, _ := json.Marshal(&updated)
+		require.Equal(t, http.StatusOK, serve(event.Owner, "PUT", "/event", updatedMarshal).Code)
+		require.Equal(t, http.StatusOK,
+			serve("bob", "PUT", "/event/"+event.ID+"/rsvp", []byte(`{"status":"accepted"}`)).Code)
+		require.Equal(t, http.StatusNoContent, serve(event.Owner, "DELETE", "/event/"+event.ID, nil).Code)
+
+		history := func() []storage.AuditEntry {
+			respHistory := serve(event.Owner, "GET", "/event/"+event.ID+"/history", nil)
+			require.Equal(t, http.StatusOK, respHistory.Code)
+
+			var entries []storage.AuditEntry
+			err := json.Unmarshal(respHistory.Body.Bytes(), &entries)
+			require.NoError(t, err)
+			return entries
+		}
+
+		entries := history()
+		require.Len(t, entries, 4)
+		for i, action := range []storage.AuditAction{
+			storage.AuditCreate, storage.AuditUpdate, storage.AuditRespond, storage.AuditDelete,
+		} {
+			require.Equal(t, action, entries[i].Action)
+			require.Equal(t, event.ID, entries[i].EventID)
+			require.False(t, entries[i].Time.IsZero())
+		}
+
+		require.Equal(t, event.Owner, entries[0].Actor)
+		require.Contains(t, entries[0].Changes, storage.AuditChange{Field: "title", After: []byte(`"test_title"`)})
+		require.Equal(t, []storage.AuditChange{
+			{Field: "title", Before: []byte(`"test_title"`), After: []byte(`"test_title2"`)},
+		}, entries[1].Changes)
+		require.Equal(t, "bob", entries[2].Actor)
+		require.Equal(t, []storage.AuditChange{{
+			Field:  "attendees",
+			Before: []byte(`[{"user":"bob","status":"needs-action"}]`),
+			After:  []byte(`[{"user":"bob","status":"accepted"}]`),
+		}}, entries[2].Changes)
+		require.Equal(t, "deletedAt", entries[3].Changes[0].Field)
+		require.Empty(t, entries[3].Changes[0].Before)
+
+		require.Equal(t, http.StatusOK, serve(event.Owner, "POST", "/trash/"+event.ID+"/restore", nil).Code)
+		entries = history()
+		require.Len(t, entries, 5)
+		require.Equal(t, storage.AuditRestore, entries[4].Action)
+
+		require.Equal(t, http.StatusForbidden, serve("bob", "GET", "/event/"+event.ID+"/history", nil).Code)
+		require.Equal(t, http.StatusNotFound, serve(event.Owner, "GET", "/event/missing_id/history", nil).Code)
+	})
+
+	t.Run("Patch event", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := memorystorage.New()
+		handler := asUser(NewServer(ctx, logg, app.New(memory), authenticator).Handler(), testEvent.Owner)
+		patch := func(id, ifMatch, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("PATCH", "/event/"+id, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			if ifMatch != "" {
+				req.Header.Set("If-Match", ifMatch)
+			}
+
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+			return resp
+		}
+		stored := func() storage.Event {
+			events, err := memory.GetEvents(ctx)
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			return events[0]
+		}
+
+		respCreate := httptest.NewRecorder()
+		handler.ServeHTTP(respCreate, httptest.NewRequest("POST", "/event", bytes.NewBuffer(testEventMarshal)))
+		require.Equal(t, http.StatusOK, respCreate.Code)
+
+		sent := stored()
+		sent.Reminders = []storage.EventReminder{{Before: 100, IsSend: true}}
+		err := memory.UpdateEventWithOutbox(ctx, &sent, nil)
+		require.NoError(t, err)
+
+		respPatch := patch(testEvent.ID, `"2"`, `{"title":"test_title2","description":null,"duration":45}`)
+		var patched storage.Event
+		err = json.Unmarshal(respPatch.Body.Bytes(), &patched)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, respPatch.Code)
+		require.Equal(t, `"3"`, respPatch.Header().Get("ETag"))
+		require.Equal(t, "test_title2", patched.Title)
+		require.Empty(t, patched.Description)
+		require.Equal(t, time.Duration(45), patched.Duration)
+		require.Equal(t, testEvent.StartDate.UTC(), patched.StartDate)
+		require.Equal(t, []storage.EventReminder{{Before: 100, IsSend: true}}, stored().Reminders)
+
+		respPatch = patch(testEvent.ID, `"3"`, `{"reminders":[{"before":100,"isSend":false},{"before":15}]}`)
+		require.Equal(t, http.StatusOK, respPatch.Code)
+		require.Equal(t, []storage.EventReminder{{Before: 100, IsSend: true}, {Before: 15}}, stored().Reminders)
+
+		for name, tc := range map[string]struct {
+			id      string
+			ifMatch string
+			body    string
+			code    int
+		}{
+			"malformed patch":      {id: testEvent.ID, ifMatch: `"4"`, body: `{`, code: http.StatusBadRequest},
+			"removed title":        {id: testEvent.ID, ifMatch: `"4"`, body: `{"title":null}`, code: 422},
+			"mistyped duration":    {id: testEvent.ID, ifMatch: `"4"`, body: `{"duration":"long"}`, code: 422},
+			"reminder after start": {id: testEvent.ID, ifMatch: `"4"`, body: `{"reminders":[{"before":-5}]}`, code: 422},
+			"transferred event":    {id: testEvent.ID, ifMatch: `"4"`, body: `{"owner":"test_user2"}`, code: 403},
+			"missing event":        {id: "missing_id", ifMatch: `"1"`, body: `{}`, code: http.StatusNotFound},
+			"stale version":        {id: testEvent.ID, ifMatch: `"3"`, body: `{}`, code: http.StatusPreconditionFailed},
+			"unconditional update": {id: testEvent.ID, body: `{}`, code: http.StatusPreconditionRequired},
+		} {
+			require.Equal(t, tc.code, patch(tc.id, tc.ifMatch, tc.body).Code, name)
+		}
+
+		require.Equal(t, "test_title2", stored().Title)
+		require.Equal(t, int64(4), stored().Version)
+	})
+
+	t.Run("Recurring event reminders", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := memorystorage.New()
+		handler := asUser(NewServer(ctx, logg, app.New(memory), authenticator).Handler(), testEvent.Owner)
+		startDate := time.Now().UTC().Truncate(time.Second).AddDate(-1, 0, 0)
+		daily := storage.Event{
+			ID:        "daily_id",
+			Title:     "daily",
+			StartDate: startDate,
+			Duration:  30,
+			RRule:     "FREQ=DAILY",
+			Reminders: []storage.EventReminder{{Before: 15}, {At: &startDate}},
+		}
+		save := func(method, path string) storage.Event {
+			body, err := json.Marshal(&daily)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(method, path, bytes.NewBuffer(body))
+			if daily.Version != 0 {
+				req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, daily.Version))
+			}
+
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code)
+
+			stored, err := memory.GetEvent(ctx, daily.ID)
+			require.NoError(t, err)
+			return stored
+		}
+		lastStarted := func(event storage.Event) time.Time {
+			started, err := event.PreviousOccurrence(time.Now())
+			require.NoError(t, err)
+			return started
+		}
+
+		stored := save("POST", "/event")
+		require.True(t, stored.Reminders[0].SentUntil.After(time.Now().Add(-24*time.Hour)))
+		require.Equal(t, lastStarted(stored), stored.Reminders[0].SentUntil)
+		require.True(t, stored.Reminders[1].SentUntil.IsZero())
+
+		due, err := stored.ReminderDue(&stored.Reminders[0])
+		require.NoError(t, err)
+		require.True(t, due.After(time.Now().Add(-15*time.Minute)))
+
+		daily.Version = stored.Version
+		daily.StartDate = startDate.Add(-time.Hour)
+		stored = save("PUT", "/event")
+		require.Equal(t, lastStarted(stored), stored.Reminders[0].SentUntil)
 	})
 
 	t.Run("Delete event", func(t *testing.T) {
@@ -143,10 +459,10 @@ func TestStorage(t *testing.T) {
 		defer cancel()
 
 		calendar := app.New(memorystorage.New())
-		server := NewServer(ctx, logg, calendar)
+		server := NewServer(ctx, logg, calendar, authenticator)
 		require.NotNil(t, server)
 
-		handler := api.HandlerFromMux(server, http.NewServeMux())
+		handler := asUser(server.Handler(), testEvent.Owner)
 		require.NotNil(t, handler)
 
 		ts := httptest.NewServer(handler)
@@ -173,10 +489,10 @@ func TestStorage(t *testing.T) {
 		defer cancel()
 
 		calendar := app.New(memorystorage.New())
-		server := NewServer(ctx, logg, calendar)
+		server := NewServer(ctx, logg, calendar, authenticator)
 		require.NotNil(t, server)
 
-		handler := api.HandlerFromMux(server, http.NewServeMux())
+		handler := asUser(server.Handler(), testEvent.Owner)
 		require.NotNil(t, handler)
 
 		ts := httptest.NewServer(handler)
@@ -205,10 +521,10 @@ func TestStorage(t *testing.T) {
 		defer cancel()
 
 		calendar := app.New(memorystorage.New())
-		server := NewServer(ctx, logg, calendar)
+		server := NewServer(ctx, logg, calendar, authenticator)
 		require.NotNil(t, server)
 
-		handler := api.HandlerFromMux(server, http.NewServeMux())
+		handler := asUser(server.Handler(), testEvent.Owner)
 		require.NotNil(t, handler)
 
 		ts := httptest.NewServer(handler)
@@ -237,10 +553,10 @@ func TestStorage(t *testing.T) {
 		defer cancel()
 
 		calendar := app.New(memorystorage.New())
-		server := NewServer(ctx, logg, calendar)
+		server := NewServer(ctx, logg, calendar, authenticator)
 		require.NotNil(t, server)
 
-		handler := api.HandlerFromMux(server, http.NewServeMux())
+		handler := asUser(server.Handler(), testEvent.Owner)
 		require.NotNil(t, handler)
 
 		ts := httptest.NewServer(handler)
@@ -263,4 +579,676 @@ func TestStorage(t *testing.T) {
 		require.Equal(t, respGet.Code, 200)
 		require.Equal(t, len(respEvents), 1)
 	})
+
+	t.Run("Get events by date", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		calendar := app.New(memorystorage.New())
+		server := NewServer(ctx, logg, calendar, authenticator)
+		require.NotNil(t, server)
+
+		handler := asUser(server.Handler(), testEvent.Owner)
+		require.NotNil(t, handler)
+
+		datedEvent := *testEvent
+		datedEvent.StartDate = time.Date(2024, time.March, 13, 23, 30, 0, 0, time.UTC)
+		datedEventMarshal, _ := json.Marshal(&datedEvent)
+
+		reqCreate := httptest.NewRequest("POST", "/event", bytes.NewBuffer(datedEventMarshal))
+		respCreate := httptest.NewRecorder()
+		handler.ServeHTTP(respCreate, reqCreate)
+		require.Equal(t, respCreate.Code, 200)
+
+		tests := []struct {
+			url   string
+			count int
+		}{
+			{url: "/event/test_user/getDay?date=2024-03-13", count: 1},
+			{url: "/event/test_user/getDay?date=2024-03-14", count: 0},
+			{url: "/event/test_user/getDay?date=2024-03-14&timeZone=Europe/Moscow", count: 1},
+			{url: "/event/test_user/getWeek?date=2024-03-11", count: 1},
+			{url: "/event/test_user/getWeek?date=2024-03-17", count: 1},
+			{url: "/event/test_user/getWeek?date=2024-03-18", count: 0},
+			{url: "/event/test_user/getWeek?date=2024-03-10", count: 0},
+			{url: "/event/test_user/getMonth?date=2024-03-31", count: 1},
+			{url: "/event/test_user/getMonth?date=2024-04-01", count: 0},
+		}
+
+		for _, tc := range tests {
+			reqGet := httptest.NewRequest("GET", tc.url, bytes.NewBuffer(nil))
+			respGet := httptest.NewRecorder()
+			handler.ServeHTTP(respGet, reqGet)
+
+			respBody, _ := io.ReadAll(respGet.Body)
+			var respEvents []*storage.Event
+
+			err := json.Unmarshal(respBody, &respEvents)
+			require.NoError(t, err, tc.url)
+
+			require.Equal(t, respGet.Code, 200, tc.url)
+			require.Equal(t, len(respEvents), tc.count, tc.url)
+		}
+
+		reqGet := httptest.NewRequest("GET", "/event/test_user/getDay?timeZone=Mars/Olympus", bytes.NewBuffer(nil))
+		respGet := httptest.NewRecorder()
+		handler.ServeHTTP(respGet, reqGet)
+		require.Equal(t, respGet.Code, 400)
+	})
+
+	t.Run("Export and import events", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		calendar := app.New(memorystorage.New())
+		server := NewServer(ctx, logg, calendar, authenticator)
+		require.NotNil(t, server)
+
+		handler := asUser(server.Handler(), testEvent.Owner)
+		require.NotNil(t, handler)
+
+		exportedEvent := *testEvent
+		exportedEvent.ID = "0193c6c8-7b1e-7cde-8d7c-3b1f8f6b2a10"
+		exportedEvent.StartDate = time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)
+		exportedEvent.Duration = time.Hour
+		exportedEvent.RRule = "FREQ=WEEKLY;BYDAY=MO;COUNT=3"
+		exportedEventMarshal, _ := json.Marshal(&exportedEvent)
+
+		reqCreate := httptest.NewRequest("POST", "/event", bytes.NewBuffer(exportedEventMarshal))
+		respCreate := httptest.NewRecorder()
+		handler.ServeHTTP(respCreate, reqCreate)
+		require.Equal(t, respCreate.Code, 200)
+
+		laterEvent := *testEvent
+		laterEvent.ID = "0193c6c8-7b1e-7cde-8d7c-3b1f8f6b2a11"
+		laterEvent.StartDate = time.Date(2024, time.May, 6, 10, 0, 0, 0, time.UTC)
+		laterEventMarshal, _ := json.Marshal(&laterEvent)
+		respCreate = httptest.NewRecorder()
+		handler.ServeHTTP(respCreate, httptest.NewRequest("POST", "/event", bytes.NewBuffer(laterEventMarshal)))
+		require.Equal(t, respCreate.Code, 200)
+
+		reqExport := httptest.NewRequest("GET",
+			"/event/test_user/export?from=2024-03-01T00:00:00Z&to=2024-04-01T00:00:00Z", bytes.NewBuffer(nil))
+		respExport := httptest.NewRecorder()
+		handler.ServeHTTP(respExport, reqExport)
+
+		exported, _ := io.ReadAll(respExport.Body)
+		require.Equal(t, respExport.Code, 200)
+		require.Contains(t, respExport.Header().Get("Content-Type"), "text/calendar")
+		require.Contains(t, string(exported), "BEGIN:VCALENDAR")
+		require.Contains(t, string(exported), "UID:"+exportedEvent.ID)
+		require.Contains(t, string(exported), "RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=3")
+		require.NotContains(t, string(exported), "UID:"+laterEvent.ID)
+
+		imported := strings.Replace(string(exported), "END:VCALENDAR",
+			"BEGIN:VEVENT\r\nUID:broken\r\nSUMMARY:no start\r\nEND:VEVENT\r\n"+
+				"BEGIN:VEVENT\r\nUID:reversed\r\nSUMMARY:ends before start\r\n"+
+				"DTSTART:20240304T100000Z\r\nDTEND:20240304T090000Z\r\nEND:VEVENT\r\nEND:VCALENDAR", 1)
+
+		importCalendar := app.New(memorystorage.New())
+		importHandler := asUser(NewServer(ctx, logg, importCalendar, authenticator).Handler(), "test_user2")
+
+		reqImport := httptest.NewRequest("POST", "/event/test_user2/import", strings.NewReader(imported))
+		respImport := httptest.NewRecorder()
+		importHandler.ServeHTTP(respImport, reqImport)
+
+		respBody, _ := io.ReadAll(respImport.Body)
+		var importResult app.ImportResult
+		err := json.Unmarshal(respBody, &importResult)
+		require.NoError(t, err)
+
+		require.Equal(t, respImport.Code, 200)
+		require.Equal(t, []app.ImportedEvent{{UID: exportedEvent.ID, ID: exportedEvent.ID}}, importResult.Imported)
+		require.Len(t, importResult.Errors, 2)
+		require.Equal(t, 1, importResult.Errors[0].Index)
+		require.Equal(t, "broken", importResult.Errors[0].UID)
+		require.Equal(t, "reversed", importResult.Errors[1].UID)
+		require.Equal(t, "duration must be positive", importResult.Errors[1].Error)
+
+		respImport = httptest.NewRecorder()
+		importHandler.ServeHTTP(respImport, httptest.NewRequest("POST", "/event/test_user2/import",
+			strings.NewReader(imported+strings.Repeat(" ", MaxImportSize))))
+		require.Equal(t, http.StatusRequestEntityTooLarge, respImport.Code)
+
+		importCtx := identity.WithCaller(ctx, "test_user2")
+		events, err := importCalendar.GetEventsMonth(importCtx, "test_user2", exportedEvent.StartDate)
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+		require.Equal(t, exportedEvent.Title, events[0].Title)
+		require.Equal(t, exportedEvent.Duration, events[0].Duration)
+	})
+
+	t.Run("List events", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		calendar := app.New(memorystorage.New())
+		server := NewServer(ctx, logg, calendar, authenticator)
+		require.NotNil(t, server)
+
+		handler := asUser(server.Handler(), testEvent.Owner)
+		require.NotNil(t, handler)
+
+		for i := 0; i < 3; i++ {
+			listedEvent := *testEvent
+			listedEvent.ID = fmt.Sprintf("test_id_%d", i)
+			listedEvent.StartDate = testEvent.StartDate.Add(time.Duration(i) * time.Hour)
+			listedEventMarshal, _ := json.Marshal(&listedEvent)
+
+			reqCreate := httptest.NewRequest("POST", "/event", bytes.NewBuffer(listedEventMarshal))
+			respCreate := httptest.NewRecorder()
+			handler.ServeHTTP(respCreate, reqCreate)
+			require.Equal(t, respCreate.Code, 200)
+		}
+
+		reqList := httptest.NewRequest("GET", "/event?owner=test_user&order=desc&limit=2", bytes.NewBuffer(nil))
+		respList := httptest.NewRecorder()
+		handler.ServeHTTP(respList, reqList)
+
+		respBody, _ := io.ReadAll(respList.Body)
+		var page storage.EventPage
+		err := json.Unmarshal(respBody, &page)
+		require.NoError(t, err)
+
+		require.Equal(t, respList.Code, 200)
+		require.Len(t, page.Events, 2)
+		require.Equal(t, "test_id_2", page.Events[0].ID)
+		require.Equal(t, "test_id_1", page.Events[1].ID)
+		require.NotEmpty(t, page.NextCursor)
+
+		reqList = httptest.NewRequest("GET",
+			"/event?owner=test_user&order=desc&limit=2&cursor="+page.NextCursor, bytes.NewBuffer(nil))
+		respList = httptest.NewRecorder()
+		handler.ServeHTTP(respList, reqList)
+
+		respBody, _ = io.ReadAll(respList.Body)
+		page = storage.EventPage{}
+		err = json.Unmarshal(respBody, &page)
+		require.NoError(t, err)
+
+		require.Equal(t, respList.Code, 200)
+		require.Len(t, page.Events, 1)
+		require.Equal(t, "test_id_0", page.Events[0].ID)
+		require.Empty(t, page.NextCursor)
+
+		for _, url := range []string{"/event?limit=0", "/event?limit=501", "/event?sort=owner", "/event?cursor=broken"} {
+			reqList = httptest.NewRequest("GET", url, bytes.NewBuffer(nil))
+			respList = httptest.NewRecorder()
+			handler.ServeHTTP(respList, reqList)
+			require.Equal(t, respList.Code, 400, url)
+		}
+	})
+
+	t.Run("Event conflicts", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		startDate := time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)
+		weekly := storage.Event{
+			ID:        "weekly_id",
+			Title:     "weekly",
+			Owner:     "test_user",
+			StartDate: startDate,
+			Duration:  time.Hour,
+			RRule:     "FREQ=WEEKLY;COUNT=4",
+		}
+		overlapping := storage.Event{
+			ID:        "overlapping_id",
+			Title:     "overlapping",
+			Owner:     "test_user",
+			StartDate: startDate.AddDate(0, 0, 14).Add(30 * time.Minute),
+			Duration:  time.Hour,
+		}
+		adjacent := overlapping
+		adjacent.ID = "adjacent_id"
+		adjacent.StartDate = startDate.AddDate(0, 0, 14).Add(time.Hour)
+
+		for _, policy := range []app.ConflictPolicy{app.ConflictPolicyWarn, app.ConflictPolicyReject} {
+			calendar := app.New(memorystorage.New(), app.WithConflictPolicy(policy))
+			handler := asUser(NewServer(ctx, logg, calendar, authenticator).Handler(), testEvent.Owner)
+
+			for _, event := range []storage.Event{weekly, adjacent, overlapping} {
+				eventMarshal, _ := json.Marshal(&event)
+				reqCreate := httptest.NewRequest("POST", "/event", bytes.NewBuffer(eventMarshal))
+				respCreate := httptest.NewRecorder()
+				handler.ServeHTTP(respCreate, reqCreate)
+
+				if event.ID != overlapping.ID {
+					require.Equal(t, 200, respCreate.Code, event.ID)
+					require.Empty(t, respCreate.Header().Get("X-Conflicting-Events"), event.ID)
+					continue
+				}
+
+				if policy == app.ConflictPolicyWarn {
+					require.Equal(t, 200, respCreate.Code)
+					require.Equal(t, "weekly_id,adjacent_id", respCreate.Header().Get("X-Conflicting-Events"))
+					continue
+				}
+
+				respBody, _ := io.ReadAll(respCreate.Body)
+				var conflict problem
+				err := json.Unmarshal(respBody, &conflict)
+				require.NoError(t, err)
+
+				require.Equal(t, http.StatusConflict, respCreate.Code)
+				require.Equal(t, problemContentType, respCreate.Header().Get("Content-Type"))
+				require.Equal(t, http.StatusConflict, conflict.Status)
+				require.Len(t, conflict.Conflicts, 2)
+				require.Equal(t, "weekly_id", conflict.Conflicts[0].ID)
+				require.Equal(t, "adjacent_id", conflict.Conflicts[1].ID)
+			}
+
+			updated := weekly
+			updated.Title = "weekly updated"
+			updatedMarshal, _ := json.Marshal(&updated)
+			reqUpdate := httptest.NewRequest("PUT", "/event", bytes.NewBuffer(updatedMarshal))
+			reqUpdate.Header.Set("If-Match", `"1"`)
+			respUpdate := httptest.NewRecorder()
+			handler.ServeHTTP(respUpdate, reqUpdate)
+
+			if policy == app.ConflictPolicyWarn {
+				require.Equal(t, 200, respUpdate.Code)
+				require.Equal(t, "overlapping_id", respUpdate.Header().Get("X-Conflicting-Events"))
+			} else {
+				require.Equal(t, 200, respUpdate.Code)
+				require.Empty(t, respUpdate.Header().Get("X-Conflicting-Events"))
+			}
+		}
+	})
+
+	t.Run("Free busy", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		calendar := app.New(memorystorage.New())
+		handler := asUser(NewServer(ctx, logg, calendar, authenticator).Handler(), testEvent.Owner)
+
+		day := time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)
+		for _, event := range []storage.Event{
+			{Title: "night", Owner: "alice", StartDate: day.Add(-4 * time.Hour), Duration: 10*time.Hour + 15*time.Minute},
+			{
+				Title:     "sync",
+				Owner:     "alice",
+				StartDate: day.Add(7 * time.Hour),
+				Duration:  time.Hour,
+				Attendees: []storage.Attendee{{User: "bob"}},
+			},
+			{Title: "review", Owner: "bob", StartDate: day.Add(7*time.Hour + 30*time.Minute), Duration: 90 * time.Minute},
+			{
+				Title:     "lunch",
+				Owner:     "bob",
+				StartDate: day.AddDate(0, 0, -1).Add(10 * time.Hour),
+				Duration:  45 * time.Minute,
+				RRule:     "FREQ=DAILY",
+			},
+			{
+				ID:        "0193c6c8-7b1e-7cde-8d7c-3b1f8f6b2a20",
+				Title:     "invitation",
+				Owner:     "carol",
+				StartDate: day.Add(12 * time.Hour),
+				Duration:  time.Hour,
+				Attendees: []storage.Attendee{{User: "bob"}},
+			},
+			{Title: "other", Owner: "carol", StartDate: day.Add(16 * time.Hour), Duration: time.Hour},
+		} {
+			eventMarshal, _ := json.Marshal(&event)
+			reqCreate := httptest.NewRequest("POST", "/event", bytes.NewBuffer(eventMarshal))
+			reqCreate.Header.Set(identity.DefaultHeader, event.Owner)
+			respCreate := httptest.NewRecorder()
+			handler.ServeHTTP(respCreate, reqCreate)
+			require.Equal(t, 200, respCreate.Code, event.Title)
+		}
+
+		reqRSVP := httptest.NewRequest("PUT", "/event/0193c6c8-7b1e-7cde-8d7c-3b1f8f6b2a20/rsvp",
+			strings.NewReader(`{"status":"accepted"}`))
+		reqRSVP.Header.Set(identity.DefaultHeader, "bob")
+		respRSVP := httptest.NewRecorder()
+		handler.ServeHTTP(respRSVP, reqRSVP)
+		require.Equal(t, 200, respRSVP.Code)
+
+		reqFreeBusy := httptest.NewRequest("GET", "/freebusy?owners=alice&owners=bob"+
+			"&from=2024-03-04T00:00:00%2B03:00&to=2024-03-05T00:00:00%2B03:00"+
+			"&slotMinutes=60&workdayStart=09:00&workdayEnd=18:00&timeZone=Europe/Moscow", nil)
+		reqFreeBusy.Header.Set(identity.DefaultHeader, "alice")
+		respFreeBusy := httptest.NewRecorder()
+		handler.ServeHTTP(respFreeBusy, reqFreeBusy)
+
+		respBody, _ := io.ReadAll(respFreeBusy.Body)
+		var freeBusy app.FreeBusy
+		err := json.Unmarshal(respBody, &freeBusy)
+		require.NoError(t, err)
+
+		require.Equal(t, 200, respFreeBusy.Code)
+		require.Equal(t, []app.Interval{
+			{Start: day.Add(-3 * time.Hour), End: day.Add(6*time.Hour + 15*time.Minute)},
+			{Start: day.Add(7 * time.Hour), End: day.Add(9 * time.Hour)},
+			{Start: day.Add(10 * time.Hour), End: day.Add(10*time.Hour + 45*time.Minute)},
+			{Start: day.Add(12 * time.Hour), End: day.Add(13 * time.Hour)},
+		}, freeBusy.Busy)
+		require.Equal(t, []app.Interval{
+			{Start: day.Add(9 * time.Hour), End: day.Add(10 * time.Hour)},
+			{Start: day.Add(10*time.Hour + 45*time.Minute), End: day.Add(12 * time.Hour)},
+			{Start: day.Add(13 * time.Hour), End: day.Add(15 * time.Hour)},
+		}, freeBusy.Slots)
+
+		for query, code := range map[string]int{
+			"owners=alice&from=2024-03-04T00:00:00Z&to=2024-03-05T00:00:00Z&workdayStart=25:00":                  400,
+			"owners=alice&from=2024-03-04T00:00:00Z&to=2024-03-05T00:00:00Z&timeZone=Mars/Olympus":               400,
+			"owners=alice&from=2024-03-04T00:00:00Z&to=2024-03-05T00:00:00Z&workdayStart=18:00&workdayEnd=09:00": 422,
+			"owners=alice&from=2024-03-04T00:00:00Z&to=2024-04-14T00:00:00Z":                                     422,
+			"owners=alice&owners=carol&from=2024-03-04T00:00:00Z&to=2024-03-05T00:00:00Z":                        403,
+		} {
+			reqFreeBusy = httptest.NewRequest("GET", "/freebusy?"+query, nil)
+			reqFreeBusy.Header.Set(identity.DefaultHeader, "alice")
+			respFreeBusy = httptest.NewRecorder()
+			handler.ServeHTTP(respFreeBusy, reqFreeBusy)
+			require.Equal(t, code, respFreeBusy.Code, query)
+		}
+	})
+
+	t.Run("Error responses", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		calendar := app.New(memorystorage.New())
+		handler := asUser(NewServer(ctx, logg, calendar, authenticator).Handler(), testEvent.Owner)
+
+		respCreate := httptest.NewRecorder()
+		handler.ServeHTTP(respCreate, httptest.NewRequest("POST", "/event", bytes.NewBuffer(testEventMarshal)))
+		require.Equal(t, 200, respCreate.Code)
+
+		invalidEvent := *testEvent
+		invalidEvent.ID = "invalid_id"
+		invalidEvent.Title = ""
+		invalidMarshal, _ := json.Marshal(&invalidEvent)
+		missingEvent := *testEvent
+		missingEvent.ID = "missing_id"
+		missingMarshal, _ := json.Marshal(&missingEvent)
+		countlessEvent := *testEvent
+		countlessEvent.ID = "countless_id"
+		countlessEvent.RRule = "FREQ=DAILY;COUNT=100000000"
+		countlessMarshal, _ := json.Marshal(&countlessEvent)
+		untillessEvent := *testEvent
+		untillessEvent.ID = "untilless_id"
+		untillessEvent.RRule = "FREQ=DAILY;UNTIL=99991231T000000Z"
+		untillessMarshal, _ := json.Marshal(&untillessEvent)
+
+		tests := []struct {
+			name    string
+			method  string
+			target  string
+			body    []byte
+			ifMatch string
+			code    int
+			param   string
+		}{
+			{name: "malformed body", method: "POST", target: "/event", body: []byte("{"), code: 400},
+			{name: "invalid event", method: "POST", target: "/event", body: invalidMarshal, code: 422, param: "title"},
+			{name: "existing event", method: "POST", target: "/event", body: testEventMarshal, code: 409},
+			{name: "oversized count", method: "POST", target: "/event", body: countlessMarshal, code: 422, param: "rrule"},
+			{name: "oversized until", method: "POST", target: "/event", body: untillessMarshal, code: 422, param: "rrule"},
+			{name: "invalid update", method: "PUT", target: "/event", body: invalidMarshal, ifMatch: `"1"`, code: 422,
+				param: "title"},
+			{name: "missing update", method: "PUT", target: "/event", body: missingMarshal, ifMatch: `"1"`, code: 404},
+			{name: "stale update", method: "PUT", target: "/event", body: testEventMarshal, ifMatch: `"2"`, code: 412},
+			{name: "malformed if-match", method: "PUT", target: "/event", body: testEventMarshal, ifMatch: "1", code: 400},
+			{name: "unconditional update", method: "PUT", target: "/event", body: testEventMarshal, code: 428},
+			{name: "missing delete", method: "DELETE", target: "/event", body: missingMarshal, code: 404},
+			{name: "invalid limit", method: "GET", target: "/event?limit=1000", code: 400},
+			{name: "invalid period", method: "GET", target: "/event?from=2024-03-05T00:00:00Z&to=2024-03-04T00:00:00Z",
+				code: 422, param: "from"},
+			{name: "invalid time zone", method: "GET", target: "/event/test_user/getDay?timeZone=Mars/Olympus", code: 400},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				req := httptest.NewRequest(tc.method, tc.target, bytes.NewBuffer(tc.body))
+				if tc.ifMatch != "" {
+					req.Header.Set("If-Match", tc.ifMatch)
+				}
+
+				resp := httptest.NewRecorder()
+				handler.ServeHTTP(resp, req)
+
+				var body problem
+				err := json.Unmarshal(resp.Body.Bytes(), &body)
+				require.NoError(t, err)
+
+				require.Equal(t, tc.code, resp.Code)
+				require.Equal(t, problemContentType, resp.Header().Get("Content-Type"))
+				require.Equal(t, "about:blank", body.Type)
+				require.Equal(t, http.StatusText(tc.code), body.Title)
+				require.Equal(t, tc.code, body.Status)
+				require.NotEmpty(t, body.Detail)
+				if tc.param == "" {
+					require.Empty(t, body.InvalidParams)
+					return
+				}
+
+				require.Equal(t, []invalidParam{{Name: tc.param, Reason: body.Detail}}, body.InvalidParams)
+			})
+		}
+	})
+
+	t.Run("Access control", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		calendar := app.New(memorystorage.New())
+		handler := NewServer(ctx, logg, calendar, authenticator).Handler()
+
+		reqCreate := httptest.NewRequest("POST", "/event", bytes.NewBuffer(testEventMarshal))
+		respCreate := httptest.NewRecorder()
+		handler.ServeHTTP(respCreate, reqCreate)
+		require.Equal(t, http.StatusUnauthorized, respCreate.Code)
+
+		reqCreate = httptest.NewRequest("POST", "/event", bytes.NewBuffer(testEventMarshal))
+		reqCreate.Header.Set(identity.DefaultHeader, testEvent.Owner)
+		respCreate = httptest.NewRecorder()
+		handler.ServeHTTP(respCreate, reqCreate)
+		require.Equal(t, http.StatusOK, respCreate.Code)
+
+		transferred := *testEvent
+		transferred.Owner = "test_user2"
+		transferredMarshal, _ := json.Marshal(&transferred)
+
+		tests := []struct {
+			method string
+			target string
+			body   []byte
+			code   int
+		}{
+			{method: "PUT", target: "/event", body: testEventMarshal, code: http.StatusForbidden},
+			{method: "DELETE", target: "/event", body: testEventMarshal, code: http.StatusNotFound},
+			{method: "GET", target: "/event/" + testEvent.ID, code: http.StatusNotFound},
+			{method: "GET", target: "/event?owner=test_user", code: http.StatusForbidden},
+			{method: "GET", target: "/event/test_user/getDay", code: http.StatusForbidden},
+			{
+				method: "GET",
+				target: "/event/test_user/export?from=2024-03-01T00:00:00Z&to=2024-04-01T00:00:00Z",
+				code:   http.StatusForbidden,
+			},
+		}
+
+		for _, tc := range tests {
+			req := httptest.NewRequest(tc.method, tc.target, bytes.NewBuffer(tc.body))
+			req.Header.Set(identity.DefaultHeader, "test_user2")
+			req.Header.Set("If-Match", `"1"`)
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+			require.Equal(t, tc.code, resp.Code, tc.method+" "+tc.target)
+		}
+
+		reqUpdate := httptest.NewRequest("PUT", "/event", bytes.NewBuffer(transferredMarshal))
+		reqUpdate.Header.Set(identity.DefaultHeader, testEvent.Owner)
+		reqUpdate.Header.Set("If-Match", `"1"`)
+		respUpdate := httptest.NewRecorder()
+		handler.ServeHTTP(respUpdate, reqUpdate)
+		require.Equal(t, http.StatusForbidden, respUpdate.Code)
+
+		reqList := httptest.NewRequest("GET", "/event", nil)
+		reqList.Header.Set(identity.DefaultHeader, "test_user2")
+		respList := httptest.NewRecorder()
+		handler.ServeHTTP(respList, reqList)
+
+		var page storage.EventPage
+		err := json.Unmarshal(respList.Body.Bytes(), &page)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, respList.Code)
+		require.Empty(t, page.Events)
+
+		jwtHandler := NewServer(ctx, logg, calendar, identity.New(configs.AuthConfig{JWTKey: "secret"})).Handler()
+		reqList = httptest.NewRequest("GET", "/event", nil)
+		reqList.Header.Set(identity.DefaultHeader, testEvent.Owner)
+		respList = httptest.NewRecorder()
+		jwtHandler.ServeHTTP(respList, reqList)
+		require.Equal(t, http.StatusUnauthorized, respList.Code)
+		require.Equal(t, "Bearer", respList.Header().Get("WWW-Authenticate"))
+
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+			Subject:   testEvent.Owner,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}).SignedString([]byte("secret"))
+		require.NoError(t, err)
+
+		reqList = httptest.NewRequest("GET", "/event", nil)
+		reqList.Header.Set(identity.AuthorizationHeader, "Bearer "+token)
+		respList = httptest.NewRecorder()
+		jwtHandler.ServeHTTP(respList, reqList)
+
+		err = json.Unmarshal(respList.Body.Bytes(), &page)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, respList.Code)
+		require.Len(t, page.Events, 1)
+	})
+
+	t.Run("Attendees", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		calendar := app.New(memorystorage.New())
+		handler := NewServer(ctx, logg, calendar, authenticator).Handler()
+		serve := func(user, method, target string, body []byte) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, target, bytes.NewBuffer(body))
+			req.Header.Set(identity.DefaultHeader, user)
+			req.Header.Set("If-Match", `"3"`) // the event is created and answered twice before it is updated
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+			return resp
+		}
+
+		event := *testEvent
+		event.StartDate = time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)
+		event.Attendees = []storage.Attendee{
+			{User: "bob", Status: storage.RSVPAccepted},
+			{User: "carol"},
+		}
+		eventMarshal, _ := json.Marshal(&event)
+
+		respCreate := serve(event.Owner, "POST", "/event", eventMarshal)
+		var created storage.Event
+		err := json.Unmarshal(respCreate.Body.Bytes(), &created)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, respCreate.Code)
+		require.Equal(t, []storage.Attendee{
+			{User: "bob", Status: storage.RSVPNeedsAction},
+			{User: "carol", Status: storage.RSVPNeedsAction},
+		}, created.Attendees)
+
+		selfInvited := event
+		selfInvited.ID = "self_invited_id"
+		selfInvited.Attendees = []storage.Attendee{{User: event.Owner}}
+		selfInvitedMarshal, _ := json.Marshal(&selfInvited)
+		require.Equal(t, http.StatusUnprocessableEntity, serve(event.Owner, "POST", "/event", selfInvitedMarshal).Code)
+
+		rsvp := func(user, id, status string) *httptest.ResponseRecorder {
+			return serve(user, "PUT", "/event/"+id+"/rsvp", []byte(`{"status":"`+status+`"}`))
+		}
+
+		respRSVP := rsvp("carol", event.ID, "accepted")
+		var attendee storage.Attendee
+		err = json.Unmarshal(respRSVP.Body.Bytes(), &attendee)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, respRSVP.Code)
+		require.Equal(t, storage.Attendee{User: "carol", Status: storage.RSVPAccepted}, attendee)
+
+		require.Equal(t, http.StatusOK, rsvp("bob", event.ID, "declined").Code)
+		require.Equal(t, http.StatusUnprocessableEntity, rsvp("bob", event.ID, "maybe").Code)
+		require.Equal(t, http.StatusForbidden, rsvp("dave", event.ID, "accepted").Code)
+		require.Equal(t, http.StatusNotFound, rsvp("bob", "missing_id", "accepted").Code)
+
+		for user, count := range map[string]int{event.Owner: 1, "carol": 1, "bob": 0, "dave": 0} {
+			respDay := serve(user, "GET", "/event/"+user+"/getDay?date=2024-03-04", nil)
+			var events []storage.Event
+			err := json.Unmarshal(respDay.Body.Bytes(), &events)
+			require.NoError(t, err, user)
+			require.Equal(t, http.StatusOK, respDay.Code, user)
+			require.Len(t, events, count, user)
+		}
+
+		updated := event
+		updated.Title = "updated_title"
+		updated.Attendees = []storage.Attendee{{User: "carol", Status: storage.RSVPDeclined}, {User: "dave"}}
+		updatedMarshal, _ := json.Marshal(&updated)
+		require.Equal(t, http.StatusForbidden, serve("carol", "PUT", "/event", updatedMarshal).Code)
+
+		respUpdate := serve(event.Owner, "PUT", "/event", updatedMarshal)
+		err = json.Unmarshal(respUpdate.Body.Bytes(), &created)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, respUpdate.Code)
+		require.Equal(t, []storage.Attendee{
+			{User: "carol", Status: storage.RSVPAccepted},
+			{User: "dave", Status: storage.RSVPNeedsAction},
+		}, created.Attendees)
+	})
+
+	t.Run("Metrics", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := NewServer(ctx, logg, app.New(memorystorage.New()), authenticator).Handler()
+		req := httptest.NewRequest("GET", "/event/missing_id", nil)
+		req.Header.Set(identity.DefaultHeader, testEvent.Owner)
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusNotFound, resp.Code)
+
+		respMetrics := httptest.NewRecorder()
+		handler.ServeHTTP(respMetrics, httptest.NewRequest("GET", "/metrics", nil))
+		require.Equal(t, http.StatusOK, respMetrics.Code)
+		require.Contains(t, respMetrics.Body.String(),
+			`calendar_http_requests_total{code="404",method="GET",route="/event/{id}"}`)
+	})
+
+	t.Run("Health probes", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ready := true
+		check := health.Check{Name: "database", Probe: func(context.Context) error {
+			if !ready {
+				return errors.New("database is unreachable")
+			}
+
+			return nil
+		}}
+		handler := NewServer(ctx, logg, app.New(memorystorage.New()), authenticator, check).Handler()
+		serve := func(target string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, httptest.NewRequest("GET", target, nil))
+			return resp
+		}
+
+		require.Equal(t, http.StatusOK, serve("/healthz").Code)
+		respReady := serve("/readyz")
+		require.Equal(t, http.StatusOK, respReady.Code)
+		require.JSONEq(t, `{"status":"ok","checks":{"database":"ok"}}`, respReady.Body.String())
+
+		ready = false
+		require.Equal(t, http.StatusOK, serve("/healthz").Code)
+		respReady = serve("/readyz")
+		require.Equal(t, http.StatusServiceUnavailable, respReady.Code)
+		require.JSONEq(t, `{"status":"unavailable","checks":{"database":"database is unreachable"}}`,
+			respReady.Body.String())
+	})
 }
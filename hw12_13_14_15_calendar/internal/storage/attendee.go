@@ -0,0 +1,69 @@
+package storage
+
+// RSVPStatus is the reply of an attendee to the event invitation.
+type RSVPStatus string
+
+const (
+	RSVPNeedsAction RSVPStatus = "needs-action"
+	RSVPAccepted    RSVPStatus = "accepted"
+	RSVPDeclined    RSVPStatus = "declined"
+	RSVPTentative   RSVPStatus = "tentative"
+)
+
+type Attendee struct {
+	User   string     `json:"user" db:"attendee"`
+	Status RSVPStatus `json:"status" db:"status"`
+}
+
+func (s RSVPStatus) Valid() bool {
+	switch s {
+	case RSVPNeedsAction, RSVPAccepted, RSVPDeclined, RSVPTentative:
+		return true
+	default:
+		return false
+	}
+}
+
+// Attendee returns the attendee of the event, nil if the user isn't invited.
+func (e *Event) Attendee(user string) *Attendee {
+	for i := range e.Attendees {
+		if e.Attendees[i].User == user {
+			return &e.Attendees[i]
+		}
+	}
+
+	return nil
+}
+
+// KeepReplies sets the replies of the attendees invited before to the stored ones,
+// only the attendees themselves change their replies.
+func (e *Event) KeepReplies(stored []Attendee) {
+	for _, previous := range stored {
+		if attendee := e.Attendee(previous.User); attendee != nil {
+			attendee.Status = previous.Status
+		}
+	}
+}
+
+// SharedWith reports whether the event is listed for the user: owners see their events,
+// invitees see the events they haven't declined.
+func (e *Event) SharedWith(user string) bool {
+	if e.Owner == user {
+		return true
+	}
+
+	attendee := e.Attendee(user)
+	return attendee != nil && attendee.Status != RSVPDeclined
+}
+
+// Recipients returns the users reminded of the event: the owner and the accepted attendees.
+func (e *Event) Recipients() []string {
+	recipients := []string{e.Owner}
+	for _, attendee := range e.Attendees {
+		if attendee.Status == RSVPAccepted {
+			recipients = append(recipients, attendee.User)
+		}
+	}
+
+	return recipients
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// AuditAction is the kind of the event change recorded in the audit log.
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+	AuditRespond AuditAction = "rsvp"
+)
+
+// AuditEntry records who changed the event, when and how. The audit log is append-only.
+type AuditEntry struct {
+	ID      int64         `json:"id" db:"id"`
+	EventID string        `json:"eventId" db:"event_id"`
+	Action  AuditAction   `json:"action" db:"action"`
+	Actor   string        `json:"actor" db:"actor"`
+	Time    time.Time     `json:"time" db:"created_at"`
+	Changes []AuditChange `json:"changes" db:"changes"`
+}
+
+// AuditChange is the JSON value of the event field before and after the change,
+// the value is omitted when the field is not set.
+type AuditChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// auditSkipped are the fields changed by every write, they aren't audited.
+// The sent state of the reminders is changed by the scheduler, it is left out of the reminders.
+var auditSkipped = map[string]bool{"version": true}
+
+// NewAuditEntry records the change of the event from before to after, nil stands for a missing event.
+func NewAuditEntry(action AuditAction, actor string, before, after *Event, at time.Time) (AuditEntry, error) {
+	eventID := ""
+	for _, event := range []*Event{before, after} {
+		if event != nil {
+			eventID = event.ID
+		}
+	}
+
+	changes, err := Diff(before, after)
+	if err != nil {
+		return AuditEntry{}, err
+	}
+
+	return AuditEntry{EventID: eventID, Action: action, Actor: actor, Time: at.UTC(), Changes: changes}, nil
+}
+
+// Diff returns the changed event fields sorted by name.
+func Diff(before, after *Event) ([]AuditChange, error) {
+	beforeFields, err := eventFields(before)
+	if err != nil {
+		return nil, err
+	}
+
+	afterFields, err := eventFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(beforeFields)+len(afterFields))
+	for name := range beforeFields {
+		names = append(names, name)
+	}
+
+	for name := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	changes := make([]AuditChange, 0)
+	for _, name := range names {
+		if auditSkipped[name] || bytes.Equal(beforeFields[name], afterFields[name]) {
+			continue
+		}
+
+		changes = append(changes, AuditChange{Field: name, Before: beforeFields[name], After: afterFields[name]})
+	}
+
+	return changes, nil
+}
+
+func eventFields(event *Event) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if event == nil {
+		return fields, nil
+	}
+
+	audited := *event
+	audited.Reminders = make([]EventReminder, 0, len(event.Reminders))
+	for _, reminder := range event.Reminders {
+		audited.Reminders = append(audited.Reminders, EventReminder{Before: reminder.Before, At: reminder.At})
+	}
+
+	data, err := json.Marshal(audited)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range fields {
+		if string(value) == "null" {
+			delete(fields, name)
+		}
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,27 @@
+package storage
+
+import "time"
+
+// ConflictCheck is run by the event write with the other writes of the event owner locked out,
+// so two overlapping events written at once can't miss each other. The storage passes Check
+// the owner events which may occur within [Start, End), an error returned by Check cancels the write.
+type ConflictCheck struct {
+	Start time.Time
+	End   time.Time
+	Check func(candidates []Event) error
+}
+
+// MayOccurBetween reports whether an event occurrence may overlap [start, end):
+// the event starts before the end and its last occurrence, if any, ends after the start.
+func (e *Event) MayOccurBetween(start, end time.Time) (bool, error) {
+	if !e.StartDate.Before(end) {
+		return false, nil
+	}
+
+	last, err := e.LastOccurrence()
+	if err != nil {
+		return false, err
+	}
+
+	return last.IsZero() || last.Add(e.Duration).After(start), nil
+}
@@ -6,17 +6,29 @@ import (
 )
 
 var (
-	ErrEventAlreadyExist = errors.New("event with this id already exist")
-	ErrEventDoesNotExist = errors.New("event does not exist")
+	ErrEventAlreadyExist    = errors.New("event with this id already exist")
+	ErrEventDoesNotExist    = errors.New("event does not exist")
+	ErrEventVersionConflict = errors.New("event was changed by another request")
+	ErrInvalidEventID       = errors.New("event id must be a UUID")
+
+	ErrAttendeeDoesNotExist      = errors.New("user is not invited to the event")
+	ErrOutboxMessageDoesNotExist = errors.New("outbox message does not exist")
 )
 
+// Event is stored with a Version increased by every write, a write based on another version of the event
+// is rejected with ErrEventVersionConflict. A deleted event is kept in the trash with its DeletedAt set
+// until it is restored or purged, the storages treat it as missing otherwise.
 type Event struct {
-	ID          string        `json:"id" db:"id"`
-	Title       string        `json:"title" db:"title"`
-	StartDate   time.Time     `json:"startDate" db:"start_date"`
-	Duration    time.Duration `json:"duration" db:"duration"`
-	Description string        `json:"description" db:"description"`
-	Owner       string        `json:"owner" db:"owner"`
-	RemindAt    int64         `json:"remindAt" db:"remind_at"`
-	IsSend      bool          `json:"isSend" db:"is_send"`
+	ID          string          `json:"id" db:"id"`
+	Title       string          `json:"title" db:"title"`
+	StartDate   time.Time       `json:"startDate" db:"start_date"`
+	Duration    time.Duration   `json:"duration" db:"duration"`
+	Description string          `json:"description" db:"description"`
+	Owner       string          `json:"owner" db:"owner"`
+	RRule       string          `json:"rrule" db:"rrule"`
+	ExDates     []time.Time     `json:"exDates" db:"ex_dates"`
+	Reminders   []EventReminder `json:"reminders" db:"reminders"`
+	Attendees   []Attendee      `json:"attendees" db:"-"`
+	Version     int64           `json:"version" db:"version"`
+	DeletedAt   *time.Time      `json:"deletedAt,omitempty" db:"deleted_at"`
 }
@@ -0,0 +1,6 @@
+package storage
+
+import "errors"
+
+// ErrNotLeader is returned by the electors to the replicas which don't hold the leadership.
+var ErrNotLeader = errors.New("another replica is the leader")
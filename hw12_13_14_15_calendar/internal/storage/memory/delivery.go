@@ -0,0 +1,35 @@
+package memorystorage
+
+import (
+	"context"
+	"time"
+
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/metrics"
+)
+
+type deliveryKey struct {
+	reminderID string
+	channel    string
+}
+
+// IsReminderDelivered reports whether the reminder was delivered through the channel.
+func (s *Storage) IsReminderDelivered(_ context.Context, reminderID, channel string) (bool, error) {
+	defer metrics.ObserveStorage("IsReminderDelivered", time.Now())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.deliveries[deliveryKey{reminderID: reminderID, channel: channel}], nil
+}
+
+// MarkReminderDelivered records the reminder was delivered through the channel, marking it again is a no-op.
+func (s *Storage) MarkReminderDelivered(_ context.Context, reminderID, channel string) error {
+	defer metrics.ObserveStorage("MarkReminderDelivered", time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deliveries[deliveryKey{reminderID: reminderID, channel: channel}] = true
+	return nil
+}
@@ -0,0 +1,71 @@
+package memorystorage
+
+import (
+	"sort"
+	"time"
+)
+
+// timeIndex keeps the event IDs ordered by time, so the events indexed before a time
+// are found without scanning all the events.
+type timeIndex struct {
+	entries []indexEntry
+	times   map[string]time.Time
+}
+
+type indexEntry struct {
+	at time.Time
+	id string
+}
+
+func newTimeIndex() *timeIndex {
+	return &timeIndex{times: make(map[string]time.Time)}
+}
+
+// set indexes the event at the time, zero time removes the event from the index.
+func (x *timeIndex) set(id string, at time.Time) {
+	x.remove(id)
+	if at.IsZero() {
+		return
+	}
+
+	i := x.search(at, id)
+	x.entries = append(x.entries, indexEntry{})
+	copy(x.entries[i+1:], x.entries[i:])
+	x.entries[i] = indexEntry{at: at, id: id}
+	x.times[id] = at
+}
+
+func (x *timeIndex) remove(id string) {
+	at, ok := x.times[id]
+	if !ok {
+		return
+	}
+
+	i := x.search(at, id)
+	if i < len(x.entries) && x.entries[i].id == id {
+		x.entries = append(x.entries[:i], x.entries[i+1:]...)
+	}
+
+	delete(x.times, id)
+}
+
+// before returns the IDs indexed before the time, the earliest first.
+func (x *timeIndex) before(at time.Time) []string {
+	ids := make([]string, 0)
+	for _, entry := range x.entries {
+		if !entry.at.Before(at) {
+			break
+		}
+
+		ids = append(ids, entry.id)
+	}
+
+	return ids
+}
+
+func (x *timeIndex) search(at time.Time, id string) int {
+	return sort.Search(len(x.entries), func(i int) bool {
+		entry := x.entries[i]
+		return entry.at.After(at) || (entry.at.Equal(at) && entry.id >= id)
+	})
+}
@@ -0,0 +1,47 @@
+package memorystorage
+
+import (
+	"context"
+
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Elector is the in-process leader lock of the storage: the first candidate asking
+// leads until it resigns, then the next candidate asking takes over.
+type Elector struct {
+	storage   *Storage
+	candidate string
+}
+
+func (s *Storage) NewElector(candidate string) *Elector {
+	return &Elector{storage: s, candidate: candidate}
+}
+
+// IsLeader takes the leadership if it is free and returns storage.ErrNotLeader if another candidate holds it.
+func (e *Elector) IsLeader(_ context.Context) error {
+	e.storage.mu.Lock()
+	defer e.storage.mu.Unlock()
+
+	if e.storage.leader == "" {
+		e.storage.leader = e.candidate
+	}
+
+	if e.storage.leader != e.candidate {
+		return storage.ErrNotLeader
+	}
+
+	return nil
+}
+
+// Resign gives the leadership up if the candidate holds it.
+func (e *Elector) Resign(_ context.Context) error {
+	e.storage.mu.Lock()
+	defer e.storage.mu.Unlock()
+
+	if e.storage.leader == e.candidate {
+		e.storage.leader = ""
+	}
+
+	return nil
+}
@@ -2,23 +2,64 @@ package memorystorage
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/metrics"
 	//nolint:depguard
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
 )
 
 type Storage struct {
-	mu    sync.RWMutex
-	event map[string]*storage.Event
+	mu         sync.RWMutex
+	event      map[string]*storage.Event
+	dueIndex   *timeIndex
+	endIndex   *timeIndex
+	outbox     []*storage.OutboxMessage
+	reminders  map[reminderKey]bool
+	audit      []storage.AuditEntry
+	deliveries map[deliveryKey]bool
+	leader     string
+}
+
+type reminderKey struct {
+	eventID    string
+	occurrence time.Time
+	recipient  string
+	reminder   string
 }
 
 func New() *Storage {
-	return &Storage{event: make(map[string]*storage.Event)}
+	return &Storage{
+		event:      make(map[string]*storage.Event),
+		dueIndex:   newTimeIndex(),
+		endIndex:   newTimeIndex(),
+		reminders:  make(map[reminderKey]bool),
+		deliveries: make(map[deliveryKey]bool),
+	}
 }
 
 func (s *Storage) CreateEvent(_ context.Context, event *storage.Event) error {
+	defer metrics.ObserveStorage("CreateEvent", time.Now())
+
+	return s.createEvent(event, nil, nil)
+}
+
+// CreateEventWithAudit creates the event and appends the entry to its audit log at once,
+// the conflict check, if any, is run under the same lock.
+func (s *Storage) CreateEventWithAudit(_ context.Context,
+	event *storage.Event,
+	entry *storage.AuditEntry,
+	check *storage.ConflictCheck,
+) error {
+	defer metrics.ObserveStorage("CreateEventWithAudit", time.Now())
+
+	return s.createEvent(event, entry, check)
+}
+
+func (s *Storage) createEvent(event *storage.Event, entry *storage.AuditEntry, check *storage.ConflictCheck) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -26,60 +67,588 @@ func (s *Storage) CreateEvent(_ context.Context, event *storage.Event) error {
 		return storage.ErrEventAlreadyExist
 	}
 
+	if err := s.checkConflicts(event, check); err != nil {
+		return err
+	}
+
+	if err := s.index(event); err != nil {
+		return err
+	}
+
+	event.Version = 1
+	event.DeletedAt = nil
 	s.event[event.ID] = event
+	s.appendAudit(entry)
 	return nil
 }
 
 func (s *Storage) UpdateEvent(_ context.Context, event *storage.Event) error {
+	defer metrics.ObserveStorage("UpdateEvent", time.Now())
+
+	return s.updateEvent(event, nil, nil)
+}
+
+// UpdateEventWithAudit updates the event and appends the entry to its audit log at once,
+// the conflict check, if any, is run under the same lock.
+func (s *Storage) UpdateEventWithAudit(_ context.Context,
+	event *storage.Event,
+	entry *storage.AuditEntry,
+	check *storage.ConflictCheck,
+) error {
+	defer metrics.ObserveStorage("UpdateEventWithAudit", time.Now())
+
+	return s.updateEvent(event, entry, check)
+}
+
+func (s *Storage) updateEvent(event *storage.Event, entry *storage.AuditEntry, check *storage.ConflictCheck) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.event[event.ID] == nil {
+	stored := s.active(event.ID)
+	if stored == nil {
 		return storage.ErrEventDoesNotExist
 	}
 
+	if stored.Version != event.Version {
+		return storage.ErrEventVersionConflict
+	}
+
+	if err := s.checkConflicts(event, check); err != nil {
+		return err
+	}
+
+	if err := s.index(event); err != nil {
+		return err
+	}
+
+	event.KeepReplies(stored.Attendees)
+	event.Version++
+	event.DeletedAt = nil
 	s.event[event.ID] = event
+	s.appendAudit(entry)
 	return nil
 }
 
+// DeleteEvent moves the event to the trash.
 func (s *Storage) DeleteEvent(_ context.Context, id string) error {
+	defer metrics.ObserveStorage("DeleteEvent", time.Now())
+
+	return s.deleteEvent(id, nil)
+}
+
+// DeleteEventWithAudit moves the event to the trash and appends the entry to its audit log at once.
+func (s *Storage) DeleteEventWithAudit(_ context.Context, id string, entry *storage.AuditEntry) error {
+	defer metrics.ObserveStorage("DeleteEventWithAudit", time.Now())
+
+	return s.deleteEvent(id, entry)
+}
+
+func (s *Storage) deleteEvent(id string, entry *storage.AuditEntry) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.event[id] == nil {
+	stored := s.active(id)
+	if stored == nil {
 		return storage.ErrEventDoesNotExist
 	}
 
-	delete(s.event, id)
+	s.trash(stored, time.Now().UTC())
+	s.appendAudit(entry)
 	return nil
 }
 
+// DeleteEvents moves the events to the trash and returns how many of them are moved.
+func (s *Storage) DeleteEvents(_ context.Context, ids []string) (int, error) {
+	defer metrics.ObserveStorage("DeleteEvents", time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	deletedAt := time.Now().UTC()
+	for _, id := range ids {
+		if stored := s.active(id); stored != nil {
+			s.trash(stored, deletedAt)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// RestoreEvent takes the event out of the trash.
+func (s *Storage) RestoreEvent(_ context.Context, id string) error {
+	defer metrics.ObserveStorage("RestoreEvent", time.Now())
+
+	return s.restoreEvent(id, nil)
+}
+
+// RestoreEventWithAudit takes the event out of the trash and appends the entry to its audit log at once.
+func (s *Storage) RestoreEventWithAudit(_ context.Context, id string, entry *storage.AuditEntry) error {
+	defer metrics.ObserveStorage("RestoreEventWithAudit", time.Now())
+
+	return s.restoreEvent(id, entry)
+}
+
+func (s *Storage) restoreEvent(id string, entry *storage.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.event[id]
+	if stored == nil || stored.DeletedAt == nil {
+		return storage.ErrEventDoesNotExist
+	}
+
+	event := *stored
+	event.DeletedAt = nil
+	event.Version++
+	s.event[id] = &event
+	s.appendAudit(entry)
+	return nil
+}
+
+// GetDeletedEvents returns the owner events in the trash, the last deleted first.
+func (s *Storage) GetDeletedEvents(_ context.Context, owner string) ([]storage.Event, error) {
+	defer metrics.ObserveStorage("GetDeletedEvents", time.Now())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := make([]storage.Event, 0)
+	for _, e := range s.event {
+		if e.DeletedAt != nil && e.Owner == owner {
+			events = append(events, *e)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].DeletedAt.Equal(*events[j].DeletedAt) {
+			return events[i].DeletedAt.After(*events[j].DeletedAt)
+		}
+
+		return events[i].ID < events[j].ID
+	})
+
+	return events, nil
+}
+
+// PurgeEvents removes the events deleted before the given time permanently.
+func (s *Storage) PurgeEvents(_ context.Context, deletedBefore time.Time) (int, error) {
+	defer metrics.ObserveStorage("PurgeEvents", time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, e := range s.event {
+		if e.DeletedAt != nil && e.DeletedAt.Before(deletedBefore) {
+			delete(s.event, id)
+			s.dueIndex.remove(id)
+			s.endIndex.remove(id)
+			s.dropOutbox(id)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+// GetOwnerEventsBetween returns the owner events which may occur within [start, end) sorted by the start date,
+// a recurring event is returned once with its recurrence rule.
+func (s *Storage) GetOwnerEventsBetween(_ context.Context,
+	owner string,
+	start time.Time,
+	end time.Time,
+) ([]storage.Event, error) {
+	defer metrics.ObserveStorage("GetOwnerEventsBetween", time.Now())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ownerEventsBetween(owner, start, end)
+}
+
+// SharesEvent reports whether one of the users is invited to an event of the other and hasn't declined it.
+func (s *Storage) SharesEvent(_ context.Context, user, other string) (bool, error) {
+	defer metrics.ObserveStorage("SharesEvent", time.Now())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, e := range s.event {
+		if e.DeletedAt != nil {
+			continue
+		}
+
+		if e.Owner == user && e.Owner != other && e.SharedWith(other) ||
+			e.Owner == other && e.Owner != user && e.SharedWith(user) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetAcceptedEventsBetween returns the events the user accepted the invitations to which may occur
+// within [start, end) sorted by the start date, a recurring event is returned once with its recurrence rule.
+func (s *Storage) GetAcceptedEventsBetween(_ context.Context,
+	user string,
+	start time.Time,
+	end time.Time,
+) ([]storage.Event, error) {
+	defer metrics.ObserveStorage("GetAcceptedEventsBetween", time.Now())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.eventsBetween(func(e *storage.Event) bool {
+		attendee := e.Attendee(user)
+		return attendee != nil && attendee.Status == storage.RSVPAccepted
+	}, start, end)
+}
+
+func (s *Storage) ownerEventsBetween(owner string, start, end time.Time) ([]storage.Event, error) {
+	return s.eventsBetween(func(e *storage.Event) bool { return e.Owner == owner }, start, end)
+}
+
+// eventsBetween returns the matching events which may occur within [start, end) sorted by the start date.
+func (s *Storage) eventsBetween(match func(e *storage.Event) bool, start, end time.Time) ([]storage.Event, error) {
+	events := make([]storage.Event, 0)
+	for _, e := range s.event {
+		if e.DeletedAt != nil || !match(e) {
+			continue
+		}
+
+		occurs, err := e.MayOccurBetween(start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		if occurs {
+			events = append(events, *e)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if cmp := events[i].StartDate.Compare(events[j].StartDate); cmp != 0 {
+			return cmp < 0
+		}
+
+		return events[i].ID < events[j].ID
+	})
+
+	return events, nil
+}
+
+// checkConflicts runs the conflict check of the event write, the caller holds the lock.
+func (s *Storage) checkConflicts(event *storage.Event, check *storage.ConflictCheck) error {
+	if check == nil {
+		return nil
+	}
+
+	candidates, err := s.ownerEventsBetween(event.Owner, check.Start, check.End)
+	if err != nil {
+		return err
+	}
+
+	return check.Check(candidates)
+}
+
 func (s *Storage) GetEventsByPeriod(_ context.Context,
 	owner string,
 	startTime time.Time,
 	endTime time.Time,
 ) ([]storage.Event, error) {
+	defer metrics.ObserveStorage("GetEventsByPeriod", time.Now())
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	events := make([]storage.Event, 0)
 	for _, e := range s.event {
-		if e.Owner == owner && (e.StartDate.After(startTime) || e.StartDate.Equal(startTime)) && e.StartDate.Before(endTime) {
+		if e.DeletedAt == nil && e.SharedWith(owner) {
 			events = append(events, *e)
 		}
 	}
 
-	return events, nil
+	return storage.ExpandByPeriod(events, startTime, endTime)
+}
+
+func (s *Storage) GetEvent(_ context.Context, id string) (storage.Event, error) {
+	defer metrics.ObserveStorage("GetEvent", time.Now())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	event := s.active(id)
+	if event == nil {
+		return storage.Event{}, storage.ErrEventDoesNotExist
+	}
+
+	return *event, nil
 }
 
 func (s *Storage) GetEvents(_ context.Context) ([]storage.Event, error) {
+	defer metrics.ObserveStorage("GetEvents", time.Now())
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	events := make([]storage.Event, len(s.event))
+	events := make([]storage.Event, 0, len(s.event))
 	for _, e := range s.event {
-		//nolint:makezero
-		events = append(events, *e)
+		if e.DeletedAt == nil {
+			events = append(events, *e)
+		}
 	}
 
 	return events, nil
 }
+
+func (s *Storage) QueryEvents(_ context.Context, query storage.EventQuery) (storage.EventPage, error) {
+	defer metrics.ObserveStorage("QueryEvents", time.Now())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := make([]storage.Event, 0)
+	for _, e := range s.event {
+		if e.DeletedAt == nil && query.Match(e) && query.AfterCursor(e) {
+			events = append(events, *e)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return query.Less(&events[i], &events[j])
+	})
+
+	return storage.NewEventPage(&query, events), nil
+}
+
+// GetEventsDueForReminder returns the events with a reminder due before the given time,
+// the earliest due first. Zero limit returns all of them.
+func (s *Storage) GetEventsDueForReminder(_ context.Context, before time.Time, limit int) ([]storage.Event, error) {
+	defer metrics.ObserveStorage("GetEventsDueForReminder", time.Now())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.indexedEvents(s.dueIndex, before, limit), nil
+}
+
+// GetEventsEndedBefore returns the events which last occurred before the given time,
+// the earliest ended first. Zero limit returns all of them.
+func (s *Storage) GetEventsEndedBefore(_ context.Context, before time.Time, limit int) ([]storage.Event, error) {
+	defer metrics.ObserveStorage("GetEventsEndedBefore", time.Now())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.indexedEvents(s.endIndex, before, limit), nil
+}
+
+// UpdateEventWithOutbox updates the event and enqueues its reminders atomically,
+// reminders which are already enqueued are skipped. The stored attendees are kept.
+func (s *Storage) UpdateEventWithOutbox(_ context.Context,
+	event *storage.Event,
+	messages []storage.OutboxMessage,
+) error {
+	defer metrics.ObserveStorage("UpdateEventWithOutbox", time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.active(event.ID)
+	if stored == nil {
+		return storage.ErrEventDoesNotExist
+	}
+
+	if stored.Version != event.Version {
+		return storage.ErrEventVersionConflict
+	}
+
+	if err := s.index(event); err != nil {
+		return err
+	}
+
+	timeNow := time.Now().UTC()
+	for _, message := range messages {
+		key := reminderKey{
+			eventID:    message.EventID,
+			occurrence: message.Occurrence.UTC(),
+			recipient:  message.Recipient,
+			reminder:   message.Reminder,
+		}
+		if s.reminders[key] {
+			continue
+		}
+
+		message.ID = int64(len(s.outbox) + 1)
+		message.CreatedAt = timeNow
+		s.reminders[key] = true
+		s.outbox = append(s.outbox, &message)
+	}
+
+	event.Attendees = stored.Attendees
+	event.Version++
+	event.DeletedAt = nil
+	s.event[event.ID] = event
+	return nil
+}
+
+// SetAttendeeStatus stores the reply of the attendee to the event invitation and appends it to the audit log.
+func (s *Storage) SetAttendeeStatus(_ context.Context, eventID, user string, status storage.RSVPStatus) error {
+	defer metrics.ObserveStorage("SetAttendeeStatus", time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.active(eventID)
+	if stored == nil {
+		return storage.ErrEventDoesNotExist
+	}
+
+	if stored.Attendee(user) == nil {
+		return storage.ErrAttendeeDoesNotExist
+	}
+
+	event := *stored
+	event.Attendees = make([]storage.Attendee, len(stored.Attendees))
+	copy(event.Attendees, stored.Attendees)
+	event.Attendee(user).Status = status
+	event.Version++
+
+	entry, err := storage.NewAuditEntry(storage.AuditRespond, user, stored, &event, time.Now())
+	if err != nil {
+		return err
+	}
+
+	s.event[eventID] = &event
+	s.appendAudit(&entry)
+	return nil
+}
+
+func (s *Storage) GetPendingOutboxMessages(_ context.Context, limit int) ([]storage.OutboxMessage, error) {
+	defer metrics.ObserveStorage("GetPendingOutboxMessages", time.Now())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	messages := make([]storage.OutboxMessage, 0)
+	for _, message := range s.outbox {
+		if limit > 0 && len(messages) == limit {
+			break
+		}
+
+		if message != nil && message.SentAt.IsZero() && s.active(message.EventID) != nil {
+			messages = append(messages, *message)
+		}
+	}
+
+	return messages, nil
+}
+
+func (s *Storage) MarkOutboxMessageSent(_ context.Context, id int64, sentAt time.Time) error {
+	defer metrics.ObserveStorage("MarkOutboxMessageSent", time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id < 1 || id > int64(len(s.outbox)) || s.outbox[id-1] == nil {
+		return storage.ErrOutboxMessageDoesNotExist
+	}
+
+	s.outbox[id-1].SentAt = sentAt.UTC()
+	return nil
+}
+
+// appendAudit appends the entry to the audit log, a nil entry isn't audited.
+func (s *Storage) appendAudit(entry *storage.AuditEntry) {
+	if entry == nil {
+		return
+	}
+
+	entry.ID = int64(len(s.audit) + 1)
+	s.audit = append(s.audit, *entry)
+}
+
+// GetAuditEntries returns the audit log of the event, the oldest entry first.
+func (s *Storage) GetAuditEntries(_ context.Context, eventID string) ([]storage.AuditEntry, error) {
+	defer metrics.ObserveStorage("GetAuditEntries", time.Now())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]storage.AuditEntry, 0)
+	for _, entry := range s.audit {
+		if entry.EventID == eventID {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// index updates the reminder and the cleanup indexes of the event.
+func (s *Storage) index(event *storage.Event) error {
+	due, err := event.NextReminderDue()
+	if err != nil {
+		return err
+	}
+
+	lastOccurrence, err := event.LastOccurrence()
+	if err != nil {
+		return err
+	}
+
+	s.dueIndex.set(event.ID, due)
+	s.endIndex.set(event.ID, lastOccurrence)
+	return nil
+}
+
+func (s *Storage) indexedEvents(index *timeIndex, before time.Time, limit int) []storage.Event {
+	events := make([]storage.Event, 0)
+	for _, id := range index.before(before) {
+		if limit > 0 && len(events) == limit {
+			break
+		}
+
+		if event := s.active(id); event != nil {
+			events = append(events, *event)
+		}
+	}
+
+	return events
+}
+
+// trash moves the event to the trash, its pending reminders are held back until it is restored.
+func (s *Storage) trash(stored *storage.Event, deletedAt time.Time) {
+	event := *stored
+	event.DeletedAt = &deletedAt
+	event.Version++
+	s.event[event.ID] = &event
+}
+
+// dropOutbox removes the outbox messages of the event and forgets they were enqueued. The message ID is
+// its position in the outbox, so a removed message leaves a gap.
+func (s *Storage) dropOutbox(eventID string) {
+	for i, message := range s.outbox {
+		if message != nil && message.EventID == eventID {
+			s.outbox[i] = nil
+		}
+	}
+
+	for key := range s.reminders {
+		if key.eventID == eventID {
+			delete(s.reminders, key)
+		}
+	}
+}
+
+// active returns the stored event unless it is missing or deleted.
+func (s *Storage) active(id string) *storage.Event {
+	event := s.event[id]
+	if event == nil || event.DeletedAt != nil {
+		return nil
+	}
+
+	return event
+}
@@ -2,12 +2,19 @@ package memorystorage
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/metrics"
 	//nolint:depguard
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
 	//nolint:depguard
+	"github.com/prometheus/client_golang/prometheus"
+	//nolint:depguard
+	dto "github.com/prometheus/client_model/go"
+	//nolint:depguard
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,7 +26,7 @@ func TestStorage(t *testing.T) {
 		StartDate:   time.Now(),
 		Duration:    30,
 		Description: "test_description",
-		RemindAt:    100,
+		Reminders:   []storage.EventReminder{{Before: 100}},
 	}
 
 	t.Run("event created", func(t *testing.T) {
@@ -38,7 +45,7 @@ func TestStorage(t *testing.T) {
 		require.Equal(t, events[0].Owner, "test_user")
 		require.Equal(t, events[0].Duration, time.Duration(30))
 		require.Equal(t, events[0].Description, "test_description")
-		require.Equal(t, events[0].RemindAt, int64(100))
+		require.Equal(t, events[0].Reminders, []storage.EventReminder{{Before: 100}})
 		require.NotNil(t, events[0].StartDate)
 
 		err = memory.DeleteEvent(ctx, events[0].ID)
@@ -78,7 +85,8 @@ func TestStorage(t *testing.T) {
 			StartDate:   updatedTime,
 			Duration:    32,
 			Description: "test_description2",
-			RemindAt:    120,
+			Reminders:   []storage.EventReminder{{Before: 120}},
+			Version:     1,
 		})
 		require.NoError(t, err)
 
@@ -90,7 +98,8 @@ func TestStorage(t *testing.T) {
 		require.Equal(t, events[0].Owner, "test_user2")
 		require.Equal(t, events[0].Duration, time.Duration(32))
 		require.Equal(t, events[0].Description, "test_description2")
-		require.Equal(t, events[0].RemindAt, int64(120))
+		require.Equal(t, events[0].Reminders, []storage.EventReminder{{Before: 120}})
+		require.Equal(t, events[0].Version, int64(2))
 		require.NotNil(t, events[0].StartDate)
 
 		err = memory.DeleteEvent(ctx, events[0].ID)
@@ -108,6 +117,29 @@ func TestStorage(t *testing.T) {
 		require.Equal(t, err, storage.ErrEventDoesNotExist)
 	})
 
+	t.Run("event update with stale version", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := New()
+		event := *testEvent
+		err := memory.CreateEvent(ctx, &event)
+		require.NoError(t, err)
+
+		stale := event
+		err = memory.UpdateEvent(ctx, &event)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), event.Version)
+
+		stale.Title = "test_title2"
+		err = memory.UpdateEvent(ctx, &stale)
+		require.ErrorIs(t, err, storage.ErrEventVersionConflict)
+
+		events, err := memory.GetEvents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, testEvent.Title, events[0].Title)
+	})
+
 	t.Run("event deleted", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -124,6 +156,203 @@ func TestStorage(t *testing.T) {
 		require.Equal(t, len(events), 0)
 	})
 
+	t.Run("event trash", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := New()
+		event := *testEvent
+		err := memory.CreateEvent(ctx, &event)
+		require.NoError(t, err)
+
+		err = memory.DeleteEvent(ctx, event.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, memory.DeleteEvent(ctx, event.ID), storage.ErrEventDoesNotExist)
+		require.ErrorIs(t, memory.UpdateEvent(ctx, &event), storage.ErrEventDoesNotExist)
+		require.ErrorIs(t, memory.CreateEvent(ctx, &storage.Event{ID: event.ID}), storage.ErrEventAlreadyExist)
+
+		_, err = memory.GetEvent(ctx, event.ID)
+		require.ErrorIs(t, err, storage.ErrEventDoesNotExist)
+		events, err := memory.GetEvents(ctx)
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		deleted, err := memory.GetDeletedEvents(ctx, event.Owner)
+		require.NoError(t, err)
+		require.Len(t, deleted, 1)
+		require.NotNil(t, deleted[0].DeletedAt)
+		require.Equal(t, int64(2), deleted[0].Version)
+
+		deleted, err = memory.GetDeletedEvents(ctx, "test_user2")
+		require.NoError(t, err)
+		require.Empty(t, deleted)
+
+		err = memory.RestoreEvent(ctx, event.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, memory.RestoreEvent(ctx, event.ID), storage.ErrEventDoesNotExist)
+
+		restored, err := memory.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		require.Nil(t, restored.DeletedAt)
+		require.Equal(t, int64(3), restored.Version)
+
+		err = memory.DeleteEvent(ctx, event.ID)
+		require.NoError(t, err)
+
+		purged, err := memory.PurgeEvents(ctx, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		require.Zero(t, purged)
+
+		purged, err = memory.PurgeEvents(ctx, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, 1, purged)
+
+		deleted, err = memory.GetDeletedEvents(ctx, event.Owner)
+		require.NoError(t, err)
+		require.Empty(t, deleted)
+	})
+
+	t.Run("events due for reminder and ended", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		startDate := time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)
+		memory := New()
+		events := []storage.Event{
+			{ID: "once_id", Owner: "test_user", StartDate: startDate, Reminders: []storage.EventReminder{{Before: 30}}},
+			{
+				ID:        "weekly_id",
+				Owner:     "test_user",
+				StartDate: startDate,
+				RRule:     "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=5",
+				Reminders: []storage.EventReminder{{}},
+			},
+			{
+				ID:        "daily_id",
+				Owner:     "test_user",
+				StartDate: startDate.AddDate(0, 0, 1),
+				RRule:     "FREQ=DAILY",
+				Reminders: []storage.EventReminder{{Before: 60}, {}},
+			},
+			{
+				ID:        "sent_id",
+				Owner:     "test_user",
+				StartDate: startDate.AddDate(0, 0, 16),
+				Reminders: []storage.EventReminder{{IsSend: true}},
+			},
+			{ID: "silent_id", Owner: "test_user", StartDate: startDate},
+			{ID: "countless_id", Owner: "test_user", StartDate: startDate, RRule: "FREQ=DAILY;COUNT=100000000"},
+		}
+		for i := range events {
+			require.NoError(t, memory.CreateEvent(ctx, &events[i]))
+		}
+
+		eventIDs := func(events []storage.Event) []string {
+			ids := make([]string, 0, len(events))
+			for _, event := range events {
+				ids = append(ids, event.ID)
+			}
+
+			return ids
+		}
+
+		due, err := memory.GetEventsDueForReminder(ctx, startDate.AddDate(0, 0, 1).Add(2*time.Hour), 0)
+		require.NoError(t, err)
+		require.Equal(t, []string{"once_id", "weekly_id", "daily_id"}, eventIDs(due))
+
+		due, err = memory.GetEventsDueForReminder(ctx, startDate.AddDate(0, 0, 1).Add(2*time.Hour), 2)
+		require.NoError(t, err)
+		require.Equal(t, []string{"once_id", "weekly_id"}, eventIDs(due))
+
+		ended, err := memory.GetEventsEndedBefore(ctx, startDate.AddDate(0, 0, 8), 0)
+		require.NoError(t, err)
+		require.Equal(t, []string{"once_id", "silent_id"}, eventIDs(ended))
+
+		ended, err = memory.GetEventsEndedBefore(ctx, startDate.AddDate(0, 1, 0), 0)
+		require.NoError(t, err)
+		require.Equal(t, []string{"once_id", "silent_id", "weekly_id", "sent_id"}, eventIDs(ended))
+
+		weekly := events[1]
+		weekly.Reminders = []storage.EventReminder{{SentUntil: startDate.AddDate(0, 0, 7)}}
+		require.NoError(t, memory.UpdateEvent(ctx, &weekly))
+
+		due, err = memory.GetEventsDueForReminder(ctx, startDate.AddDate(0, 0, 9), 0)
+		require.NoError(t, err)
+		require.Equal(t, []string{"once_id", "daily_id"}, eventIDs(due))
+
+		deleted, err := memory.DeleteEvents(ctx, []string{"once_id", "missing_id"})
+		require.NoError(t, err)
+		require.Equal(t, 1, deleted)
+
+		due, err = memory.GetEventsDueForReminder(ctx, startDate.AddDate(0, 0, 10), 0)
+		require.NoError(t, err)
+		require.Equal(t, []string{"daily_id", "weekly_id"}, eventIDs(due))
+	})
+
+	t.Run("leader election", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := New()
+		first := memory.NewElector("first")
+		second := memory.NewElector("second")
+
+		require.NoError(t, first.IsLeader(ctx))
+		require.NoError(t, first.IsLeader(ctx))
+		require.ErrorIs(t, second.IsLeader(ctx), storage.ErrNotLeader)
+
+		require.NoError(t, second.Resign(ctx))
+		require.NoError(t, first.IsLeader(ctx))
+
+		require.NoError(t, first.Resign(ctx))
+		require.NoError(t, second.IsLeader(ctx))
+		require.ErrorIs(t, first.IsLeader(ctx), storage.ErrNotLeader)
+	})
+
+	t.Run("event audit log", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := New()
+		event := *testEvent
+		event.Attendees = []storage.Attendee{{User: "bob", Status: storage.RSVPNeedsAction}}
+		created, err := storage.NewAuditEntry(storage.AuditCreate, event.Owner, nil, &event, time.Now())
+		require.NoError(t, err)
+		require.NoError(t, memory.CreateEventWithAudit(ctx, &event, &created, nil))
+
+		other := storage.Event{ID: "other_id", Owner: event.Owner}
+		require.NoError(t, memory.CreateEventWithAudit(ctx, &other,
+			&storage.AuditEntry{EventID: other.ID, Action: storage.AuditCreate, Actor: other.Owner}, nil))
+
+		updated := event
+		updated.Title = "test_title2"
+		entry, err := storage.NewAuditEntry(storage.AuditUpdate, event.Owner, &event, &updated, time.Now())
+		require.NoError(t, err)
+
+		stale := updated
+		stale.Version = 5
+		require.ErrorIs(t, memory.UpdateEventWithAudit(ctx, &stale, &entry, nil), storage.ErrEventVersionConflict)
+		require.NoError(t, memory.UpdateEventWithAudit(ctx, &updated, &entry, nil))
+		require.Equal(t, int64(3), entry.ID)
+
+		require.NoError(t, memory.SetAttendeeStatus(ctx, event.ID, "bob", storage.RSVPAccepted))
+
+		entries, err := memory.GetAuditEntries(ctx, event.ID)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		require.Equal(t, storage.AuditCreate, entries[0].Action)
+		require.Equal(t, []storage.AuditChange{
+			{Field: "title", Before: []byte(`"test_title"`), After: []byte(`"test_title2"`)},
+		}, entries[1].Changes)
+		require.Equal(t, storage.AuditRespond, entries[2].Action)
+		require.Equal(t, "bob", entries[2].Actor)
+		require.Equal(t, []storage.AuditChange{{
+			Field:  "attendees",
+			Before: []byte(`[{"user":"bob","status":"needs-action"}]`),
+			After:  []byte(`[{"user":"bob","status":"accepted"}]`),
+		}}, entries[2].Changes)
+	})
+
 	t.Run("event delete does not exists", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -152,4 +381,380 @@ func TestStorage(t *testing.T) {
 		err = memory.DeleteEvent(ctx, testEvent.ID)
 		require.NoError(t, err)
 	})
+
+	t.Run("recurring events get by period", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		startDate := time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)
+		memory := New()
+		err := memory.CreateEvent(ctx, &storage.Event{
+			ID:        "weekly_id",
+			Title:     "weekly",
+			Owner:     "test_user",
+			StartDate: startDate,
+			Duration:  30,
+			RRule:     "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=5",
+			ExDates:   []time.Time{startDate.AddDate(0, 0, 2)},
+		})
+		require.NoError(t, err)
+
+		err = memory.CreateEvent(ctx, &storage.Event{
+			ID:        "daily_id",
+			Title:     "daily",
+			Owner:     "test_user",
+			StartDate: startDate,
+			Duration:  30,
+			RRule:     "FREQ=DAILY;UNTIL=20240310T100000Z",
+		})
+		require.NoError(t, err)
+
+		events, err := memory.GetEventsByPeriod(ctx, "test_user", startDate, startDate.AddDate(0, 1, 0))
+		require.NoError(t, err)
+
+		occurrences := make(map[string][]time.Time)
+		for _, event := range events {
+			occurrences[event.ID] = append(occurrences[event.ID], event.StartDate)
+		}
+		require.ElementsMatch(t, occurrences["weekly_id"], []time.Time{
+			startDate,
+			startDate.AddDate(0, 0, 7),
+			startDate.AddDate(0, 0, 9),
+			startDate.AddDate(0, 0, 14),
+		})
+		require.Len(t, occurrences["daily_id"], 7)
+
+		events, err = memory.GetEventsByPeriod(ctx, "test_user", startDate.AddDate(0, 0, 7), startDate.AddDate(0, 0, 8))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, "weekly_id", events[0].ID)
+		require.Equal(t, startDate.AddDate(0, 0, 7), events[0].StartDate)
+	})
+
+	t.Run("owner events get between", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		startDate := time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)
+		memory := New()
+		for _, event := range []storage.Event{
+			{ID: "old_id", Owner: "test_user", StartDate: startDate.AddDate(-1, 0, 0), Duration: time.Hour},
+			{ID: "long_id", Owner: "test_user", StartDate: startDate.AddDate(0, 0, -1), Duration: 48 * time.Hour},
+			{ID: "daily_id", Owner: "test_user", StartDate: startDate.AddDate(-1, 0, 0), Duration: time.Hour,
+				RRule: "FREQ=DAILY"},
+			{ID: "ended_id", Owner: "test_user", StartDate: startDate.AddDate(-1, 0, 0), Duration: time.Hour,
+				RRule: "FREQ=DAILY;COUNT=3"},
+			{ID: "later_id", Owner: "test_user", StartDate: startDate.AddDate(0, 0, 1), Duration: time.Hour},
+			{ID: "other_id", Owner: "test_user2", StartDate: startDate, Duration: time.Hour,
+				Attendees: []storage.Attendee{{User: "test_user", Status: storage.RSVPAccepted}}},
+			{ID: "invited_id", Owner: "test_user2", StartDate: startDate, Duration: time.Hour,
+				Attendees: []storage.Attendee{{User: "test_user", Status: storage.RSVPNeedsAction}}},
+		} {
+			require.NoError(t, memory.CreateEvent(ctx, &event))
+		}
+
+		accepted, err := memory.GetAcceptedEventsBetween(ctx, "test_user", startDate, startDate.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, accepted, 1)
+		require.Equal(t, "other_id", accepted[0].ID)
+
+		events, err := memory.GetOwnerEventsBetween(ctx, "test_user", startDate, startDate.Add(time.Hour))
+		require.NoError(t, err)
+		ids := make([]string, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		require.Equal(t, []string{"daily_id", "long_id"}, ids)
+
+		var candidates []storage.Event
+		errOverlap := fmt.Errorf("overlap")
+		check := &storage.ConflictCheck{
+			Start: startDate,
+			End:   startDate.Add(time.Hour),
+			Check: func(events []storage.Event) error {
+				candidates = events
+				return errOverlap
+			},
+		}
+		event := storage.Event{ID: "new_id", Owner: "test_user", StartDate: startDate, Duration: time.Hour}
+		require.ErrorIs(t, memory.CreateEventWithAudit(ctx, &event, nil, check), errOverlap)
+		require.Len(t, candidates, 2)
+
+		_, err = memory.GetEvent(ctx, event.ID)
+		require.ErrorIs(t, err, storage.ErrEventDoesNotExist)
+	})
+
+	t.Run("event get by id", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := New()
+		err := memory.CreateEvent(ctx, testEvent)
+		require.NoError(t, err)
+
+		event, err := memory.GetEvent(ctx, testEvent.ID)
+		require.NoError(t, err)
+		require.Equal(t, *testEvent, event)
+
+		_, err = memory.GetEvent(ctx, "not_exists")
+		require.ErrorIs(t, err, storage.ErrEventDoesNotExist)
+	})
+
+	t.Run("events get all", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := New()
+		err := memory.CreateEvent(ctx, testEvent)
+		require.NoError(t, err)
+
+		events, err := memory.GetEvents(ctx)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, testEvent.ID, events[0].ID)
+	})
+
+	t.Run("events query", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		startDate := time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)
+		memory := New()
+		for i, title := range []string{"Daily standup", "Retro", "daily sync", "Planning", "Demo"} {
+			err := memory.CreateEvent(ctx, &storage.Event{
+				ID:          fmt.Sprintf("id_%d", i),
+				Title:       title,
+				Owner:       "test_user",
+				StartDate:   startDate.AddDate(0, 0, i),
+				Duration:    30,
+				Description: "team " + title,
+			})
+			require.NoError(t, err)
+		}
+
+		err := memory.CreateEvent(ctx, &storage.Event{ID: "other", Title: "Daily", Owner: "other_user", StartDate: startDate})
+		require.NoError(t, err)
+
+		query := storage.EventQuery{Owner: "test_user", Sort: storage.SortByStartDate, Limit: 2}
+		ids := make([]string, 0)
+		for {
+			page, err := memory.QueryEvents(ctx, query)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(page.Events), 2)
+
+			for _, event := range page.Events {
+				ids = append(ids, event.ID)
+			}
+
+			if page.NextCursor == "" {
+				break
+			}
+
+			query.Cursor, err = storage.DecodeCursor(page.NextCursor)
+			require.NoError(t, err)
+		}
+		require.Equal(t, []string{"id_0", "id_1", "id_2", "id_3", "id_4"}, ids)
+
+		page, err := memory.QueryEvents(ctx, storage.EventQuery{
+			Owner: "test_user",
+			Title: "DAILY",
+			Sort:  storage.SortByTitle,
+		})
+		require.NoError(t, err)
+		require.Len(t, page.Events, 2)
+		require.Equal(t, "id_0", page.Events[0].ID)
+		require.Equal(t, "id_2", page.Events[1].ID)
+
+		page, err = memory.QueryEvents(ctx, storage.EventQuery{
+			Description: "team",
+			From:        startDate.AddDate(0, 0, 1),
+			To:          startDate.AddDate(0, 0, 4),
+			Sort:        storage.SortByStartDate,
+			Desc:        true,
+		})
+		require.NoError(t, err)
+		require.Len(t, page.Events, 3)
+		require.Equal(t, "id_3", page.Events[0].ID)
+		require.Equal(t, "id_1", page.Events[2].ID)
+		require.Empty(t, page.NextCursor)
+	})
+
+	t.Run("outbox messages", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := New()
+		event := *testEvent
+		message, err := storage.NewReminderMessage(event, &testEvent.Reminders[0], event.StartDate, event.Owner)
+		require.NoError(t, err)
+
+		err = memory.UpdateEventWithOutbox(ctx, &event, []storage.OutboxMessage{message})
+		require.ErrorIs(t, err, storage.ErrEventDoesNotExist)
+
+		err = memory.CreateEvent(ctx, testEvent)
+		require.NoError(t, err)
+
+		event.Reminders = []storage.EventReminder{{Before: 100, IsSend: true}}
+		for i := 0; i < 2; i++ {
+			err = memory.UpdateEventWithOutbox(ctx, &event, []storage.OutboxMessage{message})
+			require.NoError(t, err)
+		}
+
+		messages, err := memory.GetPendingOutboxMessages(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.Equal(t, int64(1), messages[0].ID)
+		require.Equal(t, testEvent.ID, messages[0].EventID)
+
+		events, err := memory.GetEvents(ctx)
+		require.NoError(t, err)
+		require.True(t, events[0].Reminders[0].IsSend)
+
+		err = memory.MarkOutboxMessageSent(ctx, messages[0].ID, time.Now())
+		require.NoError(t, err)
+
+		err = memory.MarkOutboxMessageSent(ctx, 2, time.Now())
+		require.ErrorIs(t, err, storage.ErrOutboxMessageDoesNotExist)
+
+		messages, err = memory.GetPendingOutboxMessages(ctx, 10)
+		require.NoError(t, err)
+		require.Empty(t, messages)
+	})
+
+	t.Run("outbox messages of deleted events", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := New()
+		event := *testEvent
+		err := memory.CreateEvent(ctx, &event)
+		require.NoError(t, err)
+
+		sent, err := storage.NewReminderMessage(event, &testEvent.Reminders[0], event.StartDate, event.Owner)
+		require.NoError(t, err)
+		pending, err := storage.NewReminderMessage(event, &testEvent.Reminders[0], event.StartDate.Add(time.Hour),
+			event.Owner)
+		require.NoError(t, err)
+		err = memory.UpdateEventWithOutbox(ctx, &event, []storage.OutboxMessage{sent, pending})
+		require.NoError(t, err)
+		require.NoError(t, memory.MarkOutboxMessageSent(ctx, 1, time.Now()))
+
+		err = memory.DeleteEvent(ctx, event.ID)
+		require.NoError(t, err)
+		messages, err := memory.GetPendingOutboxMessages(ctx, 10)
+		require.NoError(t, err)
+		require.Empty(t, messages)
+
+		err = memory.RestoreEvent(ctx, event.ID)
+		require.NoError(t, err)
+		messages, err = memory.GetPendingOutboxMessages(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.Equal(t, int64(2), messages[0].ID)
+
+		err = memory.DeleteEvent(ctx, event.ID)
+		require.NoError(t, err)
+		purged, err := memory.PurgeEvents(ctx, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, 1, purged)
+		require.ErrorIs(t, memory.MarkOutboxMessageSent(ctx, 1, time.Now()), storage.ErrOutboxMessageDoesNotExist)
+
+		event = *testEvent
+		err = memory.CreateEvent(ctx, &event)
+		require.NoError(t, err)
+		err = memory.UpdateEventWithOutbox(ctx, &event, []storage.OutboxMessage{sent})
+		require.NoError(t, err)
+
+		messages, err = memory.GetPendingOutboxMessages(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.Equal(t, int64(3), messages[0].ID)
+	})
+
+	t.Run("event attendees", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		memory := New()
+		event := *testEvent
+		event.Attendees = []storage.Attendee{
+			{User: "accepted_user", Status: storage.RSVPNeedsAction},
+			{User: "declined_user", Status: storage.RSVPNeedsAction},
+		}
+		err := memory.CreateEvent(ctx, &event)
+		require.NoError(t, err)
+
+		err = memory.SetAttendeeStatus(ctx, event.ID, "accepted_user", storage.RSVPAccepted)
+		require.NoError(t, err)
+		err = memory.SetAttendeeStatus(ctx, event.ID, "declined_user", storage.RSVPDeclined)
+		require.NoError(t, err)
+		err = memory.SetAttendeeStatus(ctx, event.ID, "stranger", storage.RSVPAccepted)
+		require.ErrorIs(t, err, storage.ErrAttendeeDoesNotExist)
+		err = memory.SetAttendeeStatus(ctx, "not_exists", "accepted_user", storage.RSVPAccepted)
+		require.ErrorIs(t, err, storage.ErrEventDoesNotExist)
+
+		for user, count := range map[string]int{testEvent.Owner: 1, "accepted_user": 1, "declined_user": 0, "stranger": 0} {
+			events, err := memory.GetEventsByPeriod(ctx, user, event.StartDate, event.StartDate.Add(1))
+			require.NoError(t, err)
+			require.Len(t, events, count, user)
+		}
+
+		events, err := memory.GetEvents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), events[0].Version)
+
+		sent := events[0]
+		sent.Reminders = []storage.EventReminder{{Before: 100, IsSend: true}}
+		sent.Attendees = nil
+		err = memory.UpdateEventWithOutbox(ctx, &sent, nil)
+		require.NoError(t, err)
+
+		events, err = memory.GetEvents(ctx)
+		require.NoError(t, err)
+		require.True(t, events[0].Reminders[0].IsSend)
+		require.Equal(t, []storage.Attendee{
+			{User: "accepted_user", Status: storage.RSVPAccepted},
+			{User: "declined_user", Status: storage.RSVPDeclined},
+		}, events[0].Attendees)
+		require.Equal(t, []string{testEvent.Owner, "accepted_user"}, events[0].Recipients())
+
+		updated := events[0]
+		updated.Attendees = []storage.Attendee{
+			{User: "declined_user", Status: storage.RSVPNeedsAction},
+			{User: "new_user", Status: storage.RSVPAccepted},
+		}
+		err = memory.UpdateEvent(ctx, &updated)
+		require.NoError(t, err)
+
+		stored, err := memory.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		require.Equal(t, []storage.Attendee{
+			{User: "declined_user", Status: storage.RSVPDeclined},
+			{User: "new_user", Status: storage.RSVPAccepted},
+		}, stored.Attendees)
+	})
+
+	t.Run("operations observed", func(t *testing.T) {
+		ctx := context.Background()
+		memory := New()
+		operations := []string{"CreateEvent", "CreateEventWithAudit", "GetEvent"}
+		samples := func() []uint64 {
+			counts := make([]uint64, 0, len(operations))
+			for _, operation := range operations {
+				var metric dto.Metric
+				histogram := metrics.StorageOperationDuration.WithLabelValues(operation).(prometheus.Histogram)
+				require.NoError(t, histogram.Write(&metric))
+				counts = append(counts, metric.GetHistogram().GetSampleCount())
+			}
+			return counts
+		}
+
+		before := samples()
+		event := *testEvent
+		require.NoError(t, memory.CreateEventWithAudit(ctx, &event, nil, nil))
+		_, err := memory.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+
+		require.Equal(t, []uint64{before[0], before[1] + 1, before[2] + 1}, samples())
+	})
 }
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxMessage is a reminder stored in the same transaction as the event state change
+// and published by the outbox relay afterwards.
+// A reminder is identified by its event, occurrence, recipient and the key of the event reminder,
+// so it is enqueued at most once.
+type OutboxMessage struct {
+	ID         int64     `json:"id" db:"id"`
+	EventID    string    `json:"eventId" db:"event_id"`
+	Occurrence time.Time `json:"occurrence" db:"occurrence"`
+	Recipient  string    `json:"recipient" db:"recipient"`
+	Reminder   string    `json:"reminder" db:"reminder"`
+	Payload    []byte    `json:"payload" db:"payload"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+	SentAt     time.Time `json:"sentAt" db:"sent_at"`
+}
+
+// Reminder is the payload of a reminder message: the event with StartDate set to the occurrence
+// and the user to remind.
+type Reminder struct {
+	Event
+	Recipient string `json:"recipient"`
+}
+
+// NewReminderMessage creates the message of the event reminder for the occurrence and the recipient.
+func NewReminderMessage(event Event,
+	reminder *EventReminder,
+	occurrence time.Time,
+	recipient string,
+) (OutboxMessage, error) {
+	event.StartDate = occurrence
+	payload, err := json.Marshal(&Reminder{Event: event, Recipient: recipient})
+	if err != nil {
+		return OutboxMessage{}, err
+	}
+
+	return OutboxMessage{
+		EventID:    event.ID,
+		Occurrence: occurrence.UTC(),
+		Recipient:  recipient,
+		Reminder:   reminder.Key(),
+		Payload:    payload,
+	}, nil
+}
+
+// ReminderID identifies the reminder across outbox redeliveries.
+// The messages enqueued before the events had several reminders have no reminder key.
+func (m *OutboxMessage) ReminderID() string {
+	id := m.EventID + "/" + m.Occurrence.UTC().Format(time.RFC3339) + "/" + m.Recipient
+	if m.Reminder != "" {
+		id += "/" + m.Reminder
+	}
+
+	return id
+}
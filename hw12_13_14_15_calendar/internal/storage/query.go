@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+type EventSort string
+
+const (
+	SortByStartDate EventSort = "startDate"
+	SortByTitle     EventSort = "title"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EventQuery selects stored events, recurring events are matched by their first occurrence.
+// Empty fields don't filter, Title and Description match case-insensitive substrings,
+// the StartDate range is [From, To).
+type EventQuery struct {
+	Owner       string
+	Title       string
+	Description string
+	From        time.Time
+	To          time.Time
+	Sort        EventSort
+	Desc        bool
+	Limit       int
+	Cursor      *EventCursor
+}
+
+// EventCursor points to the last event of the previous page.
+type EventCursor struct {
+	StartDate time.Time `json:"startDate,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	ID        string    `json:"id"`
+}
+
+type EventPage struct {
+	Events     []Event `json:"events"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+}
+
+// Match reports whether the event passes the query filters.
+func (q *EventQuery) Match(event *Event) bool {
+	if q.Owner != "" && event.Owner != q.Owner {
+		return false
+	}
+
+	if q.Title != "" && !containsFold(event.Title, q.Title) {
+		return false
+	}
+
+	if q.Description != "" && !containsFold(event.Description, q.Description) {
+		return false
+	}
+
+	if !q.From.IsZero() && event.StartDate.Before(q.From) {
+		return false
+	}
+
+	return q.To.IsZero() || event.StartDate.Before(q.To)
+}
+
+// Less reports whether event a goes before event b in the query sort order, ties are broken by ID.
+// The titles are compared bytewise.
+func (q *EventQuery) Less(a, b *Event) bool {
+	cmp := 0
+	switch q.Sort {
+	case SortByTitle:
+		cmp = strings.Compare(a.Title, b.Title)
+	case SortByStartDate:
+		cmp = a.StartDate.Compare(b.StartDate)
+	}
+
+	if cmp == 0 {
+		cmp = strings.Compare(a.ID, b.ID)
+	}
+
+	if q.Desc {
+		return cmp > 0
+	}
+
+	return cmp < 0
+}
+
+// AfterCursor reports whether the event goes after the query cursor.
+func (q *EventQuery) AfterCursor(event *Event) bool {
+	if q.Cursor == nil {
+		return true
+	}
+
+	return q.Less(&Event{ID: q.Cursor.ID, Title: q.Cursor.Title, StartDate: q.Cursor.StartDate}, event)
+}
+
+// NewEventPage cuts the first query Limit events, the events must be sorted and fetched with one extra event
+// to find out whether the next page exists.
+func NewEventPage(q *EventQuery, events []Event) EventPage {
+	if q.Limit <= 0 || len(events) <= q.Limit {
+		return EventPage{Events: events}
+	}
+
+	events = events[:q.Limit]
+	return EventPage{Events: events, NextCursor: EncodeCursor(q.Sort, &events[len(events)-1])}
+}
+
+func EncodeCursor(sort EventSort, event *Event) string {
+	cursor := EventCursor{ID: event.ID}
+	switch sort {
+	case SortByTitle:
+		cursor.Title = event.Title
+	case SortByStartDate:
+		cursor.StartDate = event.StartDate
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(cursor string) (*EventCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var result EventCursor
+	if err := json.Unmarshal(data, &result); err != nil || result.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &result, nil
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	//nolint:depguard
+	"github.com/teambition/rrule-go"
+)
+
+const (
+	// MaxRecurrenceCount bounds the occurrences of a recurrence rule, a COUNT above it is rejected
+	// and a stored rule with more occurrences is treated as endless.
+	MaxRecurrenceCount = 5000
+	// MaxRecurrenceSpan bounds how long after the event start an UNTIL of a recurrence rule can be.
+	MaxRecurrenceSpan = 10 * 366 * 24 * time.Hour
+)
+
+var ErrInvalidRRule = errors.New("invalid recurrence rule")
+
+func (e *Event) IsRecurring() bool {
+	return e.RRule != ""
+}
+
+// ValidateRecurrence checks that the event RRULE is a supported RFC 5545 recurrence rule
+// which ends within MaxRecurrenceCount occurrences and MaxRecurrenceSpan if it ends.
+func (e *Event) ValidateRecurrence() error {
+	if !e.IsRecurring() {
+		return nil
+	}
+
+	set, err := e.recurrence()
+	if err != nil {
+		return err
+	}
+
+	rule := set.GetRRule()
+	if rule.OrigOptions.Count > MaxRecurrenceCount {
+		return fmt.Errorf("%w: COUNT can't be greater than %d", ErrInvalidRRule, MaxRecurrenceCount)
+	}
+
+	until := rule.OrigOptions.Until
+	if !until.IsZero() && until.After(e.StartDate.Add(MaxRecurrenceSpan)) {
+		return fmt.Errorf("%w: UNTIL can't be over %d days after the start date",
+			ErrInvalidRRule, MaxRecurrenceSpan/(24*time.Hour))
+	}
+
+	return nil
+}
+
+// Occurrences returns the start dates of the event occurrences within [start, end).
+// A one-off event has a single occurrence at its StartDate.
+func (e *Event) Occurrences(start, end time.Time) ([]time.Time, error) {
+	if !e.IsRecurring() {
+		if e.StartDate.Before(start) || !e.StartDate.Before(end) {
+			return nil, nil
+		}
+
+		return []time.Time{e.StartDate}, nil
+	}
+
+	set, err := e.recurrence()
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := make([]time.Time, 0)
+	for _, occurrence := range set.Between(start, end, true) {
+		if occurrence.Before(end) {
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+
+	return occurrences, nil
+}
+
+// NextOccurrence returns the start date of the first occurrence after the given time
+// or zero time if the event does not occur anymore.
+func (e *Event) NextOccurrence(after time.Time) (time.Time, error) {
+	if !e.IsRecurring() {
+		if e.StartDate.After(after) {
+			return e.StartDate, nil
+		}
+
+		return time.Time{}, nil
+	}
+
+	set, err := e.recurrence()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return set.After(after, false), nil
+}
+
+// PreviousOccurrence returns the start date of the last occurrence before the given time
+// or zero time if the event does not occur before it.
+func (e *Event) PreviousOccurrence(before time.Time) (time.Time, error) {
+	if !e.IsRecurring() {
+		if e.StartDate.Before(before) {
+			return e.StartDate, nil
+		}
+
+		return time.Time{}, nil
+	}
+
+	set, err := e.recurrence()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return set.Before(before, false), nil
+}
+
+func (e *Event) recurrence() (*rrule.Set, error) {
+	option, err := rrule.StrToROption(e.RRule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRRule, err)
+	}
+
+	switch option.Freq { //nolint:exhaustive
+	case rrule.DAILY, rrule.WEEKLY, rrule.MONTHLY:
+	default:
+		return nil, fmt.Errorf("%w: frequency must be DAILY, WEEKLY or MONTHLY", ErrInvalidRRule)
+	}
+
+	option.Dtstart = e.StartDate
+	rule, err := rrule.NewRRule(*option)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRRule, err)
+	}
+
+	set := &rrule.Set{}
+	set.RRule(rule)
+	set.SetExDates(e.ExDates)
+	return set, nil
+}
+
+// ExpandByPeriod returns the events occurring within [start, end),
+// every occurrence of a recurring event is returned as a copy with its own StartDate.
+func ExpandByPeriod(events []Event, start, end time.Time) ([]Event, error) {
+	result := make([]Event, 0, len(events))
+	for _, event := range events {
+		occurrences, err := event.Occurrences(start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, occurrence := range occurrences {
+			eventOccurrence := event
+			eventOccurrence.StartDate = occurrence
+			result = append(result, eventOccurrence)
+		}
+	}
+
+	return result, nil
+}
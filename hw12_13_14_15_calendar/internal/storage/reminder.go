@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"strconv"
+	"time"
+)
+
+// EventReminder fires Before minutes before every event occurrence starts or once At the absolute time.
+// The scheduler keeps the sent state of every reminder: SentUntil is the start of the last reminded
+// occurrence and IsSend is set once the reminder has nothing left to send.
+type EventReminder struct {
+	Before    int64      `json:"before,omitempty"`
+	At        *time.Time `json:"at,omitempty"`
+	IsSend    bool       `json:"isSend"`
+	SentUntil time.Time  `json:"sentUntil"`
+}
+
+// Key identifies the reminder within the event by its trigger.
+func (r *EventReminder) Key() string {
+	if r.At != nil {
+		return "at:" + r.At.UTC().Format(time.RFC3339)
+	}
+
+	return "before:" + strconv.FormatInt(r.Before, 10)
+}
+
+// Reminder returns the event reminder with the given key, nil if the event has none.
+func (e *Event) Reminder(key string) *EventReminder {
+	for i := range e.Reminders {
+		if e.Reminders[i].Key() == key {
+			return &e.Reminders[i]
+		}
+	}
+
+	return nil
+}
+
+// ReminderDue returns when the reminder is due next, zero time if it has nothing left to send.
+// An absolute reminder is due At its time, a relative one Before minutes before the start
+// of the first occurrence which isn't reminded yet.
+func (e *Event) ReminderDue(reminder *EventReminder) (time.Time, error) {
+	if reminder.IsSend {
+		return time.Time{}, nil
+	}
+
+	if reminder.At != nil {
+		return *reminder.At, nil
+	}
+
+	remindBefore := time.Minute * time.Duration(reminder.Before)
+	if !e.IsRecurring() {
+		return e.StartDate.Add(-remindBefore), nil
+	}
+
+	after := reminder.SentUntil
+	if after.IsZero() {
+		after = e.StartDate.Truncate(time.Second).Add(-time.Second)
+	}
+
+	next, err := e.NextOccurrence(after)
+	if err != nil || next.IsZero() {
+		return time.Time{}, err
+	}
+
+	return next.Add(-remindBefore), nil
+}
+
+// ReminderOccurrence returns the occurrence an absolute reminder firing at the given time reminds of:
+// the start date of a one-off event or the first occurrence of a recurring event which starts since then,
+// zero time if the recurring event does not occur anymore.
+func (e *Event) ReminderOccurrence(at time.Time) (time.Time, error) {
+	if !e.IsRecurring() {
+		return e.StartDate, nil
+	}
+
+	return e.NextOccurrence(at.Truncate(time.Second).Add(-time.Second))
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"time"
+
+	//nolint:depguard
+	"github.com/teambition/rrule-go"
+)
+
+// The storages index the events by their reminder due time and their last occurrence,
+// so the scheduler queries the due reminders and the ended events instead of scanning all the events.
+
+// NextReminderDue returns when the first event reminder is due, zero time if no reminder is left to send.
+func (e *Event) NextReminderDue() (time.Time, error) {
+	due := time.Time{}
+	for i := range e.Reminders {
+		reminderDue, err := e.ReminderDue(&e.Reminders[i])
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if !reminderDue.IsZero() && (due.IsZero() || reminderDue.Before(due)) {
+			due = reminderDue
+		}
+	}
+
+	return due, nil
+}
+
+// LastOccurrence returns the start date of the last event occurrence,
+// zero time if the event recurs endlessly or more than MaxRecurrenceCount times.
+func (e *Event) LastOccurrence() (time.Time, error) {
+	if !e.IsRecurring() {
+		return e.StartDate, nil
+	}
+
+	set, err := e.recurrence()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	option, err := rrule.StrToROption(e.RRule)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if option.Count == 0 && option.Until.IsZero() {
+		return time.Time{}, nil
+	}
+
+	last := e.StartDate
+	next := set.Iterator()
+	for i := 0; ; i++ {
+		occurrence, ok := next()
+		if !ok {
+			return last, nil
+		}
+
+		if i == MaxRecurrenceCount {
+			return time.Time{}, nil
+		}
+
+		last = occurrence
+	}
+}
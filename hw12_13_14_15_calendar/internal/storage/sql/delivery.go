@@ -0,0 +1,30 @@
+package sqlstorage
+
+//nolint:depguard
+import (
+	"context"
+	"time"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/metrics"
+)
+
+// IsReminderDelivered reports whether the reminder was delivered through the channel.
+func (s *Storage) IsReminderDelivered(ctx context.Context, reminderID, channel string) (bool, error) {
+	defer metrics.ObserveStorage("IsReminderDelivered", time.Now())
+
+	var delivered bool
+	err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM reminder_delivery WHERE reminder_id=$1 AND channel=$2)",
+		reminderID, channel).Scan(&delivered)
+	return delivered, err
+}
+
+// MarkReminderDelivered records the reminder was delivered through the channel, marking it again is a no-op.
+func (s *Storage) MarkReminderDelivered(ctx context.Context, reminderID, channel string) error {
+	defer metrics.ObserveStorage("MarkReminderDelivered", time.Now())
+
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO reminder_delivery (reminder_id, channel) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		reminderID, channel)
+	return err
+}
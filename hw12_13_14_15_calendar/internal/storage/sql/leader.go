@@ -0,0 +1,87 @@
+package sqlstorage
+
+//nolint:depguard
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Elector elects the leader by the Postgres session advisory lock. The leader holds the lock
+// on a dedicated connection, Postgres releases it when the leader dies or loses the connection,
+// then the next replica asking takes over. A connection which may hold the lock is discarded instead of
+// being returned to the pool, where the lock would outlive the leadership.
+type Elector struct {
+	db     *sql.DB
+	lockID int64
+	mu     sync.Mutex
+	conn   *sql.Conn
+}
+
+func (s *Storage) NewElector(lockID int64) *Elector {
+	return &Elector{db: s.db, lockID: lockID}
+}
+
+// IsLeader checks the lock connection is alive or tries to take the lock,
+// returns storage.ErrNotLeader if another replica holds it.
+func (e *Elector) IsLeader(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn != nil {
+		if err := e.conn.PingContext(ctx); err == nil {
+			return nil
+		}
+
+		discard(e.conn)
+		e.conn = nil
+	}
+
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	var locked bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", e.lockID).Scan(&locked)
+	if err != nil {
+		discard(conn)
+		return err
+	}
+
+	if !locked {
+		_ = conn.Close()
+		return storage.ErrNotLeader
+	}
+
+	e.conn = conn
+	return nil
+}
+
+// Resign releases the lock if the replica holds it, the connection is discarded even if the unlock fails,
+// so Postgres releases the lock with the session.
+func (e *Elector) Resign(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		return nil
+	}
+
+	defer func() {
+		discard(e.conn)
+		e.conn = nil
+	}()
+
+	_, err := e.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", e.lockID)
+	return err
+}
+
+// discard closes the connection instead of returning it to the pool.
+func discard(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = conn.Close()
+}
@@ -0,0 +1,222 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	//nolint:depguard
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	errConnLost   = errors.New("connection lost")
+	errUnlockFail = errors.New("unlock failed")
+)
+
+// lockServer emulates the Postgres session advisory locks: a lock belongs to the session which took it
+// and is released by the unlock or when the session is closed.
+type lockServer struct {
+	mu         sync.Mutex
+	holders    map[int64]*lockConn
+	failUnlock bool
+}
+
+func (s *lockServer) Connect(context.Context) (driver.Conn, error) {
+	return &lockConn{server: s}, nil
+}
+
+func (s *lockServer) Driver() driver.Driver {
+	return nil
+}
+
+func (s *lockServer) holder(lockID int64) *lockConn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.holders[lockID]
+}
+
+type lockConn struct {
+	server *lockServer
+	lost   bool
+	closed bool
+}
+
+func (c *lockConn) Prepare(string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *lockConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *lockConn) Close() error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	c.closed = true
+	for lockID, holder := range c.server.holders {
+		if holder == c {
+			delete(c.server.holders, lockID)
+		}
+	}
+
+	return nil
+}
+
+func (c *lockConn) Ping(context.Context) error {
+	if c.lost {
+		return errConnLost
+	}
+
+	return nil
+}
+
+func (c *lockConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.Contains(query, "pg_try_advisory_lock") {
+		return nil, driver.ErrSkip
+	}
+
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	lockID := args[0].Value.(int64)
+	holder, ok := c.server.holders[lockID]
+	if !ok {
+		c.server.holders[lockID] = c
+	}
+
+	return &boolRows{value: !ok || holder == c}, nil
+}
+
+func (c *lockConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if !strings.Contains(query, "pg_advisory_unlock") {
+		return nil, driver.ErrSkip
+	}
+
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	if c.server.failUnlock {
+		return nil, errUnlockFail
+	}
+
+	lockID := args[0].Value.(int64)
+	if c.server.holders[lockID] == c {
+		delete(c.server.holders, lockID)
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+type boolRows struct {
+	value bool
+	read  bool
+}
+
+func (r *boolRows) Columns() []string {
+	return []string{"locked"}
+}
+
+func (r *boolRows) Close() error {
+	return nil
+}
+
+func (r *boolRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+
+	r.read = true
+	dest[0] = r.value
+	return nil
+}
+
+func TestElector(t *testing.T) {
+	const lockID = 42
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*lockServer, *Elector, *Elector) {
+		t.Helper()
+
+		server := &lockServer{holders: make(map[int64]*lockConn)}
+		db := sql.OpenDB(server)
+		t.Cleanup(func() { db.Close() })
+
+		return server, &Elector{db: db, lockID: lockID}, &Elector{db: db, lockID: lockID}
+	}
+
+	lockConnOf := func(t *testing.T, elector *Elector) *lockConn {
+		t.Helper()
+
+		var conn *lockConn
+		require.NoError(t, elector.conn.Raw(func(driverConn any) error {
+			conn = driverConn.(*lockConn)
+			return nil
+		}))
+		return conn
+	}
+
+	t.Run("one leader at a time", func(t *testing.T) {
+		server, first, second := setup(t)
+
+		require.NoError(t, first.IsLeader(ctx))
+		require.NoError(t, first.IsLeader(ctx))
+		require.ErrorIs(t, second.IsLeader(ctx), storage.ErrNotLeader)
+		require.Same(t, lockConnOf(t, first), server.holder(lockID))
+		require.Nil(t, second.conn)
+	})
+
+	t.Run("leadership passed on resign", func(t *testing.T) {
+		server, first, second := setup(t)
+
+		require.NoError(t, first.IsLeader(ctx))
+		conn := lockConnOf(t, first)
+		require.NoError(t, first.Resign(ctx))
+		require.True(t, conn.closed)
+		require.Nil(t, first.conn)
+
+		require.NoError(t, second.IsLeader(ctx))
+		require.ErrorIs(t, first.IsLeader(ctx), storage.ErrNotLeader)
+		require.Same(t, lockConnOf(t, second), server.holder(lockID))
+	})
+
+	t.Run("lost connection discarded", func(t *testing.T) {
+		server, first, second := setup(t)
+
+		require.NoError(t, first.IsLeader(ctx))
+		conn := lockConnOf(t, first)
+		conn.lost = true
+
+		require.NoError(t, first.IsLeader(ctx))
+		require.True(t, conn.closed)
+		require.NotSame(t, conn, server.holder(lockID))
+		require.Same(t, lockConnOf(t, first), server.holder(lockID))
+		require.ErrorIs(t, second.IsLeader(ctx), storage.ErrNotLeader)
+	})
+
+	t.Run("lock released if unlock fails", func(t *testing.T) {
+		server, first, second := setup(t)
+
+		require.NoError(t, first.IsLeader(ctx))
+		server.failUnlock = true
+		require.ErrorIs(t, first.Resign(ctx), errUnlockFail)
+		require.Nil(t, server.holder(lockID))
+
+		require.NoError(t, second.IsLeader(ctx))
+	})
+
+	t.Run("resign without leadership", func(t *testing.T) {
+		_, first, _ := setup(t)
+
+		require.NoError(t, first.Resign(ctx))
+	})
+}
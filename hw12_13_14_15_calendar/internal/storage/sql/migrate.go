@@ -0,0 +1,314 @@
+package sqlstorage
+
+//nolint:depguard
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/migrations"
+	"github.com/jackc/pgtype"
+	"go.uber.org/zap"
+)
+
+// migrationLockID is the Postgres advisory lock key held while migrations are applied,
+// so several services started at once don't migrate the database concurrently.
+const migrationLockID = 7_290_130_214_161
+
+// upSteps complete the up SQL of the migration with the version in its transaction,
+// they backfill the values only the event model computes.
+var upSteps = map[int64]func(ctx context.Context, tx *sql.Tx) error{
+	12: backfillLastOccurrence,
+}
+
+var (
+	ErrInvalidMigrateCommand = errors.New("invalid migrate command, expected: migrate up | migrate down [steps]")
+	ErrInMemoryMigrate       = errors.New("in-memory storage has no migrations")
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrate connects to the database and runs the migrate subcommand:
+// "up" applies all pending migrations, "down [steps]" rolls back the last applied ones (one by default).
+func Migrate(ctx context.Context, config *configs.DBConfig, args []string) ([]Migration, error) {
+	if len(args) == 0 || len(args) > 2 || (args[0] != "up" && args[0] != "down") || (args[0] == "up" && len(args) > 1) {
+		return nil, ErrInvalidMigrateCommand
+	}
+
+	steps := 1
+	if len(args) == 2 {
+		var err error
+		steps, err = strconv.Atoi(args[1])
+		if err != nil || steps < 1 {
+			return nil, ErrInvalidMigrateCommand
+		}
+	}
+
+	storage := New()
+	if err := storage.Connect(ctx, config); err != nil {
+		return nil, err
+	}
+	defer storage.Close(ctx)
+
+	if args[0] == "up" {
+		return storage.MigrateUp(ctx)
+	}
+
+	return storage.MigrateDown(ctx, steps)
+}
+
+// MigrateCommand runs the migrate subcommand of a service, see Migrate, and logs the applied migrations.
+func MigrateCommand(ctx context.Context, config *configs.DBConfig, args []string, logg *zap.Logger) error {
+	if config.InMemory {
+		return ErrInMemoryMigrate
+	}
+
+	migrations, err := Migrate(ctx, config, args)
+	for _, migration := range migrations {
+		logg.Info("database migration applied", zap.Int64("version", migration.Version), zap.String("name", migration.Name))
+	}
+
+	return err
+}
+
+// MigrateUp applies all pending migrations and returns them.
+func (s *Storage) MigrateUp(ctx context.Context) ([]Migration, error) {
+	return s.migrate(ctx, func(available []Migration, applied map[int64]bool) []Migration {
+		pending := make([]Migration, 0)
+		for _, migration := range available {
+			if !applied[migration.Version] {
+				pending = append(pending, migration)
+			}
+		}
+
+		return pending
+	}, true)
+}
+
+// MigrateDown rolls back the last steps applied migrations and returns them.
+func (s *Storage) MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	return s.migrate(ctx, func(available []Migration, applied map[int64]bool) []Migration {
+		rollback := make([]Migration, 0, steps)
+		for i := len(available) - 1; i >= 0 && len(rollback) < steps; i-- {
+			if applied[available[i].Version] {
+				rollback = append(rollback, available[i])
+			}
+		}
+
+		return rollback
+	}, false)
+}
+
+func (s *Storage) migrate(
+	ctx context.Context,
+	choose func(available []Migration, applied map[int64]bool) []Migration,
+	up bool,
+) ([]Migration, error) {
+	available, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return nil, fmt.Errorf("migration lock failed: %w", err)
+	}
+	//nolint:errcheck
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+    		version BIGINT PRIMARY KEY,
+    		name varchar(256) not null,
+    		applied_at timestamp not null default now()
+		)`)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make([]Migration, 0)
+	for _, migration := range choose(available, applied) {
+		if err := applyMigration(ctx, conn, migration, up); err != nil {
+			return done, fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]bool, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+func applyMigration(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	if up {
+		_, err = tx.ExecContext(ctx, migration.Up)
+		if step := upSteps[migration.Version]; err == nil && step != nil {
+			err = step(ctx, tx)
+		}
+
+		if err == nil {
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+		}
+	} else {
+		_, err = tx.ExecContext(ctx, migration.Down)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// backfillLastOccurrence stores the last occurrence of the recurring events ending by COUNT or UNTIL,
+// 0009 left it unset, so the scheduler never purged them. An event with an invalid rule is left as is.
+func backfillLastOccurrence(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, start_date, rrule, ex_dates FROM event WHERE rrule <> '' AND last_occurrence IS NULL")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	events := make([]storage.Event, 0)
+	for rows.Next() {
+		var event storage.Event
+		var eventExDates pgtype.TimestampArray
+		if err := rows.Scan(&event.ID, &event.StartDate, &event.RRule, &eventExDates); err != nil {
+			return err
+		}
+
+		if err := eventExDates.AssignTo(&event.ExDates); err != nil {
+			return err
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		last, err := event.LastOccurrence()
+		if err != nil || last.IsZero() {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE event SET last_occurrence=$2 WHERE id=$1", event.ID, last.UTC())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadMigrations reads <version>_<name>.up.sql and <version>_<name>.down.sql pairs sorted by version.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, file := range files {
+		name, up := strings.CutSuffix(file, ".up.sql")
+		if !up {
+			var down bool
+			name, down = strings.CutSuffix(file, ".down.sql")
+			if !down {
+				return nil, fmt.Errorf("migration %s: expected .up.sql or .down.sql suffix", file)
+			}
+		}
+
+		versionStr, name, ok := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s: expected <version>_<name> file name", file)
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration %s: version %d is already used by %s", file, version, migration.Name)
+		}
+
+		if up {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s: both up and down files are required", migration.Version, migration.Name)
+		}
+
+		result = append(result, *migration)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package sqlstorage
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/migrations"
+	//nolint:depguard
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("embedded migrations loaded", func(t *testing.T) {
+		loaded, err := LoadMigrations(migrations.FS)
+		require.NoError(t, err)
+		require.NotEmpty(t, loaded)
+
+		for i, migration := range loaded {
+			require.Equal(t, int64(i+1), migration.Version)
+			require.NotEmpty(t, migration.Up)
+			require.NotEmpty(t, migration.Down)
+		}
+	})
+
+	t.Run("migrations sorted by version", func(t *testing.T) {
+		loaded, err := LoadMigrations(fstest.MapFS{
+			"0010_second.up.sql":   {Data: []byte("up 10")},
+			"0010_second.down.sql": {Data: []byte("down 10")},
+			"0002_first.up.sql":    {Data: []byte("up 2")},
+			"0002_first.down.sql":  {Data: []byte("down 2")},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []Migration{
+			{Version: 2, Name: "first", Up: "up 2", Down: "down 2"},
+			{Version: 10, Name: "second", Up: "up 10", Down: "down 10"},
+		}, loaded)
+	})
+
+	t.Run("invalid migrations rejected", func(t *testing.T) {
+		_, err := LoadMigrations(fstest.MapFS{"0001_first.up.sql": {Data: []byte("up")}})
+		require.Error(t, err)
+
+		_, err = LoadMigrations(fstest.MapFS{"first.up.sql": {Data: []byte("up")}})
+		require.Error(t, err)
+
+		_, err = LoadMigrations(fstest.MapFS{
+			"0001_first.up.sql":    {Data: []byte("up")},
+			"0001_second.down.sql": {Data: []byte("down")},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("invalid migrate command rejected", func(t *testing.T) {
+		for _, args := range [][]string{{}, {"sideways"}, {"up", "1"}, {"down", "zero"}, {"down", "0"}} {
+			_, err := Migrate(context.Background(), nil, args)
+			require.ErrorIs(t, err, ErrInvalidMigrateCommand, args)
+		}
+	})
+}
@@ -4,11 +4,17 @@ package sqlstorage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/configs"
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/metrics"
 	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
 	_ "github.com/jackc/pgx/v4/stdlib" // Postgres driver.
 )
 
@@ -37,11 +43,42 @@ func (s *Storage) Connect(ctx context.Context, config *configs.DBConfig) (err er
 	return nil
 }
 
+// Ping checks the database is reachable.
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
 func (s *Storage) Close(_ context.Context) error {
 	return s.db.Close()
 }
 
 func (s *Storage) CreateEvent(ctx context.Context, event *storage.Event) error {
+	defer metrics.ObserveStorage("CreateEvent", time.Now())
+
+	return s.createEvent(ctx, event, nil, nil)
+}
+
+// CreateEventWithAudit creates the event and appends the entry to its audit log in one transaction,
+// the conflict check, if any, is run in the same transaction.
+func (s *Storage) CreateEventWithAudit(ctx context.Context,
+	event *storage.Event,
+	entry *storage.AuditEntry,
+	check *storage.ConflictCheck,
+) error {
+	defer metrics.ObserveStorage("CreateEventWithAudit", time.Now())
+
+	return s.createEvent(ctx, event, entry, check)
+}
+
+func (s *Storage) createEvent(ctx context.Context,
+	event *storage.Event,
+	entry *storage.AuditEntry,
+	check *storage.ConflictCheck,
+) error {
+	if !isEventID(event.ID) {
+		return storage.ErrInvalidEventID
+	}
+
 	isExist, err := s.exists(ctx, event.ID)
 	if err != nil {
 		return err
@@ -51,28 +88,800 @@ func (s *Storage) CreateEvent(ctx context.Context, event *storage.Event) error {
 		return storage.ErrEventAlreadyExist
 	}
 
-	_, err = s.db.ExecContext(
+	due, lastOccurrence, err := scheduleTimes(event)
+	if err != nil {
+		return err
+	}
+
+	reminders, err := json.Marshal(eventReminders(event))
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	err = s.checkConflicts(ctx, tx, event, check)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
 		ctx,
-		`INSERT INTO event (id, title, start_date, duration, description,  owner,  remind_at, is_send)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		`INSERT INTO event (id, title, start_date, duration, description,  owner,
+				rrule, ex_dates, reminders, remind_due, last_occurrence, version)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, 1)`,
 		event.ID,
 		event.Title,
 		event.StartDate,
 		event.Duration,
 		event.Description,
 		event.Owner,
-		event.RemindAt,
-		event.IsSend,
+		event.RRule,
+		exDates(event.ExDates),
+		string(reminders),
+		due,
+		lastOccurrence,
 	)
 	if err != nil {
 		return err
 	}
 
+	err = insertAttendees(ctx, tx, event)
+	if err != nil {
+		return err
+	}
+
+	err = insertAudit(ctx, tx, entry)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	event.Version = 1
 	return nil
 }
 
 func (s *Storage) UpdateEvent(ctx context.Context, event *storage.Event) error {
-	isExist, err := s.exists(ctx, event.ID)
+	defer metrics.ObserveStorage("UpdateEvent", time.Now())
+
+	return s.saveEvent(ctx, event, nil, nil)
+}
+
+// UpdateEventWithAudit updates the event and appends the entry to its audit log in one transaction,
+// the conflict check, if any, is run in the same transaction.
+func (s *Storage) UpdateEventWithAudit(ctx context.Context,
+	event *storage.Event,
+	entry *storage.AuditEntry,
+	check *storage.ConflictCheck,
+) error {
+	defer metrics.ObserveStorage("UpdateEventWithAudit", time.Now())
+
+	return s.saveEvent(ctx, event, entry, check)
+}
+
+func (s *Storage) saveEvent(ctx context.Context,
+	event *storage.Event,
+	entry *storage.AuditEntry,
+	check *storage.ConflictCheck,
+) error {
+	if !isEventID(event.ID) {
+		return storage.ErrEventDoesNotExist
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	err = s.checkConflicts(ctx, tx, event, check)
+	if err != nil {
+		return err
+	}
+
+	updated, err := updateEvent(ctx, tx, event)
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		return s.updateFailure(ctx, event.ID)
+	}
+
+	// the event row is locked by the update, so no reply is stored in the meantime
+	stored, err := eventAttendees(ctx, tx, event.ID)
+	if err != nil {
+		return err
+	}
+
+	event.KeepReplies(stored)
+	err = mergeAttendees(ctx, tx, event)
+	if err != nil {
+		return err
+	}
+
+	err = insertAudit(ctx, tx, entry)
+	if err != nil {
+		return err
+	}
+
+	return commitUpdate(tx, event)
+}
+
+// SetAttendeeStatus stores the reply of the attendee to the event invitation and appends it to the audit log
+// in one transaction. The event row is locked first, so the reply is serialized with the owner updates
+// and the audited attendees are the ones the reply changed.
+func (s *Storage) SetAttendeeStatus(ctx context.Context, eventID, user string, status storage.RSVPStatus) error {
+	defer metrics.ObserveStorage("SetAttendeeStatus", time.Now())
+
+	if !isEventID(eventID) {
+		return storage.ErrEventDoesNotExist
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	var id string
+	err = tx.QueryRowContext(ctx, "SELECT id FROM event WHERE id=$1 AND deleted_at IS NULL FOR UPDATE", eventID).
+		Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrEventDoesNotExist
+	}
+
+	if err != nil {
+		return err
+	}
+
+	before := storage.Event{ID: eventID}
+	before.Attendees, err = eventAttendees(ctx, tx, eventID)
+	if err != nil {
+		return err
+	}
+
+	after := before
+	after.Attendees = make([]storage.Attendee, len(before.Attendees))
+	copy(after.Attendees, before.Attendees)
+	attendee := after.Attendee(user)
+	if attendee == nil {
+		return storage.ErrAttendeeDoesNotExist
+	}
+
+	attendee.Status = status
+	_, err = tx.ExecContext(ctx, "UPDATE event_attendee SET status=$1 WHERE event_id=$2 AND attendee=$3",
+		status, eventID, user)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE event SET version=version+1 WHERE id=$1", eventID)
+	if err != nil {
+		return err
+	}
+
+	entry, err := storage.NewAuditEntry(storage.AuditRespond, user, &before, &after, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = insertAudit(ctx, tx, &entry)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateEventWithOutbox updates the event and enqueues its reminders in one transaction,
+// reminders which are already enqueued are skipped. The stored attendees are kept.
+func (s *Storage) UpdateEventWithOutbox(ctx context.Context,
+	event *storage.Event,
+	messages []storage.OutboxMessage,
+) error {
+	defer metrics.ObserveStorage("UpdateEventWithOutbox", time.Now())
+
+	if !isEventID(event.ID) {
+		return storage.ErrEventDoesNotExist
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	updated, err := updateEvent(ctx, tx, event)
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		return s.updateFailure(ctx, event.ID)
+	}
+
+	for _, message := range messages {
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO outbox (event_id, occurrence, recipient, reminder, payload)
+				VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (event_id, occurrence, recipient, reminder) DO NOTHING`,
+			message.EventID,
+			message.Occurrence,
+			message.Recipient,
+			message.Reminder,
+			message.Payload,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return commitUpdate(tx, event)
+}
+
+// GetPendingOutboxMessages returns the messages which aren't sent yet, the oldest first. The messages of
+// the events in the trash are held back until the events are restored.
+func (s *Storage) GetPendingOutboxMessages(ctx context.Context, limit int) ([]storage.OutboxMessage, error) {
+	defer metrics.ObserveStorage("GetPendingOutboxMessages", time.Now())
+
+	statement := `SELECT outbox.id, event_id, occurrence, recipient, reminder, payload, created_at
+		FROM outbox JOIN event ON event.id = outbox.event_id::uuid
+		WHERE sent_at IS NULL AND deleted_at IS NULL ORDER BY outbox.id`
+	if limit > 0 {
+		statement += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]storage.OutboxMessage, 0)
+	for rows.Next() {
+		var message storage.OutboxMessage
+		err := rows.Scan(&message.ID, &message.EventID, &message.Occurrence, &message.Recipient, &message.Reminder,
+			&message.Payload, &message.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+func (s *Storage) MarkOutboxMessageSent(ctx context.Context, id int64, sentAt time.Time) error {
+	defer metrics.ObserveStorage("MarkOutboxMessageSent", time.Now())
+
+	result, err := s.db.ExecContext(ctx, "UPDATE outbox SET sent_at=$1 WHERE id=$2", sentAt.UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return storage.ErrOutboxMessageDoesNotExist
+	}
+
+	return nil
+}
+
+// DeleteEvent moves the event to the trash.
+func (s *Storage) DeleteEvent(ctx context.Context, id string) error {
+	defer metrics.ObserveStorage("DeleteEvent", time.Now())
+
+	return s.updateTrashed(ctx, nil, deleteStatement, id, time.Now().UTC())
+}
+
+// DeleteEventWithAudit moves the event to the trash and appends the entry to its audit log in one transaction.
+func (s *Storage) DeleteEventWithAudit(ctx context.Context, id string, entry *storage.AuditEntry) error {
+	defer metrics.ObserveStorage("DeleteEventWithAudit", time.Now())
+
+	return s.updateTrashed(ctx, entry, deleteStatement, id, time.Now().UTC())
+}
+
+// DeleteEvents moves the events to the trash and returns how many of them are moved.
+func (s *Storage) DeleteEvents(ctx context.Context, ids []string) (int, error) {
+	defer metrics.ObserveStorage("DeleteEvents", time.Now())
+
+	validIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if isEventID(id) {
+			validIDs = append(validIDs, id)
+		}
+	}
+
+	var eventIDs pgtype.TextArray
+	_ = eventIDs.Set(validIDs)
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE event SET deleted_at=$2, version=version+1 WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL",
+		&eventIDs, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(affected), nil
+}
+
+// RestoreEvent takes the event out of the trash.
+func (s *Storage) RestoreEvent(ctx context.Context, id string) error {
+	defer metrics.ObserveStorage("RestoreEvent", time.Now())
+
+	return s.updateTrashed(ctx, nil, restoreStatement, id)
+}
+
+// RestoreEventWithAudit takes the event out of the trash and appends the entry to its audit log in one transaction.
+func (s *Storage) RestoreEventWithAudit(ctx context.Context, id string, entry *storage.AuditEntry) error {
+	defer metrics.ObserveStorage("RestoreEventWithAudit", time.Now())
+
+	return s.updateTrashed(ctx, entry, restoreStatement, id)
+}
+
+// GetDeletedEvents returns the owner events in the trash, the last deleted first.
+func (s *Storage) GetDeletedEvents(ctx context.Context, owner string) ([]storage.Event, error) {
+	defer metrics.ObserveStorage("GetDeletedEvents", time.Now())
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+eventColumns+` FROM event
+			WHERE owner=$1 AND deleted_at IS NOT NULL
+			ORDER BY deleted_at DESC, id`,
+		owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.scanEvents(ctx, rows)
+}
+
+// PurgeEvents removes the events deleted before the given time and their outbox messages permanently.
+func (s *Storage) PurgeEvents(ctx context.Context, deletedBefore time.Time) (int, error) {
+	defer metrics.ObserveStorage("PurgeEvents", time.Now())
+
+	var purged int
+	err := s.db.QueryRowContext(ctx,
+		`WITH purged AS (DELETE FROM event WHERE deleted_at < $1 RETURNING id),
+				dropped AS (DELETE FROM outbox WHERE event_id IN (SELECT id::text FROM purged))
+			SELECT count(*) FROM purged`,
+		deletedBefore.UTC()).Scan(&purged)
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+const (
+	deleteStatement  = "UPDATE event SET deleted_at=$2, version=version+1 WHERE id=$1 AND deleted_at IS NULL"
+	restoreStatement = "UPDATE event SET deleted_at=NULL, version=version+1 WHERE id=$1 AND deleted_at IS NOT NULL"
+)
+
+// updateTrashed moves the event to or out of the trash and appends the entry to its audit log, if any.
+// The statement takes the event ID first.
+func (s *Storage) updateTrashed(
+	ctx context.Context,
+	entry *storage.AuditEntry,
+	statement string,
+	id string,
+	args ...any,
+) error {
+	if !isEventID(id) {
+		return storage.ErrEventDoesNotExist
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, statement, append([]any{id}, args...)...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return storage.ErrEventDoesNotExist
+	}
+
+	err = insertAudit(ctx, tx, entry)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+//nolint:lll
+func (s *Storage) GetEventsByPeriod(ctx context.Context, owner string, startTime time.Time, endTime time.Time) ([]storage.Event, error) {
+	defer metrics.ObserveStorage("GetEventsByPeriod", time.Now())
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT `+eventColumns+`
+			FROM event
+			WHERE (owner = $1 OR id IN (
+					SELECT event_id FROM event_attendee WHERE attendee = $1 AND status <> $4
+				))
+				AND deleted_at IS NULL
+				AND start_date < $3 AND (start_date >= $2 OR rrule <> '')`,
+		owner, startTime, endTime, storage.RSVPDeclined,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.scanEvents(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.ExpandByPeriod(events, startTime, endTime)
+}
+
+// SharesEvent reports whether one of the users is invited to an event of the other and hasn't declined it.
+func (s *Storage) SharesEvent(ctx context.Context, user, other string) (bool, error) {
+	defer metrics.ObserveStorage("SharesEvent", time.Now())
+
+	var shared bool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS(
+			SELECT * FROM event JOIN event_attendee ON event_attendee.event_id = event.id
+				WHERE event.deleted_at IS NULL AND event_attendee.status <> $3
+					AND (event.owner = $1 AND event_attendee.attendee = $2
+						OR event.owner = $2 AND event_attendee.attendee = $1)
+		)`,
+		user, other, storage.RSVPDeclined).Scan(&shared)
+	if err != nil {
+		return false, err
+	}
+
+	return shared, nil
+}
+
+// GetOwnerEventsBetween returns the owner events which may occur within [start, end) sorted by the start date,
+// a recurring event is returned once with its recurrence rule.
+func (s *Storage) GetOwnerEventsBetween(ctx context.Context,
+	owner string,
+	start time.Time,
+	end time.Time,
+) ([]storage.Event, error) {
+	defer metrics.ObserveStorage("GetOwnerEventsBetween", time.Now())
+
+	return s.ownerEventsBetween(ctx, s.db, owner, start, end)
+}
+
+// GetAcceptedEventsBetween returns the events the user accepted the invitations to which may occur
+// within [start, end) sorted by the start date, a recurring event is returned once with its recurrence rule.
+func (s *Storage) GetAcceptedEventsBetween(ctx context.Context,
+	user string,
+	start time.Time,
+	end time.Time,
+) ([]storage.Event, error) {
+	defer metrics.ObserveStorage("GetAcceptedEventsBetween", time.Now())
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+eventColumns+` FROM event
+			WHERE id IN (SELECT event_id FROM event_attendee WHERE attendee = $1 AND status = $4)
+				AND deleted_at IS NULL AND start_date < $3
+				AND (last_occurrence IS NULL OR last_occurrence + duration / 1000 * interval '1 microsecond' > $2)
+			ORDER BY start_date, id`,
+		user, start.UTC(), end.UTC(), storage.RSVPAccepted)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.scanEvents(ctx, rows)
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// ownerEventsBetween selects the owner events by their first and last occurrences, the last one is unset
+// for the events recurring endlessly. The duration column keeps nanoseconds.
+func (s *Storage) ownerEventsBetween(ctx context.Context,
+	db querier,
+	owner string,
+	start time.Time,
+	end time.Time,
+) ([]storage.Event, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT `+eventColumns+` FROM event
+			WHERE owner = $1 AND deleted_at IS NULL AND start_date < $3
+				AND (last_occurrence IS NULL OR last_occurrence + duration / 1000 * interval '1 microsecond' > $2)
+			ORDER BY start_date, id`,
+		owner, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.scanEvents(ctx, rows)
+}
+
+// checkConflicts runs the conflict check of the event write in its transaction. The owner advisory lock
+// is held until the transaction ends, so the concurrent writes of the owner see each other's events.
+func (s *Storage) checkConflicts(ctx context.Context,
+	tx *sql.Tx,
+	event *storage.Event,
+	check *storage.ConflictCheck,
+) error {
+	if check == nil {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtextextended($1, 0))", "event owner "+event.Owner)
+	if err != nil {
+		return err
+	}
+
+	candidates, err := s.ownerEventsBetween(ctx, tx, event.Owner, check.Start, check.End)
+	if err != nil {
+		return err
+	}
+
+	return check.Check(candidates)
+}
+
+func (s *Storage) GetEvent(ctx context.Context, id string) (storage.Event, error) {
+	defer metrics.ObserveStorage("GetEvent", time.Now())
+
+	if !isEventID(id) {
+		return storage.Event{}, storage.ErrEventDoesNotExist
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+eventColumns+` FROM event WHERE id=$1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	events, err := s.scanEvents(ctx, rows)
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	if len(events) == 0 {
+		return storage.Event{}, storage.ErrEventDoesNotExist
+	}
+
+	return events[0], nil
+}
+
+func (s *Storage) GetEvents(ctx context.Context) ([]storage.Event, error) {
+	defer metrics.ObserveStorage("GetEvents", time.Now())
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+eventColumns+` FROM event WHERE deleted_at IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.scanEvents(ctx, rows)
+}
+
+// GetEventsDueForReminder returns the events with a reminder due before the given time,
+// the earliest due first. Zero limit returns all of them.
+func (s *Storage) GetEventsDueForReminder(ctx context.Context, before time.Time, limit int) ([]storage.Event, error) {
+	defer metrics.ObserveStorage("GetEventsDueForReminder", time.Now())
+
+	return s.eventsBefore(ctx, "remind_due", before, limit)
+}
+
+// GetEventsEndedBefore returns the events which last occurred before the given time,
+// the earliest ended first. Zero limit returns all of them.
+func (s *Storage) GetEventsEndedBefore(ctx context.Context, before time.Time, limit int) ([]storage.Event, error) {
+	defer metrics.ObserveStorage("GetEventsEndedBefore", time.Now())
+
+	return s.eventsBefore(ctx, "last_occurrence", before, limit)
+}
+
+// eventsBefore returns the events with the indexed column before the given time ordered by it.
+func (s *Storage) eventsBefore(ctx context.Context,
+	column string,
+	before time.Time,
+	limit int,
+) ([]storage.Event, error) {
+	statement := fmt.Sprintf(`SELECT %s FROM event
+			WHERE %[2]s < $1 AND deleted_at IS NULL
+			ORDER BY %[2]s, id`, eventColumns, column)
+	args := []any{before.UTC()}
+	if limit > 0 {
+		statement += " LIMIT $2"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.scanEvents(ctx, rows)
+}
+
+func (s *Storage) QueryEvents(ctx context.Context, query storage.EventQuery) (storage.EventPage, error) {
+	defer metrics.ObserveStorage("QueryEvents", time.Now())
+
+	if query.Cursor != nil && !isEventID(query.Cursor.ID) {
+		return storage.EventPage{}, storage.ErrInvalidCursor
+	}
+
+	conditions := []string{"deleted_at IS NULL"}
+	args := make([]any, 0)
+	addCondition := func(condition string, values ...any) {
+		for _, value := range values {
+			args = append(args, value)
+			condition = strings.Replace(condition, "?", fmt.Sprintf("$%d", len(args)), 1)
+		}
+
+		conditions = append(conditions, condition)
+	}
+
+	if query.Owner != "" {
+		addCondition("owner = ?", query.Owner)
+	}
+
+	if query.Title != "" {
+		addCondition(`title ILIKE '%' || ? || '%'`, escapeLike(query.Title))
+	}
+
+	if query.Description != "" {
+		addCondition(`description ILIKE '%' || ? || '%'`, escapeLike(query.Description))
+	}
+
+	if !query.From.IsZero() {
+		addCondition("start_date >= ?", query.From)
+	}
+
+	if !query.To.IsZero() {
+		addCondition("start_date < ?", query.To)
+	}
+
+	// the titles are compared bytewise like the memory storage does, whatever the database collation is
+	sortColumn := "start_date"
+	if query.Sort == storage.SortByTitle {
+		sortColumn = `title COLLATE "C"`
+	}
+
+	direction, comparison := "ASC", ">"
+	if query.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if query.Cursor != nil {
+		var cursorValue any = query.Cursor.StartDate
+		if query.Sort == storage.SortByTitle {
+			cursorValue = query.Cursor.Title
+		}
+
+		addCondition(fmt.Sprintf("(%s, id) %s (?, ?)", sortColumn, comparison), cursorValue, query.Cursor.ID)
+	}
+
+	statement := `SELECT ` + eventColumns + ` FROM event WHERE ` + strings.Join(conditions, " AND ")
+
+	statement += fmt.Sprintf(" ORDER BY %s %s, id %s", sortColumn, direction, direction)
+	if query.Limit > 0 {
+		statement += fmt.Sprintf(" LIMIT %d", query.Limit+1)
+	}
+
+	rows, err := s.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return storage.EventPage{}, err
+	}
+
+	events, err := s.scanEvents(ctx, rows)
+	if err != nil {
+		return storage.EventPage{}, err
+	}
+
+	return storage.NewEventPage(&query, events), nil
+}
+
+// GetAuditEntries returns the audit log of the event, the oldest entry first.
+func (s *Storage) GetAuditEntries(ctx context.Context, eventID string) ([]storage.AuditEntry, error) {
+	defer metrics.ObserveStorage("GetAuditEntries", time.Now())
+
+	if !isEventID(eventID) {
+		return []storage.AuditEntry{}, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, event_id, action, actor, created_at, changes
+			FROM event_audit WHERE event_id=$1 ORDER BY id`,
+		eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]storage.AuditEntry, 0)
+	for rows.Next() {
+		var entry storage.AuditEntry
+		var changes []byte
+		err := rows.Scan(&entry.ID, &entry.EventID, &entry.Action, &entry.Actor, &entry.Time, &changes)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(changes, &entry.Changes)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// exists reports whether the event ID is taken, the events in the trash included.
+func (s *Storage) exists(ctx context.Context, id string) (bool, error) {
+	return s.queryExists(ctx, "SELECT EXISTS(SELECT * FROM event WHERE id = $1)", id)
+}
+
+// active reports whether the event exists and isn't deleted.
+func (s *Storage) active(ctx context.Context, id string) (bool, error) {
+	return s.queryExists(ctx, "SELECT EXISTS(SELECT * FROM event WHERE id = $1 AND deleted_at IS NULL)", id)
+}
+
+func (s *Storage) queryExists(ctx context.Context, statement string, id string) (bool, error) {
+	if !isEventID(id) {
+		return false, nil
+	}
+
+	var exists bool
+	row, err := s.db.QueryContext(ctx, statement, id)
+	if err != nil {
+		return false, err
+	}
+
+	for row.Next() {
+		if err := row.Scan(&exists); err != nil {
+			return false, err
+		}
+	}
+
+	return exists, nil
+}
+
+// isEventID reports whether the ID can be stored in the UUID id column, Postgres fails the queries
+// comparing the column with anything else, so no event has such an ID.
+func isEventID(id string) bool {
+	_, err := uuid.Parse(id)
+	return err == nil
+}
+
+// updateFailure tells why no event was updated: it is missing or its version is stale.
+func (s *Storage) updateFailure(ctx context.Context, id string) error {
+	isExist, err := s.active(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -81,7 +890,36 @@ func (s *Storage) UpdateEvent(ctx context.Context, event *storage.Event) error {
 		return storage.ErrEventDoesNotExist
 	}
 
-	_, err = s.db.ExecContext(
+	return storage.ErrEventVersionConflict
+}
+
+func commitUpdate(tx *sql.Tx, event *storage.Event) error {
+	err := tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	event.Version++
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// updateEvent updates the event of the same version and reports whether it is found.
+func updateEvent(ctx context.Context, db execer, event *storage.Event) (bool, error) {
+	due, lastOccurrence, err := scheduleTimes(event)
+	if err != nil {
+		return false, err
+	}
+
+	reminders, err := json.Marshal(eventReminders(event))
+	if err != nil {
+		return false, err
+	}
+
+	result, err := db.ExecContext(
 		ctx,
 		`UPDATE event
 			SET title=$1,
@@ -89,133 +927,229 @@ func (s *Storage) UpdateEvent(ctx context.Context, event *storage.Event) error {
     		    duration=$3, 
     		    description=$4, 
     		    owner=$5, 
-    		    remind_at=$6,
-    		    is_send=$7
-			WHERE id=$8`,
+    		    rrule=$6,
+    		    ex_dates=$7,
+    		    reminders=$8,
+    		    remind_due=$9,
+    		    last_occurrence=$10,
+    		    version=version+1
+			WHERE id=$11 AND version=$12 AND deleted_at IS NULL`,
 		event.Title,
 		event.StartDate,
 		event.Duration,
 		event.Description,
 		event.Owner,
-		event.RemindAt,
-		event.IsSend,
+		event.RRule,
+		exDates(event.ExDates),
+		string(reminders),
+		due,
+		lastOccurrence,
 		event.ID,
+		event.Version,
 	)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
-}
-
-func (s *Storage) DeleteEvent(ctx context.Context, id string) error {
-	isExist, err := s.exists(ctx, id)
+	affected, err := result.RowsAffected()
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	if !isExist {
-		return storage.ErrEventDoesNotExist
+	return affected > 0, nil
+}
+
+const eventColumns = `id, title, start_date, duration, description, owner,
+	rrule, ex_dates, reminders, version, deleted_at`
+
+// insertAudit appends the entry to the audit log, a nil entry isn't audited.
+func insertAudit(ctx context.Context, tx *sql.Tx, entry *storage.AuditEntry) error {
+	if entry == nil {
+		return nil
 	}
 
-	_, err = s.db.ExecContext(ctx, "DELETE FROM event WHERE id=$1", id)
+	changes, err := json.Marshal(entry.Changes)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return tx.QueryRowContext(ctx,
+		`INSERT INTO event_audit (event_id, action, actor, created_at, changes)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id`,
+		entry.EventID, entry.Action, entry.Actor, entry.Time.UTC(), string(changes),
+	).Scan(&entry.ID)
 }
 
-//nolint:lll
-func (s *Storage) GetEventsByPeriod(ctx context.Context, owner string, startTime time.Time, endTime time.Time) ([]storage.Event, error) {
-	rows, err := s.db.QueryContext(
-		ctx,
-		`SELECT id, 
-       			title, 
-       			start_date, 
-    		    duration, 
-    		    description, 
-    		    owner, 
-    		    remind_at,
-    		    is_send
-			FROM event
-			WHERE owner = $1 AND start_date >=$2 AND start_date < $3`,
-		owner, startTime, endTime,
-	)
+// eventAttendees returns the attendees of the event in the order the events are read with.
+func eventAttendees(ctx context.Context, tx *sql.Tx, eventID string) ([]storage.Attendee, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT attendee, status FROM event_attendee WHERE event_id=$1 ORDER BY attendee", eventID)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	events := make([]storage.Event, 0)
+	var attendees []storage.Attendee
 	for rows.Next() {
-		var ev storage.Event
-		if err = rows.Scan(
-			&ev.ID,
-			&ev.Title,
-			&ev.StartDate,
-			&ev.Duration,
-			&ev.Description,
-			&ev.Owner,
-			&ev.RemindAt,
-			&ev.IsSend,
-		); err != nil {
+		var attendee storage.Attendee
+		if err := rows.Scan(&attendee.User, &attendee.Status); err != nil {
 			return nil, err
 		}
-		events = append(events, ev)
+
+		attendees = append(attendees, attendee)
 	}
 
-	return events, nil
+	return attendees, rows.Err()
 }
 
-func (s *Storage) GetEvents(ctx context.Context) ([]storage.Event, error) {
-	rows, err := s.db.QueryContext(
-		ctx,
-		`SELECT id, 
-       			title, 
-       			start_date, 
-    		    duration, 
-    		    description, 
-    		    owner, 
-    		    remind_at,
-    		    is_send
-			FROM event`)
+// insertAttendees invites the attendees who aren't invited yet, the stored replies are kept.
+func insertAttendees(ctx context.Context, db execer, event *storage.Event) error {
+	for _, attendee := range event.Attendees {
+		_, err := db.ExecContext(ctx,
+			`INSERT INTO event_attendee (event_id, attendee, status) VALUES ($1, $2, $3)
+				ON CONFLICT (event_id, attendee) DO NOTHING`,
+			event.ID, attendee.User, attendee.Status)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mergeAttendees removes the attendees who aren't invited anymore and invites the new ones.
+func mergeAttendees(ctx context.Context, db execer, event *storage.Event) error {
+	users := make([]string, 0, len(event.Attendees))
+	for _, attendee := range event.Attendees {
+		users = append(users, attendee.User)
+	}
+
+	var invited pgtype.TextArray
+	_ = invited.Set(users)
+	_, err := db.ExecContext(ctx,
+		"DELETE FROM event_attendee WHERE event_id=$1 AND attendee <> ALL($2::varchar[])",
+		event.ID, &invited)
+	if err != nil {
+		return err
+	}
+
+	return insertAttendees(ctx, db, event)
+}
+
+// scanEvents reads the events with their attendees.
+func (s *Storage) scanEvents(ctx context.Context, rows *sql.Rows) ([]storage.Event, error) {
+	events, err := scanEventRows(rows)
+	if err != nil || len(events) == 0 {
+		return events, err
+	}
+
+	ids := make([]string, 0, len(events))
+	byID := make(map[string]*storage.Event, len(events))
+	for i := range events {
+		ids = append(ids, events[i].ID)
+		byID[events[i].ID] = &events[i]
+	}
+
+	var eventIDs pgtype.TextArray
+	_ = eventIDs.Set(ids)
+	attendees, err := s.db.QueryContext(ctx,
+		`SELECT event_id, attendee, status FROM event_attendee
+			WHERE event_id = ANY($1::uuid[])
+			ORDER BY event_id, attendee`,
+		&eventIDs)
 	if err != nil {
 		return nil, err
 	}
+	defer attendees.Close()
+
+	for attendees.Next() {
+		var eventID string
+		var attendee storage.Attendee
+		if err := attendees.Scan(&eventID, &attendee.User, &attendee.Status); err != nil {
+			return nil, err
+		}
+
+		if event := byID[eventID]; event != nil {
+			event.Attendees = append(event.Attendees, attendee)
+		}
+	}
+
+	return events, attendees.Err()
+}
+
+func scanEventRows(rows *sql.Rows) ([]storage.Event, error) {
+	defer rows.Close()
 
 	events := make([]storage.Event, 0)
 	for rows.Next() {
 		var ev storage.Event
-		if err = rows.Scan(
+		var evExDates pgtype.TimestampArray
+		var evReminders []byte
+		if err := rows.Scan(
 			&ev.ID,
 			&ev.Title,
 			&ev.StartDate,
 			&ev.Duration,
 			&ev.Description,
 			&ev.Owner,
-			&ev.RemindAt,
-			&ev.IsSend,
+			&ev.RRule,
+			&evExDates,
+			&evReminders,
+			&ev.Version,
+			&ev.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
+
+		if err := evExDates.AssignTo(&ev.ExDates); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(evReminders, &ev.Reminders); err != nil {
+			return nil, err
+		}
+
 		events = append(events, ev)
 	}
 
-	return events, nil
+	return events, rows.Err()
 }
 
-func (s *Storage) exists(ctx context.Context, id string) (bool, error) {
-	var exists bool
-	row, err := s.db.QueryContext(ctx, "SELECT EXISTS(SELECT * FROM event WHERE id = $1)", id)
+// scheduleTimes returns the reminder due time and the last occurrence of the event, NULL if there is none.
+func scheduleTimes(event *storage.Event) (sql.NullTime, sql.NullTime, error) {
+	due, err := event.NextReminderDue()
 	if err != nil {
-		return false, err
+		return sql.NullTime{}, sql.NullTime{}, err
 	}
 
-	for row.Next() {
-		if err := row.Scan(&exists); err != nil {
-			return false, err
-		}
+	lastOccurrence, err := event.LastOccurrence()
+	if err != nil {
+		return sql.NullTime{}, sql.NullTime{}, err
 	}
 
-	return exists, nil
+	return sql.NullTime{Time: due, Valid: !due.IsZero()},
+		sql.NullTime{Time: lastOccurrence, Valid: !lastOccurrence.IsZero()}, nil
+}
+
+func eventReminders(event *storage.Event) []storage.EventReminder {
+	if event.Reminders == nil {
+		return []storage.EventReminder{}
+	}
+
+	return event.Reminders
+}
+
+func exDates(dates []time.Time) *pgtype.TimestampArray {
+	var result pgtype.TimestampArray
+	if dates == nil {
+		dates = []time.Time{}
+	}
+
+	_ = result.Set(dates)
+	return &result
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
 }
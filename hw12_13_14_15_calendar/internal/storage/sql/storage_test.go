@@ -0,0 +1,278 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	//nolint:depguard
+	"github.com/BingaBonga/otus_hw/hw12_13_14_15_calendar/internal/storage"
+	//nolint:depguard
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvalidEventID(t *testing.T) {
+	ctx := context.Background()
+	// the storage isn't connected: an ID which isn't a UUID is rejected before any query
+	s := New()
+	event := &storage.Event{ID: "test_id", Title: "test_title", StartDate: time.Now(), Version: 1}
+
+	t.Run("event not found", func(t *testing.T) {
+		_, err := s.GetEvent(ctx, event.ID)
+		require.ErrorIs(t, err, storage.ErrEventDoesNotExist)
+		require.ErrorIs(t, s.UpdateEvent(ctx, event), storage.ErrEventDoesNotExist)
+		require.ErrorIs(t, s.UpdateEventWithOutbox(ctx, event, nil), storage.ErrEventDoesNotExist)
+		require.ErrorIs(t, s.DeleteEvent(ctx, event.ID), storage.ErrEventDoesNotExist)
+		require.ErrorIs(t, s.RestoreEvent(ctx, event.ID), storage.ErrEventDoesNotExist)
+		require.ErrorIs(t, s.SetAttendeeStatus(ctx, event.ID, "bob", storage.RSVPAccepted),
+			storage.ErrEventDoesNotExist)
+
+		entries, err := s.GetAuditEntries(ctx, event.ID)
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+
+	t.Run("event not created", func(t *testing.T) {
+		require.ErrorIs(t, s.CreateEvent(ctx, event), storage.ErrInvalidEventID)
+	})
+
+	t.Run("cursor rejected", func(t *testing.T) {
+		_, err := s.QueryEvents(ctx, storage.EventQuery{Cursor: &storage.EventCursor{ID: event.ID}})
+		require.ErrorIs(t, err, storage.ErrInvalidCursor)
+	})
+}
+
+type statement struct {
+	query string
+	args  []any
+}
+
+// recorder is a database driver which records the statements, it answers the queries with the rows
+// of the first matching answer and the statements with the affected rows.
+type recorder struct {
+	mu         sync.Mutex
+	statements []statement
+	answers    map[string][][]driver.Value
+	affected   int64
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) {
+	return &recordingConn{recorder: r}, nil
+}
+
+func (r *recorder) Driver() driver.Driver {
+	return nil
+}
+
+func (r *recorder) record(query string, args []driver.NamedValue) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	values := make([]any, 0, len(args))
+	for _, arg := range args {
+		values = append(values, arg.Value)
+	}
+
+	r.statements = append(r.statements, statement{query: strings.Join(strings.Fields(query), " "), args: values})
+}
+
+type recordingConn struct {
+	recorder *recorder
+}
+
+func (c *recordingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *recordingConn) Commit() error {
+	return nil
+}
+
+func (c *recordingConn) Rollback() error {
+	return nil
+}
+
+func (c *recordingConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.recorder.record(query, args)
+	for match, values := range c.recorder.answers {
+		if strings.Contains(query, match) {
+			return &recordedRows{values: values}, nil
+		}
+	}
+
+	return &recordedRows{}, nil
+}
+
+func (c *recordingConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.recorder.record(query, args)
+	return driver.RowsAffected(c.recorder.affected), nil
+}
+
+type recordedRows struct {
+	values [][]driver.Value
+}
+
+func (r *recordedRows) Columns() []string {
+	if len(r.values) == 0 {
+		return nil
+	}
+
+	return make([]string, len(r.values[0]))
+}
+
+func (r *recordedRows) Close() error {
+	return nil
+}
+
+func (r *recordedRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestRecordedStatements(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T, answers map[string][][]driver.Value) (*recorder, *Storage) {
+		t.Helper()
+
+		r := &recorder{answers: answers, affected: 1}
+		db := sql.OpenDB(r)
+		t.Cleanup(func() { db.Close() })
+
+		return r, &Storage{db: db}
+	}
+
+	t.Run("events before the indexed time", func(t *testing.T) {
+		r, s := setup(t, nil)
+		before := time.Date(2024, time.March, 4, 10, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+
+		events, err := s.GetEventsDueForReminder(ctx, before, 10)
+		require.NoError(t, err)
+		require.Empty(t, events)
+		events, err = s.GetEventsEndedBefore(ctx, before, 0)
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		require.Equal(t, []statement{
+			{
+				query: "SELECT " + strings.Join(strings.Fields(eventColumns), " ") + " FROM event " +
+					"WHERE remind_due < $1 AND deleted_at IS NULL ORDER BY remind_due, id LIMIT $2",
+				args: []any{before.UTC(), int64(10)},
+			},
+			{
+				query: "SELECT " + strings.Join(strings.Fields(eventColumns), " ") + " FROM event " +
+					"WHERE last_occurrence < $1 AND deleted_at IS NULL ORDER BY last_occurrence, id",
+				args: []any{before.UTC()},
+			},
+		}, r.statements)
+	})
+
+	t.Run("events deleted by valid ids", func(t *testing.T) {
+		r, s := setup(t, nil)
+		first, second := "0190a6a4-6a3e-7c3b-8f6e-1d2f3a4b5c6d", "0190a6a4-6a3e-7c3b-8f6e-1d2f3a4b5c6e"
+
+		deleted, err := s.DeleteEvents(ctx, []string{first, "test_id", second})
+		require.NoError(t, err)
+		require.Equal(t, 1, deleted)
+
+		require.Len(t, r.statements, 1)
+		require.Equal(t,
+			"UPDATE event SET deleted_at=$2, version=version+1 WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL",
+			r.statements[0].query)
+		require.Equal(t, "{"+first+","+second+"}", r.statements[0].args[0])
+	})
+
+	t.Run("outbox messages of trashed events held back", func(t *testing.T) {
+		r, s := setup(t, nil)
+
+		messages, err := s.GetPendingOutboxMessages(ctx, 10)
+		require.NoError(t, err)
+		require.Empty(t, messages)
+
+		require.Len(t, r.statements, 1)
+		require.Equal(t, "SELECT outbox.id, event_id, occurrence, recipient, reminder, payload, created_at "+
+			"FROM outbox JOIN event ON event.id = outbox.event_id::uuid "+
+			"WHERE sent_at IS NULL AND deleted_at IS NULL ORDER BY outbox.id LIMIT 10", r.statements[0].query)
+	})
+
+	t.Run("outbox messages purged with the events", func(t *testing.T) {
+		r, s := setup(t, map[string][][]driver.Value{"WITH purged": {{int64(2)}}})
+		deletedBefore := time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)
+
+		purged, err := s.PurgeEvents(ctx, deletedBefore)
+		require.NoError(t, err)
+		require.Equal(t, 2, purged)
+
+		require.Equal(t, []statement{
+			{
+				query: "WITH purged AS (DELETE FROM event WHERE deleted_at < $1 RETURNING id), " +
+					"dropped AS (DELETE FROM outbox WHERE event_id IN (SELECT id::text FROM purged)) " +
+					"SELECT count(*) FROM purged",
+				args: []any{deletedBefore},
+			},
+		}, r.statements)
+	})
+
+	t.Run("events sorted by title bytewise", func(t *testing.T) {
+		r, s := setup(t, nil)
+		cursor := &storage.EventCursor{Title: "Zeta", ID: "0190a6a4-6a3e-7c3b-8f6e-1d2f3a4b5c6d"}
+
+		page, err := s.QueryEvents(ctx, storage.EventQuery{Sort: storage.SortByTitle, Limit: 10, Cursor: cursor})
+		require.NoError(t, err)
+		require.Empty(t, page.Events)
+
+		require.Len(t, r.statements, 1)
+		require.Contains(t, r.statements[0].query, `(title COLLATE "C", id) > ($1, $2)`)
+		require.Contains(t, r.statements[0].query, `ORDER BY title COLLATE "C" ASC, id ASC LIMIT 11`)
+		require.Equal(t, []any{cursor.Title, cursor.ID}, r.statements[0].args)
+	})
+
+	t.Run("last occurrence backfilled", func(t *testing.T) {
+		startDate := time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)
+		r, s := setup(t, map[string][][]driver.Value{
+			"SELECT id, start_date, rrule, ex_dates": {
+				{"count_id", startDate, "FREQ=DAILY;COUNT=3", nil},
+				{"until_id", startDate, "FREQ=WEEKLY;UNTIL=20240320T100000Z", "{\"2024-03-18 10:00:00\"}"},
+				{"endless_id", startDate, "FREQ=DAILY", nil},
+				{"invalid_id", startDate, "FREQ=SECONDLY", nil},
+			},
+		})
+
+		tx, err := s.db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		require.NoError(t, backfillLastOccurrence(ctx, tx))
+		require.NoError(t, tx.Commit())
+
+		require.Equal(t, []statement{
+			{
+				query: "SELECT id, start_date, rrule, ex_dates FROM event WHERE rrule <> '' AND last_occurrence IS NULL",
+				args:  []any{},
+			},
+			{
+				query: "UPDATE event SET last_occurrence=$2 WHERE id=$1",
+				args:  []any{"count_id", startDate.AddDate(0, 0, 2)},
+			},
+			{
+				query: "UPDATE event SET last_occurrence=$2 WHERE id=$1",
+				args:  []any{"until_id", startDate.AddDate(0, 0, 7)},
+			},
+		}, r.statements)
+	})
+}
@@ -1,4 +1,4 @@
-CREATE TABLE event (
+CREATE TABLE IF NOT EXISTS event (
     id UUID not null,
     title varchar(256) not null,
     start_date timestamp not null default now(),
@@ -7,4 +7,4 @@ CREATE TABLE event (
     owner varchar(256) not null,
     remind_at BIGINT,
     is_send bool default false
-);
\ No newline at end of file
+);
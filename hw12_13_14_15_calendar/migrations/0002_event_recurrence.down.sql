@@ -0,0 +1,4 @@
+ALTER TABLE event
+    DROP COLUMN IF EXISTS rrule,
+    DROP COLUMN IF EXISTS ex_dates,
+    DROP COLUMN IF EXISTS sent_until;
@@ -0,0 +1,4 @@
+ALTER TABLE event
+    ADD COLUMN IF NOT EXISTS rrule varchar(1024) not null default '',
+    ADD COLUMN IF NOT EXISTS ex_dates timestamp[] not null default '{}',
+    ADD COLUMN IF NOT EXISTS sent_until timestamp not null default '0001-01-01 00:00:00';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS event_owner_start_date_idx;
+ALTER TABLE event DROP CONSTRAINT IF EXISTS event_pkey;
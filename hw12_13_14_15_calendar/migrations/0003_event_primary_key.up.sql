@@ -0,0 +1,2 @@
+ALTER TABLE event ADD CONSTRAINT event_pkey PRIMARY KEY (id);
+CREATE INDEX IF NOT EXISTS event_owner_start_date_idx ON event (owner, start_date);
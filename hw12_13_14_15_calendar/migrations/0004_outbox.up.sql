@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id varchar(256) not null,
+    occurrence timestamp not null,
+    payload bytea not null,
+    created_at timestamp not null default now(),
+    sent_at timestamp,
+    CONSTRAINT outbox_event_occurrence_key UNIQUE (event_id, occurrence)
+);
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (id) WHERE sent_at IS NULL;
@@ -0,0 +1,7 @@
+DELETE FROM outbox a USING outbox b
+    WHERE a.event_id = b.event_id AND a.occurrence = b.occurrence AND a.id > b.id;
+ALTER TABLE outbox DROP CONSTRAINT IF EXISTS outbox_event_occurrence_recipient_key;
+ALTER TABLE outbox ADD CONSTRAINT outbox_event_occurrence_key UNIQUE (event_id, occurrence);
+ALTER TABLE outbox DROP COLUMN IF EXISTS recipient;
+
+DROP TABLE IF EXISTS event_attendee;
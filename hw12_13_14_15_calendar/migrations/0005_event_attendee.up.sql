@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS event_attendee (
+    event_id UUID not null REFERENCES event (id) ON DELETE CASCADE,
+    attendee varchar(256) not null,
+    status varchar(16) not null default 'needs-action',
+    CONSTRAINT event_attendee_pkey PRIMARY KEY (event_id, attendee)
+);
+CREATE INDEX IF NOT EXISTS event_attendee_attendee_idx ON event_attendee (attendee);
+
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS recipient varchar(256) not null default '';
+ALTER TABLE outbox DROP CONSTRAINT IF EXISTS outbox_event_occurrence_key;
+ALTER TABLE outbox ADD CONSTRAINT outbox_event_occurrence_recipient_key UNIQUE (event_id, occurrence, recipient);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS event_deleted_at_idx;
+ALTER TABLE event DROP COLUMN IF EXISTS deleted_at;
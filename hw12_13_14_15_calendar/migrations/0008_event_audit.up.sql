@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS event_audit (
+    id BIGSERIAL PRIMARY KEY,
+    event_id varchar(256) not null,
+    action varchar(16) not null,
+    actor varchar(256) not null,
+    created_at timestamp not null default now(),
+    changes jsonb not null default '[]'
+);
+CREATE INDEX IF NOT EXISTS event_audit_event_idx ON event_audit (event_id, id);
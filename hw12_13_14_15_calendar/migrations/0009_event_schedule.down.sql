@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS event_last_occurrence_idx;
+DROP INDEX IF EXISTS event_remind_due_idx;
+ALTER TABLE event DROP COLUMN IF EXISTS last_occurrence;
+ALTER TABLE event DROP COLUMN IF EXISTS remind_due;
@@ -0,0 +1,8 @@
+ALTER TABLE event ADD COLUMN IF NOT EXISTS remind_due timestamp;
+ALTER TABLE event ADD COLUMN IF NOT EXISTS last_occurrence timestamp;
+UPDATE event SET remind_due = start_date + COALESCE(remind_at, 0) * interval '1 minute'
+    WHERE is_send IS NOT TRUE AND rrule = '';
+UPDATE event SET remind_due = start_date WHERE is_send IS NOT TRUE AND rrule <> '';
+UPDATE event SET last_occurrence = start_date WHERE rrule = '';
+CREATE INDEX IF NOT EXISTS event_remind_due_idx ON event (remind_due) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS event_last_occurrence_idx ON event (last_occurrence) WHERE deleted_at IS NULL;
@@ -0,0 +1,19 @@
+DELETE FROM outbox a USING outbox b
+    WHERE a.id > b.id AND a.event_id = b.event_id AND a.occurrence = b.occurrence AND a.recipient = b.recipient;
+ALTER TABLE outbox DROP CONSTRAINT IF EXISTS outbox_event_occurrence_recipient_reminder_key;
+ALTER TABLE outbox ADD CONSTRAINT outbox_event_occurrence_recipient_key UNIQUE (event_id, occurrence, recipient);
+ALTER TABLE outbox DROP COLUMN IF EXISTS reminder;
+ALTER TABLE event
+    ADD COLUMN IF NOT EXISTS remind_at BIGINT,
+    ADD COLUMN IF NOT EXISTS is_send bool default false,
+    ADD COLUMN IF NOT EXISTS sent_until timestamp not null default '0001-01-01 00:00:00';
+UPDATE event SET remind_at = COALESCE(
+        -(reminders -> 0 ->> 'before')::bigint,
+        (EXTRACT(EPOCH FROM (reminders -> 0 ->> 'at')::timestamp - start_date) / 60)::bigint,
+        0),
+    is_send = COALESCE((reminders -> 0 ->> 'isSend')::boolean, true),
+    sent_until = COALESCE((reminders -> 0 ->> 'sentUntil')::timestamp, '0001-01-01 00:00:00');
+UPDATE event SET remind_due = start_date + remind_at * interval '1 minute'
+    WHERE is_send IS NOT TRUE AND rrule = '';
+UPDATE event SET remind_due = start_date WHERE is_send IS NOT TRUE AND rrule <> '';
+ALTER TABLE event DROP COLUMN IF EXISTS reminders;
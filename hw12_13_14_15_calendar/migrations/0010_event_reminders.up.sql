@@ -0,0 +1,20 @@
+ALTER TABLE event ADD COLUMN IF NOT EXISTS reminders jsonb not null default '[]';
+-- remind_at is the signed offset from the start: a negative one becomes the minutes before the start,
+-- a positive one, the reminder after the start, becomes the absolute reminder time.
+UPDATE event SET reminders = jsonb_build_array(jsonb_strip_nulls(jsonb_build_object(
+        'before', CASE WHEN remind_at < 0 THEN -remind_at END,
+        'at', CASE WHEN remind_at > 0
+            THEN to_char(start_date + remind_at * interval '1 minute', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') END,
+        'isSend', COALESCE(is_send, false),
+        'sentUntil', to_char(COALESCE(sent_until, '0001-01-01'), 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+    )));
+-- the recurring events are due not later than their first occurrence reminder, the scheduler recomputes it
+UPDATE event SET remind_due = start_date + COALESCE(remind_at, 0) * interval '1 minute'
+    WHERE is_send IS NOT TRUE;
+ALTER TABLE event DROP COLUMN IF EXISTS remind_at;
+ALTER TABLE event DROP COLUMN IF EXISTS is_send;
+ALTER TABLE event DROP COLUMN IF EXISTS sent_until;
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS reminder varchar(256) not null default '';
+ALTER TABLE outbox DROP CONSTRAINT IF EXISTS outbox_event_occurrence_recipient_key;
+ALTER TABLE outbox ADD CONSTRAINT outbox_event_occurrence_recipient_reminder_key
+    UNIQUE (event_id, occurrence, recipient, reminder);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS reminder_delivery (
+    reminder_id varchar(1024) not null,
+    channel varchar(64) not null,
+    delivered_at timestamp not null default now(),
+    PRIMARY KEY (reminder_id, channel)
+);
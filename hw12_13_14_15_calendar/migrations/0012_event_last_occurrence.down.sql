@@ -0,0 +1,2 @@
+-- the backfilled last occurrences stay valid after the rollback
+SELECT 1;
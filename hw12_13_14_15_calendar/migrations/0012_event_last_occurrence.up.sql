@@ -0,0 +1,3 @@
+-- the last occurrences of the recurring events ending by COUNT or UNTIL are backfilled by the Go step
+-- of the migration, only the event model expands the recurrence rules
+UPDATE event SET last_occurrence = start_date WHERE rrule = '' AND last_occurrence IS NULL;